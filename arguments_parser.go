@@ -28,6 +28,12 @@ type Arguments struct {
 var opts struct {
 	Source string `short:"s" long:"source" description:"the Oberon file to parse"`
 	Debug  bool   `long:"debug" description:"Show debug statements"`
+
+	Dialect            string `long:"dialect" description:"Language report the source follows" choice:"oberon07" choice:"oberon2" choice:"cp" default:"oberon07"`
+	UnicodeIdentifiers bool   `long:"unicode-identifiers" description:"Allow Unicode letters in identifiers"`
+	WarnIncompleteCase bool   `long:"warn-incomplete-case" description:"Warn about CASE statements whose labels leave out values of their expression"`
+
+	DiagnosticsFormat string `long:"diagnostics-format" description:"Format of the reported diagnostics" choice:"text" choice:"json" choice:"sarif" default:"text"`
 }
 
 func parse() Arguments {
@@ -43,6 +49,10 @@ func parse() Arguments {
 	}
 	args["source"] = opts.Source
 	args["debug"] = strconv.FormatBool(opts.Debug)
+	args["dialect"] = opts.Dialect
+	args["unicode-identifiers"] = strconv.FormatBool(opts.UnicodeIdentifiers)
+	args["warn-incomplete-case"] = strconv.FormatBool(opts.WarnIncompleteCase)
+	args["diagnostics-format"] = opts.DiagnosticsFormat
 	return Arguments{
 		result:    SUCCESS,
 		arguments: args,
@@ -0,0 +1,735 @@
+package ast
+
+import (
+	lexer "oberon/lexer"
+	source "oberon/source"
+)
+
+// NO_POS marks an optional token that is absent from the source.
+const NO_POS = -1
+
+// Node is implemented by every node of the tree. Pos is the byte offset of
+// the first character of the node and End the offset just after its last
+// character.
+type Node interface {
+	Pos() int
+	End() int
+}
+
+type Expr interface {
+	Node
+	exprNode()
+}
+
+type Type interface {
+	Node
+	typeNode()
+}
+
+type Stmt interface {
+	Node
+	stmtNode()
+}
+
+type Decl interface {
+	Node
+	declNode()
+}
+
+type Selector interface {
+	Node
+	selectorNode()
+}
+
+// Span resolves the position of node in file.
+func Span(file *source.SourceFile, node Node) source.Span {
+	return file.Span(node.Pos(), node.End())
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+type Ident struct {
+	NamePos int
+	Name    string
+}
+
+// BasicLit is a number, character constant or string. The value denoted by
+// the literal is stored as decoded by the lexer.
+type BasicLit struct {
+	ValuePos  int
+	Kind      lexer.TokenKind
+	Value     string
+	IntValue  int64
+	RealValue float64
+	StrValue  string
+}
+
+// BoolLit is one of TRUE and FALSE.
+type BoolLit struct {
+	ValuePos int
+	Value    bool
+}
+
+type NilLit struct {
+	NilPos int
+}
+
+// qualident = [ident "."] ident.
+type QualIdent struct {
+	Module *Ident
+	Name   *Ident
+}
+
+// designator = qualident {selector}.
+type Designator struct {
+	Qualident *QualIdent
+	Selectors []Selector
+}
+
+// CallExpr is a designator followed by actual parameters. Lparen and Rparen
+// are NO_POS when a procedure is called without a parameter list.
+type CallExpr struct {
+	Fun    *Designator
+	Lparen int
+	Args   []Expr
+	Rparen int
+}
+
+// set = "{" [element {"," element}] "}".
+type SetExpr struct {
+	Lbrace   int
+	Elements []Expr
+	Rbrace   int
+}
+
+// RangeExpr is a set element or case label of the form a .. b.
+type RangeExpr struct {
+	Low  Expr
+	High Expr
+}
+
+type ParenExpr struct {
+	Lparen int
+	X      Expr
+	Rparen int
+}
+
+// UnaryExpr is "~" x or the sign of the first term of a SimpleExpression,
+// so -a * b is -(a * b).
+type UnaryExpr struct {
+	OpPos int
+	Op    lexer.TokenKind
+	X     Expr
+}
+
+// BinaryExpr is x Op y. Operators of equal precedence associate to the
+// left, see lexer.TokenKind.Precedence. For IS, Y is the *QualIdent of
+// the type tested.
+type BinaryExpr struct {
+	X     Expr
+	OpPos int
+	Op    lexer.TokenKind
+	Y     Expr
+}
+
+// BadExpr stands in for an expression, or a type, that could not be
+// parsed. It covers the skipped source text, which may be empty.
+type BadExpr struct {
+	From int
+	To   int
+}
+
+func (x *BadExpr) Pos() int    { return x.From }
+func (x *Ident) Pos() int      { return x.NamePos }
+func (x *BasicLit) Pos() int   { return x.ValuePos }
+func (x *BoolLit) Pos() int    { return x.ValuePos }
+func (x *NilLit) Pos() int     { return x.NilPos }
+func (x *Designator) Pos() int { return x.Qualident.Pos() }
+func (x *CallExpr) Pos() int   { return x.Fun.Pos() }
+func (x *SetExpr) Pos() int    { return x.Lbrace }
+func (x *RangeExpr) Pos() int  { return x.Low.Pos() }
+func (x *ParenExpr) Pos() int  { return x.Lparen }
+func (x *UnaryExpr) Pos() int  { return x.OpPos }
+func (x *BinaryExpr) Pos() int { return x.X.Pos() }
+
+func (x *BadExpr) End() int  { return x.To }
+func (x *Ident) End() int    { return x.NamePos + len(x.Name) }
+func (x *BasicLit) End() int { return x.ValuePos + len(x.Value) }
+func (x *BoolLit) End() int {
+	if x.Value {
+		return x.ValuePos + len("TRUE")
+	}
+	return x.ValuePos + len("FALSE")
+}
+func (x *NilLit) End() int    { return x.NilPos + len("NIL") }
+func (x *QualIdent) End() int { return x.Name.End() }
+func (x *Designator) End() int {
+	if len(x.Selectors) > 0 {
+		return x.Selectors[len(x.Selectors)-1].End()
+	}
+	return x.Qualident.End()
+}
+func (x *CallExpr) End() int {
+	if x.Rparen != NO_POS {
+		return x.Rparen + 1
+	}
+	return x.Fun.End()
+}
+func (x *SetExpr) End() int    { return x.Rbrace + 1 }
+func (x *RangeExpr) End() int  { return x.High.End() }
+func (x *ParenExpr) End() int  { return x.Rparen + 1 }
+func (x *UnaryExpr) End() int  { return x.X.End() }
+func (x *BinaryExpr) End() int { return x.Y.End() }
+
+func (*BadExpr) exprNode()    {}
+func (*Ident) exprNode()      {}
+func (*BasicLit) exprNode()   {}
+func (*BoolLit) exprNode()    {}
+func (*NilLit) exprNode()     {}
+func (*QualIdent) exprNode()  {}
+func (*Designator) exprNode() {}
+func (*CallExpr) exprNode()   {}
+func (*SetExpr) exprNode()    {}
+func (*RangeExpr) exprNode()  {}
+func (*ParenExpr) exprNode()  {}
+func (*UnaryExpr) exprNode()  {}
+func (*BinaryExpr) exprNode() {}
+
+// IsQualified reports whether the qualident names an imported object.
+func (x *QualIdent) Pos() int {
+	if x.Module != nil {
+		return x.Module.Pos()
+	}
+	return x.Name.Pos()
+}
+
+func (x *QualIdent) IsQualified() bool {
+	return x.Module != nil
+}
+
+func (x *QualIdent) String() string {
+	if x.Module != nil {
+		return x.Module.Name + "." + x.Name.Name
+	}
+	return x.Name.Name
+}
+
+// ----------------------------------------------------------------------------
+// Selectors
+
+// "." ident
+type FieldSelector struct {
+	Period int
+	Name   *Ident
+}
+
+// "[" ExpList "]"
+type IndexSelector struct {
+	Lbrack  int
+	Indices []Expr
+	Rbrack  int
+}
+
+// "^"
+type DerefSelector struct {
+	Arrow int
+}
+
+// "(" qualident ")"
+type TypeGuardSelector struct {
+	Lparen int
+	Type   *QualIdent
+	Rparen int
+}
+
+func (s *FieldSelector) Pos() int     { return s.Period }
+func (s *IndexSelector) Pos() int     { return s.Lbrack }
+func (s *DerefSelector) Pos() int     { return s.Arrow }
+func (s *TypeGuardSelector) Pos() int { return s.Lparen }
+
+func (s *FieldSelector) End() int     { return s.Name.End() }
+func (s *IndexSelector) End() int     { return s.Rbrack + 1 }
+func (s *DerefSelector) End() int     { return s.Arrow + 1 }
+func (s *TypeGuardSelector) End() int { return s.Rparen + 1 }
+
+func (*FieldSelector) selectorNode()     {}
+func (*IndexSelector) selectorNode()     {}
+func (*DerefSelector) selectorNode()     {}
+func (*TypeGuardSelector) selectorNode() {}
+
+// ----------------------------------------------------------------------------
+// Types
+
+// ArrayType is ARRAY length {"," length} OF type. A formal parameter of
+// type ARRAY OF T is an open array and has no lengths.
+type ArrayType struct {
+	Array   int
+	Lengths []Expr
+	Elem    Type
+}
+
+// RecordType is [ABSTRACT | EXTENSIBLE | LIMITED] RECORD ["(" BaseType ")"]
+// [FieldListSequence] END. Attr is the Component Pascal record attribute,
+// at AttrPos, or ILLEGAL for a record without one.
+type RecordType struct {
+	Attr    lexer.TokenKind
+	AttrPos int
+	Record  int
+	Base    *QualIdent
+	Fields  []*FieldList
+	EndPos  int
+}
+
+// FieldList is IdentList ":" type.
+type FieldList struct {
+	Names []*IdentDef
+	Type  Type
+}
+
+// PointerType is POINTER TO type.
+type PointerType struct {
+	Pointer int
+	Base    Type
+}
+
+// ProcedureType is PROCEDURE [FormalParameters]; Params is nil without a
+// parameter list.
+type ProcedureType struct {
+	Procedure int
+	Params    *FormalParameters
+}
+
+// FormalParameters is "(" [FPSection {";" FPSection}] ")" [":" qualident].
+type FormalParameters struct {
+	Lparen   int
+	Sections []*FPSection
+	Rparen   int
+	Result   *QualIdent
+}
+
+// FPSection is [VAR | IN | OUT] ident {"," ident} ":" FormalType. Var is
+// the position of the parameter mode Mode, which is VAR or, in Component
+// Pascal, IN or OUT. For value parameters Var is NO_POS and Mode ILLEGAL.
+type FPSection struct {
+	Var   int
+	Mode  lexer.TokenKind
+	Names []*Ident
+	Type  Type
+}
+
+func (t *ArrayType) Pos() int { return t.Array }
+func (t *RecordType) Pos() int {
+	if t.Attr != lexer.ILLEGAL {
+		return t.AttrPos
+	}
+	return t.Record
+}
+func (t *PointerType) Pos() int   { return t.Pointer }
+func (t *ProcedureType) Pos() int { return t.Procedure }
+
+func (t *ArrayType) End() int   { return t.Elem.End() }
+func (t *RecordType) End() int  { return t.EndPos + len("END") }
+func (t *PointerType) End() int { return t.Base.End() }
+func (t *ProcedureType) End() int {
+	if t.Params != nil {
+		return t.Params.End()
+	}
+	return t.Procedure + len("PROCEDURE")
+}
+
+func (*BadExpr) typeNode()       {}
+func (*QualIdent) typeNode()     {}
+func (*ArrayType) typeNode()     {}
+func (*RecordType) typeNode()    {}
+func (*PointerType) typeNode()   {}
+func (*ProcedureType) typeNode() {}
+
+func (f *FieldList) Pos() int { return f.Names[0].Pos() }
+func (f *FieldList) End() int { return f.Type.End() }
+
+func (p *FormalParameters) Pos() int { return p.Lparen }
+func (p *FormalParameters) End() int {
+	if p.Result != nil {
+		return p.Result.End()
+	}
+	return p.Rparen + 1
+}
+
+func (s *FPSection) Pos() int {
+	if s.Var != NO_POS {
+		return s.Var
+	}
+	return s.Names[0].Pos()
+}
+func (s *FPSection) End() int { return s.Type.End() }
+
+// IsOpenArray reports whether t is a formal type of the form ARRAY OF T.
+func IsOpenArray(t Type) bool {
+	array, ok := t.(*ArrayType)
+	return ok && len(array.Lengths) == 0
+}
+
+// ----------------------------------------------------------------------------
+// Statements
+
+// BadStmt covers source text skipped while recovering from a syntax
+// error in a statement sequence.
+type BadStmt struct {
+	From int
+	To   int
+}
+
+// EmptyStmt is the empty statement, which covers no source text. At is
+// the offset of the token that follows it: the ";" or the END, ELSE,
+// ELSIF, UNTIL, "|" or RETURN that closes its statement sequence.
+type EmptyStmt struct {
+	At int
+}
+
+// assignment = designator ":=" expression.
+type AssignStmt struct {
+	Lhs    *Designator
+	Assign int
+	Rhs    Expr
+}
+
+// ProcedureCall = designator [ActualParameters].
+type CallStmt struct {
+	Call *CallExpr
+}
+
+// Elsif is an ELSIF branch of an IF or WHILE statement.
+type Elsif struct {
+	Elsif int
+	Cond  Expr
+	Body  []Stmt
+}
+
+// IfStatement = IF expression THEN StatementSequence
+// {ELSIF expression THEN StatementSequence}
+// [ELSE StatementSequence] END.
+type IfStmt struct {
+	If     int
+	Cond   Expr
+	Body   []Stmt
+	Elsifs []*Elsif
+	Else   []Stmt
+	EndPos int
+}
+
+// CaseClause is CaseLabelList ":" StatementSequence. A label is an
+// expression or a RangeExpr.
+type CaseClause struct {
+	Labels []Expr
+	Colon  int
+	Body   []Stmt
+}
+
+// CaseStatement = CASE expression OF case {"|" case} [ELSE
+// StatementSequence] END. The ELSE part is Oberon-2 only; Else is nil
+// without it.
+type CaseStmt struct {
+	Case    int
+	X       Expr
+	Clauses []*CaseClause
+	Else    []Stmt
+	EndPos  int
+}
+
+// WhileStatement = WHILE expression DO StatementSequence
+// {ELSIF expression DO StatementSequence} END.
+type WhileStmt struct {
+	While  int
+	Cond   Expr
+	Body   []Stmt
+	Elsifs []*Elsif
+	EndPos int
+}
+
+// RepeatStatement = REPEAT StatementSequence UNTIL expression.
+type RepeatStmt struct {
+	Repeat int
+	Body   []Stmt
+	Until  int
+	Cond   Expr
+}
+
+// ForStatement = FOR ident ":=" expression TO expression [BY ConstExpression]
+// DO StatementSequence END.
+type ForStmt struct {
+	For    int
+	Var    *Ident
+	Low    Expr
+	High   Expr
+	By     Expr
+	Body   []Stmt
+	EndPos int
+}
+
+// WithClause is guard DO StatementSequence, where guard = qualident ":"
+// qualident: within Body, the variable Var is regarded as being of Type.
+type WithClause struct {
+	Var   Expr
+	Colon int
+	Type  Expr
+	Body  []Stmt
+}
+
+// WithStatement = WITH guard DO StatementSequence {"|" guard DO
+// StatementSequence} [ELSE StatementSequence] END. Oberon-2 only.
+type WithStmt struct {
+	With    int
+	Clauses []*WithClause
+	Else    []Stmt
+	EndPos  int
+}
+
+// LoopStatement = LOOP StatementSequence END. Oberon-2 only.
+type LoopStmt struct {
+	Loop   int
+	Body   []Stmt
+	EndPos int
+}
+
+// ExitStmt is the EXIT statement, which leaves the innermost LOOP.
+// Oberon-2 only.
+type ExitStmt struct {
+	Exit int
+}
+
+// ReturnStmt is the RETURN statement, which ends the procedure it is in
+// and returns X, if any, as its result. Oberon-2 only: an Oberon-07
+// procedure returns its result at the end of its body.
+type ReturnStmt struct {
+	Return int
+	X      Expr
+}
+
+func (s *ReturnStmt) Pos() int { return s.Return }
+func (s *ReturnStmt) End() int {
+	if s.X != nil {
+		return s.X.End()
+	}
+	return s.Return + len("RETURN")
+}
+func (*ReturnStmt) stmtNode() {}
+
+func (s *BadStmt) Pos() int    { return s.From }
+func (s *EmptyStmt) Pos() int  { return s.At }
+func (s *AssignStmt) Pos() int { return s.Lhs.Pos() }
+func (s *CallStmt) Pos() int   { return s.Call.Pos() }
+func (s *IfStmt) Pos() int     { return s.If }
+func (s *CaseStmt) Pos() int   { return s.Case }
+func (s *WhileStmt) Pos() int  { return s.While }
+func (s *RepeatStmt) Pos() int { return s.Repeat }
+func (s *ForStmt) Pos() int    { return s.For }
+func (s *WithStmt) Pos() int   { return s.With }
+func (s *LoopStmt) Pos() int   { return s.Loop }
+func (s *ExitStmt) Pos() int   { return s.Exit }
+
+func (s *BadStmt) End() int    { return s.To }
+func (s *EmptyStmt) End() int  { return s.At }
+func (s *AssignStmt) End() int { return s.Rhs.End() }
+func (s *CallStmt) End() int   { return s.Call.End() }
+func (s *IfStmt) End() int     { return s.EndPos + len("END") }
+func (s *CaseStmt) End() int   { return s.EndPos + len("END") }
+func (s *WhileStmt) End() int  { return s.EndPos + len("END") }
+func (s *RepeatStmt) End() int { return s.Cond.End() }
+func (s *ForStmt) End() int    { return s.EndPos + len("END") }
+func (s *WithStmt) End() int   { return s.EndPos + len("END") }
+func (s *LoopStmt) End() int   { return s.EndPos + len("END") }
+func (s *ExitStmt) End() int   { return s.Exit + len("EXIT") }
+
+func (*BadStmt) stmtNode()    {}
+func (*EmptyStmt) stmtNode()  {}
+func (*AssignStmt) stmtNode() {}
+func (*CallStmt) stmtNode()   {}
+func (*IfStmt) stmtNode()     {}
+func (*CaseStmt) stmtNode()   {}
+func (*WhileStmt) stmtNode()  {}
+func (*RepeatStmt) stmtNode() {}
+func (*ForStmt) stmtNode()    {}
+func (*WithStmt) stmtNode()   {}
+func (*LoopStmt) stmtNode()   {}
+func (*ExitStmt) stmtNode()   {}
+
+func (e *Elsif) Pos() int { return e.Elsif }
+func (e *Elsif) End() int {
+	if len(e.Body) > 0 {
+		return e.Body[len(e.Body)-1].End()
+	}
+	return e.Cond.End()
+}
+
+func (c *WithClause) Pos() int { return c.Var.Pos() }
+func (c *WithClause) End() int {
+	if len(c.Body) > 0 {
+		return c.Body[len(c.Body)-1].End()
+	}
+	return c.Type.End()
+}
+
+func (c *CaseClause) Pos() int { return c.Labels[0].Pos() }
+func (c *CaseClause) End() int {
+	if len(c.Body) > 0 {
+		return c.Body[len(c.Body)-1].End()
+	}
+	return c.Colon + 1
+}
+
+// ----------------------------------------------------------------------------
+// Declarations
+
+// identdef = ident ["*" | "-"]. Star is the position of the export mark
+// and NO_POS unless the ident is exported. ReadOnly is set for the
+// Oberon-2 mark "-", which exports the ident for reading only.
+type IdentDef struct {
+	Name     *Ident
+	Star     int
+	ReadOnly bool
+}
+
+func (d *IdentDef) Pos() int { return d.Name.Pos() }
+func (d *IdentDef) End() int {
+	if d.Star != NO_POS {
+		return d.Star + 1
+	}
+	return d.Name.End()
+}
+
+// IsExported reports whether the ident is marked for export.
+func (d *IdentDef) IsExported() bool {
+	return d.Star != NO_POS
+}
+
+// import = ident [":=" ident]. For an aliased import, Alias is the name
+// under which Module is known in the importing module.
+type ImportDecl struct {
+	Alias  *Ident
+	Module *Ident
+}
+
+// LocalName returns the name the import binds in the importing module.
+func (d *ImportDecl) LocalName() *Ident {
+	if d.Alias != nil {
+		return d.Alias
+	}
+	return d.Module
+}
+
+// BadDecl covers source text skipped while recovering from a syntax
+// error in a declaration sequence.
+type BadDecl struct {
+	From int
+	To   int
+}
+
+// ConstDeclaration = identdef "=" ConstExpression.
+type ConstDecl struct {
+	Name  *IdentDef
+	Value Expr
+}
+
+// TypeDeclaration = identdef "=" StrucType.
+type TypeDecl struct {
+	Name *IdentDef
+	Type Type
+}
+
+// VariableDeclaration = IdentList ":" type.
+type VarDecl struct {
+	Names []*IdentDef
+	Type  Type
+}
+
+// receiver = "(" [VAR | IN] ident ":" ident ")". The receiver binds an
+// Oberon-2 procedure to the record or pointer type Type. Var is the
+// position of the mode Mode, as in FPSection.
+type Receiver struct {
+	Lparen int
+	Var    int
+	Mode   lexer.TokenKind
+	Name   *Ident
+	Type   *Ident
+	Rparen int
+}
+
+func (r *Receiver) Pos() int { return r.Lparen }
+func (r *Receiver) End() int { return r.Rparen + 1 }
+
+// ProcedureDeclaration = ProcedureHeading ";" ProcedureBody ident.
+// Receiver is nil unless the procedure is type-bound. New is the position
+// of the Component Pascal method attribute NEW, or NO_POS, and Attr that
+// of ABSTRACT, EMPTY or EXTENSIBLE, at AttrPos, or ILLEGAL. ABSTRACT and
+// EMPTY procedures have no body and no closing name. The body consists
+// of the local declarations, the statements following BEGIN and the
+// expression following RETURN, each of which is optional. In the dialects
+// with RETURN statements a RETURN is a statement of Body and Return is
+// nil.
+type ProcDecl struct {
+	Procedure int
+	Receiver  *Receiver
+	Name      *IdentDef
+	Params    *FormalParameters
+	New       int
+	Attr      lexer.TokenKind
+	AttrPos   int
+	Decls     []Decl
+	Body      []Stmt
+	Return    Expr
+	EndPos    int
+	EndName   *Ident
+}
+
+func (d *ImportDecl) Pos() int {
+	if d.Alias != nil {
+		return d.Alias.Pos()
+	}
+	return d.Module.Pos()
+}
+func (d *BadDecl) Pos() int   { return d.From }
+func (d *ConstDecl) Pos() int { return d.Name.Pos() }
+func (d *TypeDecl) Pos() int  { return d.Name.Pos() }
+func (d *VarDecl) Pos() int   { return d.Names[0].Pos() }
+func (d *ProcDecl) Pos() int  { return d.Procedure }
+
+func (d *BadDecl) End() int    { return d.To }
+func (d *ImportDecl) End() int { return d.Module.End() }
+func (d *ConstDecl) End() int  { return d.Value.End() }
+func (d *TypeDecl) End() int   { return d.Type.End() }
+func (d *VarDecl) End() int    { return d.Type.End() }
+func (d *ProcDecl) End() int {
+	if !d.HasBody() {
+		return d.AttrPos + len(d.Attr.String())
+	}
+	return d.EndName.End()
+}
+
+// HasBody reports whether the procedure has a body, which all but the
+// ABSTRACT and EMPTY ones have.
+func (d *ProcDecl) HasBody() bool {
+	return d.Attr != lexer.ABSTRACT && d.Attr != lexer.EMPTY
+}
+
+func (*BadDecl) declNode()    {}
+func (*ImportDecl) declNode() {}
+func (*ConstDecl) declNode()  {}
+func (*TypeDecl) declNode()   {}
+func (*VarDecl) declNode()    {}
+func (*ProcDecl) declNode()   {}
+
+// module = MODULE ident ";" [ImportList] DeclarationSequence
+// [BEGIN StatementSequence] END ident ".".
+type Module struct {
+	Module  int
+	Name    *Ident
+	Imports []*ImportDecl
+	Decls   []Decl
+	Body    []Stmt
+	EndName *Ident
+	Period  int
+}
+
+func (m *Module) Pos() int { return m.Module }
+func (m *Module) End() int { return m.Period + 1 }
@@ -0,0 +1,64 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// positionType is the type of the fields holding byte offsets.
+var positionType = reflect.TypeOf(NO_POS)
+
+// Fprint writes the tree rooted at node to w, one node per line, indented
+// by depth. Scalar fields are shown next to the node's type; positions and
+// absent nodes are left out.
+func Fprint(w io.Writer, node Node) {
+	fprint(w, reflect.ValueOf(node), "", 0)
+}
+
+func fprint(w io.Writer, value reflect.Value, name string, depth int) {
+	if value.Kind() == reflect.Interface {
+		value = value.Elem()
+	}
+	if !value.IsValid() || (value.Kind() == reflect.Ptr && value.IsNil()) {
+		return
+	}
+	var indentation = strings.Repeat("  ", depth)
+	if name != "" {
+		name += ": "
+	}
+	if value.Kind() == reflect.Slice {
+		for i := 0; i < value.Len(); i++ {
+			fprint(w, value.Index(i), fmt.Sprintf("%s[%d]", strings.TrimSuffix(name, ": "), i), depth)
+		}
+		return
+	}
+
+	var element = value.Elem()
+	var kind = element.Type()
+	var scalars []string
+	var children []int
+	for i := 0; i < kind.NumField(); i++ {
+		field := kind.Field(i)
+		fieldValue := element.Field(i)
+		switch {
+		case field.Type == positionType:
+			continue
+		case field.Type.Kind() == reflect.Ptr, field.Type.Kind() == reflect.Slice, field.Type.Kind() == reflect.Interface:
+			children = append(children, i)
+		case fieldValue.IsZero() && field.Name != "Value":
+			continue
+		default:
+			scalars = append(scalars, fmt.Sprintf("%s=%v", field.Name, fieldValue.Interface()))
+		}
+	}
+	fmt.Fprintf(w, "%s%s%s", indentation, name, kind.Name())
+	if len(scalars) > 0 {
+		fmt.Fprintf(w, " (%s)", strings.Join(scalars, ", "))
+	}
+	fmt.Fprintln(w)
+	for _, i := range children {
+		fprint(w, element.Field(i), kind.Field(i).Name, depth+1)
+	}
+}
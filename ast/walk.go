@@ -0,0 +1,266 @@
+package ast
+
+import "fmt"
+
+// Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of
+// node with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+func walkExprs(v Visitor, list []Expr) {
+	for _, x := range list {
+		Walk(v, x)
+	}
+}
+
+func walkStmts(v Visitor, list []Stmt) {
+	for _, s := range list {
+		Walk(v, s)
+	}
+}
+
+func walkDecls(v Visitor, list []Decl) {
+	for _, d := range list {
+		Walk(v, d)
+	}
+}
+
+// Walk traverses the tree rooted at node in depth-first order, visiting
+// children in source order.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *BadExpr, *BadStmt, *EmptyStmt, *ExitStmt, *BadDecl, *Ident, *BasicLit, *BoolLit, *NilLit, *DerefSelector:
+		// no children
+
+	case *ReturnStmt:
+		if n.X != nil {
+			Walk(v, n.X)
+		}
+
+	case *QualIdent:
+		if n.Module != nil {
+			Walk(v, n.Module)
+		}
+		Walk(v, n.Name)
+
+	case *Designator:
+		Walk(v, n.Qualident)
+		for _, selector := range n.Selectors {
+			Walk(v, selector)
+		}
+
+	case *CallExpr:
+		Walk(v, n.Fun)
+		walkExprs(v, n.Args)
+
+	case *SetExpr:
+		walkExprs(v, n.Elements)
+
+	case *RangeExpr:
+		Walk(v, n.Low)
+		Walk(v, n.High)
+
+	case *ParenExpr:
+		Walk(v, n.X)
+
+	case *UnaryExpr:
+		Walk(v, n.X)
+
+	case *BinaryExpr:
+		Walk(v, n.X)
+		Walk(v, n.Y)
+
+	case *FieldSelector:
+		Walk(v, n.Name)
+
+	case *IndexSelector:
+		walkExprs(v, n.Indices)
+
+	case *TypeGuardSelector:
+		Walk(v, n.Type)
+
+	case *ArrayType:
+		walkExprs(v, n.Lengths)
+		Walk(v, n.Elem)
+
+	case *RecordType:
+		if n.Base != nil {
+			Walk(v, n.Base)
+		}
+		for _, field := range n.Fields {
+			Walk(v, field)
+		}
+
+	case *FieldList:
+		for _, name := range n.Names {
+			Walk(v, name)
+		}
+		Walk(v, n.Type)
+
+	case *PointerType:
+		Walk(v, n.Base)
+
+	case *ProcedureType:
+		if n.Params != nil {
+			Walk(v, n.Params)
+		}
+
+	case *FormalParameters:
+		for _, section := range n.Sections {
+			Walk(v, section)
+		}
+		if n.Result != nil {
+			Walk(v, n.Result)
+		}
+
+	case *FPSection:
+		for _, name := range n.Names {
+			Walk(v, name)
+		}
+		Walk(v, n.Type)
+
+	case *AssignStmt:
+		Walk(v, n.Lhs)
+		Walk(v, n.Rhs)
+
+	case *CallStmt:
+		Walk(v, n.Call)
+
+	case *Elsif:
+		Walk(v, n.Cond)
+		walkStmts(v, n.Body)
+
+	case *IfStmt:
+		Walk(v, n.Cond)
+		walkStmts(v, n.Body)
+		for _, elsif := range n.Elsifs {
+			Walk(v, elsif)
+		}
+		walkStmts(v, n.Else)
+
+	case *CaseClause:
+		walkExprs(v, n.Labels)
+		walkStmts(v, n.Body)
+
+	case *CaseStmt:
+		Walk(v, n.X)
+		for _, clause := range n.Clauses {
+			Walk(v, clause)
+		}
+		walkStmts(v, n.Else)
+
+	case *WhileStmt:
+		Walk(v, n.Cond)
+		walkStmts(v, n.Body)
+		for _, elsif := range n.Elsifs {
+			Walk(v, elsif)
+		}
+
+	case *RepeatStmt:
+		walkStmts(v, n.Body)
+		Walk(v, n.Cond)
+
+	case *ForStmt:
+		Walk(v, n.Var)
+		Walk(v, n.Low)
+		Walk(v, n.High)
+		if n.By != nil {
+			Walk(v, n.By)
+		}
+		walkStmts(v, n.Body)
+
+	case *WithClause:
+		Walk(v, n.Var)
+		Walk(v, n.Type)
+		walkStmts(v, n.Body)
+
+	case *WithStmt:
+		for _, clause := range n.Clauses {
+			Walk(v, clause)
+		}
+		walkStmts(v, n.Else)
+
+	case *LoopStmt:
+		walkStmts(v, n.Body)
+
+	case *IdentDef:
+		Walk(v, n.Name)
+
+	case *Receiver:
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+
+	case *ImportDecl:
+		if n.Alias != nil {
+			Walk(v, n.Alias)
+		}
+		Walk(v, n.Module)
+
+	case *ConstDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Value)
+
+	case *TypeDecl:
+		Walk(v, n.Name)
+		Walk(v, n.Type)
+
+	case *VarDecl:
+		for _, name := range n.Names {
+			Walk(v, name)
+		}
+		Walk(v, n.Type)
+
+	case *ProcDecl:
+		if n.Receiver != nil {
+			Walk(v, n.Receiver)
+		}
+		Walk(v, n.Name)
+		if n.Params != nil {
+			Walk(v, n.Params)
+		}
+		walkDecls(v, n.Decls)
+		walkStmts(v, n.Body)
+		if n.Return != nil {
+			Walk(v, n.Return)
+		}
+		if n.EndName != nil {
+			Walk(v, n.EndName)
+		}
+
+	case *Module:
+		Walk(v, n.Name)
+		for _, decl := range n.Imports {
+			Walk(v, decl)
+		}
+		walkDecls(v, n.Decls)
+		walkStmts(v, n.Body)
+		Walk(v, n.EndName)
+
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// f for each node and, after its children, f(nil). If f returns false the
+// children of the node are skipped.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,130 @@
+package cst
+
+import (
+	"sort"
+	"strings"
+
+	ast "oberon/ast"
+	lexer "oberon/lexer"
+	source "oberon/source"
+)
+
+// Build constructs the concrete syntax tree of file from its AST, its
+// tokens and its comments, as returned by lexer.Lexer and parser.Parser.
+// Each AST node becomes a Node holding the tokens its span covers that
+// are not covered by one of its children; the text between tokens
+// becomes trivia.
+func Build(file *source.SourceFile, tree *ast.Module, tokens []lexer.Token, comments []lexer.Token) *File {
+	var b = builder{file: file, comments: comments}
+	b.attachTrivia(tokens)
+	root := b.node(tree)
+	for ; b.index < len(b.tokens); b.index++ {
+		root.Children = append(root.Children, b.tokens[b.index])
+	}
+	return &File{Module: root, EOF: b.eof}
+}
+
+type builder struct {
+	file     *source.SourceFile
+	comments []lexer.Token
+	tokens   []*Token
+	eof      *Token
+	index    int
+}
+
+// trivia splits the text in [start, end) into comments, whitespace runs
+// ending at a newline and skipped text.
+func (b *builder) trivia(start int, end int) []Trivia {
+	var trivia []Trivia
+	var contents = b.file.Contents
+	for start < end {
+		if len(b.comments) > 0 && b.comments[0].Offset == start {
+			comment := b.comments[0]
+			b.comments = b.comments[1:]
+			trivia = append(trivia, Trivia{Kind: COMMENT, Offset: start, Text: comment.Label})
+			start = comment.End
+			continue
+		}
+		var stop = start
+		var kind = WHITESPACE
+		if !isWhitespace(contents[start]) {
+			kind = SKIPPED
+		}
+		for stop < end && (len(b.comments) == 0 || b.comments[0].Offset != stop) && isWhitespace(contents[stop]) == (kind == WHITESPACE) {
+			stop++
+			if kind == WHITESPACE && contents[stop-1] == '\n' {
+				break
+			}
+		}
+		trivia = append(trivia, Trivia{Kind: kind, Offset: start, Text: string(contents[start:stop])})
+		start = stop
+	}
+	return trivia
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// attachTrivia wraps tokens and distributes the text between them over
+// the trailing trivia of one token and the leading trivia of the next.
+func (b *builder) attachTrivia(tokens []lexer.Token) {
+	var previous *Token
+	var offset = 0
+	var attach = func(next *Token, end int) {
+		trivia := b.trivia(offset, end)
+		if previous != nil {
+			var i = 0
+			for i < len(trivia) {
+				i++
+				if strings.Contains(trivia[i-1].Text, "\n") {
+					break
+				}
+			}
+			previous.Trailing, trivia = trivia[:i], trivia[i:]
+		}
+		next.Leading = trivia
+	}
+	for _, token := range tokens {
+		next := &Token{Token: token}
+		attach(next, token.Offset)
+		b.tokens = append(b.tokens, next)
+		previous = next
+		offset = token.End
+	}
+	b.eof = &Token{Token: lexer.Token{Kind: lexer.EOF, Offset: b.file.Size(), End: b.file.Size()}}
+	attach(b.eof, b.file.Size())
+}
+
+// children returns the direct children of node in source order.
+func children(node ast.Node) []ast.Node {
+	var nodes []ast.Node
+	ast.Inspect(node, func(child ast.Node) bool {
+		if child == node {
+			return true
+		}
+		if child != nil {
+			nodes = append(nodes, child)
+		}
+		return false
+	})
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Pos() < nodes[j].Pos()
+	})
+	return nodes
+}
+
+func (b *builder) node(node ast.Node) *Node {
+	var result = &Node{Node: node}
+	var nodes = children(node)
+	for b.index < len(b.tokens) && b.tokens[b.index].Offset < node.End() {
+		if len(nodes) > 0 && b.tokens[b.index].Offset >= nodes[0].Pos() {
+			result.Children = append(result.Children, b.node(nodes[0]))
+			nodes = nodes[1:]
+			continue
+		}
+		result.Children = append(result.Children, b.tokens[b.index])
+		b.index++
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package cst
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	ast "oberon/ast"
+	lexer "oberon/lexer"
+)
+
+// TriviaKind classifies the text between two tokens.
+type TriviaKind int
+
+const (
+	WHITESPACE TriviaKind = iota
+	COMMENT
+	// SKIPPED is text that is neither whitespace nor a comment, such as a
+	// byte order mark; it is kept so that no input is lost.
+	SKIPPED
+)
+
+func (k TriviaKind) String() string {
+	switch k {
+	case WHITESPACE:
+		return "WHITESPACE"
+	case COMMENT:
+		return "COMMENT"
+	case SKIPPED:
+		return "SKIPPED"
+	}
+	return fmt.Sprintf("TriviaKind(%d)", int(k))
+}
+
+// Trivia is a run of whitespace, a comment or skipped text. A whitespace
+// run never extends past a newline.
+type Trivia struct {
+	Kind   TriviaKind
+	Offset int
+	Text   string
+}
+
+// Element is a Token or a Node. Pos and End exclude the trivia.
+type Element interface {
+	Pos() int
+	End() int
+	element()
+}
+
+// Token is a lexer token together with its trivia. Trailing trivia runs
+// up to and including the first newline after the token; everything
+// after that is leading trivia of the next token.
+type Token struct {
+	lexer.Token
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// Node is the concrete counterpart of an AST node. Its children are the
+// tokens and nodes it is made of, in source order.
+type Node struct {
+	Node     ast.Node
+	Children []Element
+}
+
+// File is the concrete syntax tree of a whole source file. Trivia after
+// the last token of the module is the leading trivia of EOF.
+type File struct {
+	Module *Node
+	EOF    *Token
+}
+
+func (t *Token) Pos() int { return t.Offset }
+func (t *Token) End() int { return t.Token.End }
+
+func (n *Node) Pos() int {
+	if len(n.Children) == 0 {
+		return n.Node.Pos()
+	}
+	return n.Children[0].Pos()
+}
+
+func (n *Node) End() int {
+	if len(n.Children) == 0 {
+		return n.Node.End()
+	}
+	return n.Children[len(n.Children)-1].End()
+}
+
+func (*Token) element() {}
+func (*Node) element()  {}
+
+// Tokens returns the tokens of element in source order.
+func Tokens(element Element) []*Token {
+	var tokens []*Token
+	var collect func(Element)
+	collect = func(element Element) {
+		switch e := element.(type) {
+		case *Token:
+			tokens = append(tokens, e)
+		case *Node:
+			for _, child := range e.Children {
+				collect(child)
+			}
+		}
+	}
+	collect(element)
+	return tokens
+}
+
+func writeTrivia(w *strings.Builder, trivia []Trivia) {
+	for _, t := range trivia {
+		w.WriteString(t.Text)
+	}
+}
+
+// Text returns the source text of element, including the trivia of its
+// tokens.
+func Text(element Element) string {
+	var w strings.Builder
+	for _, token := range Tokens(element) {
+		writeTrivia(&w, token.Leading)
+		w.WriteString(token.Label)
+		writeTrivia(&w, token.Trailing)
+	}
+	return w.String()
+}
+
+// Write writes the source text of file to w. For a tree built from a
+// file this reproduces the file byte for byte.
+func Write(w io.Writer, file *File) error {
+	var text strings.Builder
+	text.WriteString(Text(file.Module))
+	writeTrivia(&text, file.EOF.Leading)
+	_, err := io.WriteString(w, text.String())
+	return err
+}
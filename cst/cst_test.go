@@ -0,0 +1,142 @@
+package cst
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	diag "oberon/diag"
+	lexer "oberon/lexer"
+	parser "oberon/parser"
+	source "oberon/source"
+)
+
+// dialectOf returns the dialect the example name is written in.
+func dialectOf(name string) *lexer.Dialect {
+	switch {
+	case strings.Contains(name, "component_pascal"):
+		return lexer.COMPONENT_PASCAL
+	case strings.Contains(name, "oberon2"):
+		return lexer.OBERON2
+	}
+	return lexer.OBERON07
+}
+
+// roundTrip builds the concrete syntax tree of file and checks that
+// writing it gives back the contents of file byte for byte.
+func roundTrip(t *testing.T, file *source.SourceFile, dialect *lexer.Dialect) {
+	t.Helper()
+	result, _ := lexer.Lexer(file, lexer.Options{Dialect: dialect, UnicodeIdentifiers: true}, false)
+	tree, err := parser.Parser(file, result.Tokens, dialect, diag.NewReporter(), false)
+	if tree == nil && err != nil {
+		return // the parser gave up on the file: there is no tree to write
+	}
+	if tree == nil {
+		t.Fatalf("%s: no syntax tree", file.Name)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, Build(file, tree, *result.Tokens, *result.Comments)); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), file.Contents) {
+		t.Errorf("%s: the tree is written as\n%s", file.Name, out.Bytes())
+	}
+}
+
+func TestWriteExamples(t *testing.T) {
+	names, err := filepath.Glob("../examples/*.ob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		file, err := source.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roundTrip(t, file, dialectOf(name))
+		crlf := bytes.Replace(file.Contents, []byte("\n"), []byte("\r\n"), -1)
+		roundTrip(t, source.NewSourceFile(name+" (CRLF)", crlf), dialectOf(name))
+	}
+}
+
+func TestWriteNestedComments(t *testing.T) {
+	var text = "(* a (* nested (* comment *) *) first *)\r\n" +
+		"MODULE M; (* (* after *) the header *)\r\n" +
+		"  VAR x (* (**) *): INTEGER;\n" +
+		"BEGIN x := 1 (* (* *) *)END M. (* trailing (* *) *)\r\n"
+	roundTrip(t, source.NewSourceFile("nested.ob", []byte(text)), lexer.OBERON07)
+}
+
+// shape writes element as nested parentheses: a node as its AST type
+// and children, a token as its text.
+func shape(element Element) string {
+	switch e := element.(type) {
+	case *Token:
+		return e.Label
+	case *Node:
+		var parts = []string{strings.TrimPrefix(fmt.Sprintf("%T", e.Node), "*ast.")}
+		for _, child := range e.Children {
+			parts = append(parts, shape(child))
+		}
+		return "(" + strings.Join(parts, " ") + ")"
+	}
+	return ""
+}
+
+func TestBuildProcedure(t *testing.T) {
+	var text = "MODULE M;\n" +
+		"  PROCEDURE P*(x: INTEGER); (* heading *)\n" +
+		"  BEGIN x := x + 1 (* body *)\n" +
+		"  END P; (* procedure *)\n" +
+		"END M.\n"
+	var file = source.NewSourceFile("procedure.ob", []byte(text))
+	result, _ := lexer.Lexer(file, lexer.Options{}, false)
+	tree, err := parser.Parser(file, result.Tokens, lexer.OBERON07, diag.NewReporter(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var built = Build(file, tree, *result.Tokens, *result.Comments)
+	var want = "(Module MODULE (Ident M) ; " +
+		"(ProcDecl PROCEDURE (IdentDef (Ident P) *) " +
+		"(FormalParameters ( (FPSection (Ident x) : (QualIdent (Ident INTEGER))) )) ; " +
+		"BEGIN (AssignStmt (Designator (QualIdent (Ident x))) := " +
+		"(BinaryExpr (Designator (QualIdent (Ident x))) + (BasicLit 1))) " +
+		"END (Ident P)) ; END (Ident M) .)"
+	if got := shape(built.Module); got != want {
+		t.Errorf("the tree is\n%s\nnot\n%s", got, want)
+	}
+
+	// Each comment trails the token before it on its line, up to the
+	// newline; the indentation of the next line leads the next token.
+	var tokens = Tokens(built.Module)
+	for _, test := range []struct{ token, comment, indentation string }{
+		{";", "(* heading *)", "  "},
+		{"1", "(* body *)", "  "},
+		{";", "(* procedure *)", ""},
+	} {
+		var at = strings.Index(text, test.token+" "+test.comment)
+		for i, token := range tokens {
+			if token.Offset != at {
+				continue
+			}
+			var kinds []TriviaKind
+			for _, trivia := range token.Trailing {
+				kinds = append(kinds, trivia.Kind)
+			}
+			if want := []TriviaKind{WHITESPACE, COMMENT, WHITESPACE}; fmt.Sprint(kinds) != fmt.Sprint(want) || token.Trailing[1].Text != test.comment {
+				t.Errorf("%s: trailing trivia %v of kinds %v", test.comment, token.Trailing, kinds)
+			}
+			var leading strings.Builder
+			writeTrivia(&leading, tokens[i+1].Leading)
+			if leading.String() != test.indentation {
+				t.Errorf("%s: %s has the leading trivia %v", test.comment, tokens[i+1].Label, tokens[i+1].Leading)
+			}
+			at = -1
+		}
+		if at >= 0 {
+			t.Errorf("%s: no token at %d", test.comment, at)
+		}
+	}
+}
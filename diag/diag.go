@@ -0,0 +1,138 @@
+package diag
+
+import (
+	"fmt"
+	"sort"
+
+	source "oberon/source"
+)
+
+type Severity int
+
+const (
+	ERROR Severity = iota
+	WARNING
+	NOTE
+)
+
+func (s Severity) String() string {
+	switch s {
+	case ERROR:
+		return "error"
+	case WARNING:
+		return "warning"
+	case NOTE:
+		return "note"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Note adds context to a diagnostic. A note without a span (a zero
+// Span.Start.Line) is not tied to a location.
+type Note struct {
+	Message string
+	Span    source.Span
+}
+
+// Suggestion proposes replacing the text covered by Span with
+// Replacement.
+type Suggestion struct {
+	Message     string
+	Span        source.Span
+	Replacement string
+}
+
+type Diagnostic struct {
+	Severity    Severity
+	Code        string
+	Message     string
+	Span        source.Span
+	Notes       []Note
+	Suggestions []Suggestion
+}
+
+func Errorf(code string, span source.Span, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: ERROR, Code: code, Message: fmt.Sprintf(format, args...), Span: span}
+}
+
+func Warningf(code string, span source.Span, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: WARNING, Code: code, Message: fmt.Sprintf(format, args...), Span: span}
+}
+
+func Notef(code string, span source.Span, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: NOTE, Code: code, Message: fmt.Sprintf(format, args...), Span: span}
+}
+
+// WithNote returns a copy of d with a note attached.
+func (d Diagnostic) WithNote(span source.Span, format string, args ...interface{}) Diagnostic {
+	d.Notes = append(append([]Note(nil), d.Notes...), Note{Message: fmt.Sprintf(format, args...), Span: span})
+	return d
+}
+
+// WithSuggestion returns a copy of d with a suggested replacement
+// attached.
+func (d Diagnostic) WithSuggestion(span source.Span, replacement string, format string, args ...interface{}) Diagnostic {
+	d.Suggestions = append(append([]Suggestion(nil), d.Suggestions...), Suggestion{
+		Message:     fmt.Sprintf(format, args...),
+		Span:        span,
+		Replacement: replacement,
+	})
+	return d
+}
+
+func (d Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s[%s]: %s", d.Span.Start, d.Severity, d.Code, d.Message)
+}
+
+// Reporter collects the diagnostics of every phase of a compilation.
+type Reporter struct {
+	diagnostics []Diagnostic
+}
+
+func NewReporter() *Reporter {
+	return new(Reporter)
+}
+
+func (r *Reporter) Report(diagnostics ...Diagnostic) {
+	r.diagnostics = append(r.diagnostics, diagnostics...)
+}
+
+// Diagnostics returns the reported diagnostics ordered by file and
+// position; diagnostics at the same position keep the order in which they
+// were reported.
+func (r *Reporter) Diagnostics() []Diagnostic {
+	var sorted = append([]Diagnostic(nil), r.diagnostics...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].Span.Start, sorted[j].Span.Start
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Offset < b.Offset
+	})
+	return sorted
+}
+
+func (r *Reporter) Count(severity Severity) int {
+	var count = 0
+	for _, diagnostic := range r.diagnostics {
+		if diagnostic.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Reporter) HasErrors() bool {
+	return r.Count(ERROR) > 0
+}
+
+// Err returns the first error in source order, or nil if no error has
+// been reported.
+func (r *Reporter) Err() error {
+	for _, diagnostic := range r.Diagnostics() {
+		if diagnostic.Severity == ERROR {
+			return diagnostic
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package diag
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	source "oberon/source"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// mismatch is a diagnostic with a note and a suggestion, as the analyzer
+// reports for a procedure that ends with another name.
+func mismatch() []Diagnostic {
+	var file = source.NewSourceFile("examples/max.ob", []byte(
+		"MODULE M;\n"+
+			"    PROCEDURE Max(a, b: INTEGER): INTEGER;\n"+
+			"    BEGIN RETURN a\n"+
+			"    END Min;\n"+
+			"END M.\n"))
+	return []Diagnostic{
+		Errorf("S003", file.Span(80, 83), "procedure Max ends with the name Min").
+			WithNote(file.Span(24, 27), "procedure Max is declared here").
+			WithSuggestion(file.Span(80, 83), "Max", "end the procedure with its own name"),
+	}
+}
+
+// golden compares what write writes for diagnostics with the file name
+// in testdata.
+func golden(t *testing.T, name string, write func(io.Writer, []Diagnostic) error, diagnostics []Diagnostic) {
+	t.Helper()
+	var out bytes.Buffer
+	if err := write(&out, diagnostics); err != nil {
+		t.Fatal(err)
+	}
+	var path = filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("%s differs:\n%s", path, out.Bytes())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	golden(t, "mismatch.jsonl", WriteJSON, mismatch())
+}
+
+func TestWriteSARIF(t *testing.T) {
+	golden(t, "mismatch.sarif", WriteSARIF, mismatch())
+}
@@ -0,0 +1,80 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+
+	source "oberon/source"
+)
+
+type jsonLocation struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndLine   int    `json:"endLine"`
+	EndColumn int    `json:"endColumn"`
+}
+
+type jsonNote struct {
+	*jsonLocation
+	Message string `json:"message"`
+}
+
+type jsonSuggestion struct {
+	jsonLocation
+	Message     string `json:"message"`
+	Replacement string `json:"replacement"`
+}
+
+type jsonDiagnostic struct {
+	jsonLocation
+	Severity    string           `json:"severity"`
+	Code        string           `json:"code"`
+	Message     string           `json:"message"`
+	Notes       []jsonNote       `json:"notes,omitempty"`
+	Suggestions []jsonSuggestion `json:"suggestions,omitempty"`
+}
+
+func newJSONLocation(span source.Span) jsonLocation {
+	return jsonLocation{
+		File:      span.Start.Filename,
+		Line:      span.Start.Line,
+		Column:    span.Start.Column,
+		EndLine:   span.End.Line,
+		EndColumn: span.End.Column,
+	}
+}
+
+// WriteJSON writes diagnostics as JSON Lines: one object per line, each
+// holding the file, line, column, code and message of a diagnostic.
+// Columns are 1-based byte columns, as in the text output.
+func WriteJSON(writer io.Writer, diagnostics []Diagnostic) error {
+	var encoder = json.NewEncoder(writer)
+	for _, diagnostic := range diagnostics {
+		var object = jsonDiagnostic{
+			jsonLocation: newJSONLocation(diagnostic.Span),
+			Severity:     diagnostic.Severity.String(),
+			Code:         diagnostic.Code,
+			Message:      diagnostic.Message,
+		}
+		for _, note := range diagnostic.Notes {
+			var converted = jsonNote{Message: note.Message}
+			if note.Span.Start.Line > 0 {
+				location := newJSONLocation(note.Span)
+				converted.jsonLocation = &location
+			}
+			object.Notes = append(object.Notes, converted)
+		}
+		for _, suggestion := range diagnostic.Suggestions {
+			object.Suggestions = append(object.Suggestions, jsonSuggestion{
+				jsonLocation: newJSONLocation(suggestion.Span),
+				Message:      suggestion.Message,
+				Replacement:  suggestion.Replacement,
+			})
+		}
+		if err := encoder.Encode(object); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+
+	source "oberon/source"
+)
+
+// Renderer prints diagnostics in the style of rustc: a header line, the
+// location, and the offending source line with the span underlined.
+type Renderer struct {
+	writer io.Writer
+	files  map[string]*source.SourceFile
+
+	severity map[Severity]*color.Color
+	accent   *color.Color
+	bold     *color.Color
+	help     *color.Color
+}
+
+func NewRenderer(writer io.Writer, colored bool, files ...*source.SourceFile) *Renderer {
+	var renderer = &Renderer{
+		writer: writer,
+		files:  make(map[string]*source.SourceFile),
+		severity: map[Severity]*color.Color{
+			ERROR:   color.New(color.FgRed, color.Bold),
+			WARNING: color.New(color.FgYellow, color.Bold),
+			NOTE:    color.New(color.FgCyan, color.Bold),
+		},
+		accent: color.New(color.FgBlue, color.Bold),
+		bold:   color.New(color.Bold),
+		help:   color.New(color.FgGreen, color.Bold),
+	}
+	for _, file := range files {
+		renderer.files[file.Name] = file
+	}
+	for _, c := range []*color.Color{renderer.severity[ERROR], renderer.severity[WARNING], renderer.severity[NOTE], renderer.accent, renderer.bold, renderer.help} {
+		if colored {
+			c.EnableColor()
+		} else {
+			c.DisableColor()
+		}
+	}
+	return renderer
+}
+
+func (r *Renderer) Render(diagnostics ...Diagnostic) {
+	for _, diagnostic := range diagnostics {
+		r.render(diagnostic)
+	}
+}
+
+// Summary prints the number of errors and warnings, e.g.
+// "error: 2 errors, 1 warning emitted".
+func (r *Renderer) Summary(reporter *Reporter) {
+	var errors = reporter.Count(ERROR)
+	var warnings = reporter.Count(WARNING)
+	if errors == 0 && warnings == 0 {
+		return
+	}
+	var parts []string
+	if errors > 0 {
+		parts = append(parts, plural(errors, "error"))
+	}
+	if warnings > 0 {
+		parts = append(parts, plural(warnings, "warning"))
+	}
+	var severity = ERROR
+	if errors == 0 {
+		severity = WARNING
+	}
+	fmt.Fprintf(r.writer, "%s%s\n", r.severity[severity].Sprint(severity.String()), r.bold.Sprintf(": %s emitted", strings.Join(parts, ", ")))
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+func (r *Renderer) render(d Diagnostic) {
+	var severity = r.severity[d.Severity]
+	var header = d.Severity.String()
+	if d.Code != "" {
+		header = fmt.Sprintf("%s[%s]", header, d.Code)
+	}
+	fmt.Fprintf(r.writer, "%s%s\n", severity.Sprint(header), r.bold.Sprintf(": %s", d.Message))
+
+	var gutter = strings.Repeat(" ", len(strconv.Itoa(d.Span.End.Line)))
+	fmt.Fprintf(r.writer, "%s%s %s\n", gutter, r.accent.Sprint("-->"), d.Span.Start)
+	if r.snippet(gutter, d.Span, severity, "") {
+		for _, note := range d.Notes {
+			if note.Span.Start.Line > 0 && note.Span.Start.Filename == d.Span.Start.Filename {
+				r.snippet(gutter, note.Span, r.accent, note.Message)
+			}
+		}
+	}
+	for _, note := range d.Notes {
+		if note.Span.Start.Line == 0 || note.Span.Start.Filename != d.Span.Start.Filename {
+			fmt.Fprintf(r.writer, "%s %s %s\n", gutter, r.accent.Sprint("="), r.bold.Sprintf("note: %s", note.Message))
+		}
+	}
+	for _, suggestion := range d.Suggestions {
+		var message = suggestion.Message
+		if suggestion.Replacement != "" {
+			message = fmt.Sprintf("%s: `%s`", message, suggestion.Replacement)
+		}
+		fmt.Fprintf(r.writer, "%s %s %s\n", gutter, r.accent.Sprint("="), r.help.Sprintf("help: %s", message))
+	}
+	fmt.Fprintln(r.writer)
+}
+
+// snippet prints the first line of span with the spanned text underlined
+// by carets and followed by label. It reports false if the source of the
+// span is not available.
+func (r *Renderer) snippet(gutter string, span source.Span, underline *color.Color, label string) bool {
+	var file = r.files[span.Start.Filename]
+	if file == nil || span.Start.Line < 1 || span.Start.Line > file.LineCount() {
+		return false
+	}
+	var line = file.Line(span.Start.Line)
+	var start = span.Start.Column - 1
+	if start > len(line) {
+		start = len(line)
+	}
+	var end = len(line)
+	if span.End.Line == span.Start.Line && span.End.Column-1 < end {
+		end = span.End.Column - 1
+	}
+
+	// Pad with the same whitespace as the source line so that tabs line
+	// up, and draw one caret per character rather than per byte.
+	var padding strings.Builder
+	for _, ch := range string(line[:start]) {
+		if ch == '\t' {
+			padding.WriteRune('\t')
+		} else {
+			padding.WriteRune(' ')
+		}
+	}
+	var width = utf8.RuneCount(line[start:end])
+	if width == 0 {
+		width = 1
+	}
+	var carets = strings.Repeat("^", width)
+	if label != "" {
+		carets += " " + label
+	}
+
+	var number = strconv.Itoa(span.Start.Line)
+	number = strings.Repeat(" ", len(gutter)-len(number)) + number
+	fmt.Fprintf(r.writer, "%s %s\n", gutter, r.accent.Sprint("|"))
+	fmt.Fprintf(r.writer, "%s %s %s\n", r.accent.Sprint(number), r.accent.Sprint("|"), line)
+	fmt.Fprintf(r.writer, "%s %s %s%s\n", gutter, r.accent.Sprint("|"), padding.String(), underline.Sprint(carets))
+	return true
+}
@@ -0,0 +1,181 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+
+	source "oberon/source"
+)
+
+const (
+	SARIF_VERSION = "2.1.0"
+	SARIF_SCHEMA  = "https://json.schemastore.org/sarif-2.1.0.json"
+	TOOL_NAME     = "oberon"
+)
+
+// The types below model the subset of SARIF 2.1.0 that the compiler
+// produces.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	Id string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId           string          `json:"ruleId,omitempty"`
+	RuleIndex        int             `json:"ruleIndex"`
+	Level            string          `json:"level"`
+	Message          sarifMessage    `json:"message"`
+	Locations        []sarifLocation `json:"locations"`
+	RelatedLocations []sarifLocation `json:"relatedLocations,omitempty"`
+	Fixes            []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	Id               *int                  `json:"id,omitempty"`
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	Message          *sarifMessage         `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+// sarifRegion columns are counted in UTF-16 code units, the SARIF
+// default column kind.
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion  `json:"deletedRegion"`
+	InsertedContent sarifMessage `json:"insertedContent"`
+}
+
+func sarifUri(filename string) sarifArtifactLocation {
+	return sarifArtifactLocation{Uri: (&url.URL{Path: filepath.ToSlash(filename)}).String()}
+}
+
+func newSarifPhysicalLocation(span source.Span) sarifPhysicalLocation {
+	return sarifPhysicalLocation{
+		ArtifactLocation: sarifUri(span.Start.Filename),
+		Region: sarifRegion{
+			StartLine:   span.Start.Line,
+			StartColumn: span.Start.UTF16Column,
+			EndLine:     span.End.Line,
+			EndColumn:   span.End.UTF16Column,
+		},
+	}
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case WARNING:
+		return "warning"
+	case NOTE:
+		return "note"
+	}
+	return "error"
+}
+
+// WriteSARIF writes diagnostics as a SARIF 2.1.0 log with a single run.
+// Every distinct diagnostic code becomes a rule of the tool. Notes with a
+// span become related locations, the others are appended to the message,
+// and suggestions become fixes.
+func WriteSARIF(writer io.Writer, diagnostics []Diagnostic) error {
+	var driver = sarifDriver{Name: TOOL_NAME}
+	var ruleIndex = make(map[string]int)
+	var results = []sarifResult{}
+	for _, diagnostic := range diagnostics {
+		index, ok := ruleIndex[diagnostic.Code]
+		if !ok {
+			index = len(driver.Rules)
+			ruleIndex[diagnostic.Code] = index
+			driver.Rules = append(driver.Rules, sarifRule{Id: diagnostic.Code})
+		}
+		var result = sarifResult{
+			RuleId:    diagnostic.Code,
+			RuleIndex: index,
+			Level:     sarifLevel(diagnostic.Severity),
+			Message:   sarifMessage{Text: diagnostic.Message},
+			Locations: []sarifLocation{{PhysicalLocation: newSarifPhysicalLocation(diagnostic.Span)}},
+		}
+		for _, note := range diagnostic.Notes {
+			if note.Span.Start.Line == 0 {
+				result.Message.Text += "\nnote: " + note.Message
+				continue
+			}
+			id := len(result.RelatedLocations) + 1
+			result.RelatedLocations = append(result.RelatedLocations, sarifLocation{
+				Id:               &id,
+				PhysicalLocation: newSarifPhysicalLocation(note.Span),
+				Message:          &sarifMessage{Text: note.Message},
+			})
+		}
+		for _, suggestion := range diagnostic.Suggestions {
+			location := newSarifPhysicalLocation(suggestion.Span)
+			result.Fixes = append(result.Fixes, sarifFix{
+				Description: sarifMessage{Text: suggestion.Message},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: location.ArtifactLocation,
+					Replacements: []sarifReplacement{{
+						DeletedRegion:   location.Region,
+						InsertedContent: sarifMessage{Text: suggestion.Replacement},
+					}},
+				}},
+			})
+		}
+		results = append(results, result)
+	}
+	var log = sarifLog{
+		Schema:  SARIF_SCHEMA,
+		Version: SARIF_VERSION,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	var encoder = json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(log)
+}
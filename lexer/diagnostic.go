@@ -0,0 +1,26 @@
+package lexer
+
+// Codes of the lexical diagnostics.
+const (
+	UNRECOGNIZED_TOKEN  = "L001"
+	UNCLOSED_COMMENT    = "L002"
+	UNFINISHED_STRING   = "L003"
+	INVALID_UTF8        = "L004"
+	MALFORMED_NUMBER    = "L005"
+	MISSING_HEX_SUFFIX  = "L006"
+	INTEGER_OVERFLOW    = "L007"
+	REAL_OVERFLOW       = "L008"
+	CHAR_OUT_OF_RANGE   = "L009"
+	UNICODE_IDENTIFIERS = "L010"
+)
+
+// literalError is returned by decodeLiteral for values that cannot be
+// represented.
+type literalError struct {
+	code    string
+	message string
+}
+
+func (e literalError) Error() string {
+	return e.message
+}
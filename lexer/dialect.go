@@ -0,0 +1,196 @@
+package lexer
+
+// Extension is a construct of the grammar that some dialects have in
+// addition to those of Oberon-07, or a rule of their type system, as
+// NUMERIC_TYPE_INCLUSION. Extensions are combined as bits.
+type Extension uint
+
+const (
+	TYPE_BOUND_PROCEDURES Extension = 1 << iota
+	READ_ONLY_EXPORTS
+	OPEN_ARRAY_TYPES
+	RECORD_ATTRIBUTES
+	METHOD_ATTRIBUTES
+	IN_AND_OUT_PARAMETERS
+	IN_RECEIVERS
+	NUMERIC_TYPE_INCLUSION
+	RETURN_STATEMENTS
+	CASE_ELSE
+)
+
+// EXTENSION_NAMES are the names of the extensions shown in diagnostics.
+var EXTENSION_NAMES = map[Extension]string{
+	TYPE_BOUND_PROCEDURES: "type-bound procedures",
+	READ_ONLY_EXPORTS:     "read-only export marks",
+	OPEN_ARRAY_TYPES:      "open array types",
+	RECORD_ATTRIBUTES:     "record attributes",
+	METHOD_ATTRIBUTES:     "method attributes",
+	IN_AND_OUT_PARAMETERS: "IN and OUT parameters",
+	IN_RECEIVERS:          "IN receivers",
+
+	NUMERIC_TYPE_INCLUSION: "expressions of mixed numeric types",
+	RETURN_STATEMENTS:      "RETURN statements",
+	CASE_ELSE:              "ELSE parts of CASE statements",
+}
+
+func (e Extension) String() string {
+	return EXTENSION_NAMES[e]
+}
+
+// Dialect describes the language report a source file is read against:
+// its reserved words, its predeclared identifiers and the constructs its
+// grammar has beyond those of Oberon-07. Reserved words of other dialects
+// are scanned as identifiers.
+type Dialect struct {
+	Name   string // as in "Oberon-07"
+	Option string // the value of --dialect that selects the dialect
+
+	ReservedWords map[string]TokenKind
+
+	// The predeclared identifiers, by the kind of object they denote.
+	PredeclaredTypes      map[string]bool
+	PredeclaredConstants  map[string]bool
+	PredeclaredProcedures map[string]bool
+
+	Extensions Extension
+}
+
+// OBERON2_RESERVED_WORDS are the reserved words Oberon-2 and Component
+// Pascal have in addition to RESERVED_WORDS. In Oberon-07 they are
+// ordinary identifiers.
+var OBERON2_RESERVED_WORDS = map[string]TokenKind{
+	"EXIT": EXIT,
+	"LOOP": LOOP,
+	"WITH": WITH,
+}
+
+// COMPONENT_PASCAL_RESERVED_WORDS are the reserved words Component Pascal
+// has in addition to OBERON2_RESERVED_WORDS.
+var COMPONENT_PASCAL_RESERVED_WORDS = map[string]TokenKind{
+	"ABSTRACT":   ABSTRACT,
+	"EMPTY":      EMPTY,
+	"EXTENSIBLE": EXTENSIBLE,
+	"LIMITED":    LIMITED,
+	"OUT":        OUT,
+}
+
+// OBERON07 is the language of the Oberon-07 report, revised 2016. TRUE
+// and FALSE are reserved words there, not predeclared constants.
+var OBERON07 = &Dialect{
+	Name:   "Oberon-07",
+	Option: "oberon07",
+	ReservedWords: reservedWords(RESERVED_WORDS, map[string]TokenKind{
+		"FALSE": FALSE,
+		"TRUE":  TRUE,
+	}),
+	PredeclaredTypes: names("BOOLEAN", "BYTE", "CHAR", "INTEGER", "REAL", "SET"),
+	PredeclaredProcedures: names(
+		"ABS", "ASR", "CHR", "FLOOR", "FLT", "LEN", "LSL", "ODD", "ORD", "ROR",
+		"ASSERT", "DEC", "EXCL", "INC", "INCL", "NEW", "PACK", "UNPK",
+	),
+}
+
+// OBERON2 is the language of the Oberon-2 report of 1993.
+var OBERON2 = &Dialect{
+	Name:          "Oberon-2",
+	Option:        "oberon2",
+	ReservedWords: reservedWords(RESERVED_WORDS, OBERON2_RESERVED_WORDS),
+	PredeclaredTypes: names(
+		"BOOLEAN", "CHAR", "INTEGER", "LONGINT", "LONGREAL", "REAL", "SET", "SHORTINT",
+	),
+	PredeclaredConstants: names("FALSE", "TRUE"),
+	PredeclaredProcedures: names(
+		"ABS", "ASH", "CAP", "CHR", "ENTIER", "LEN", "LONG", "MAX", "MIN", "ODD", "ORD", "SHORT", "SIZE",
+		"ASSERT", "COPY", "DEC", "EXCL", "HALT", "INC", "INCL", "NEW",
+	),
+	Extensions: TYPE_BOUND_PROCEDURES | READ_ONLY_EXPORTS | OPEN_ARRAY_TYPES | NUMERIC_TYPE_INCLUSION |
+		RETURN_STATEMENTS | CASE_ELSE,
+}
+
+// COMPONENT_PASCAL is the language of the Component Pascal report,
+// revised 2004.
+var COMPONENT_PASCAL = &Dialect{
+	Name:          "Component Pascal",
+	Option:        "cp",
+	ReservedWords: reservedWords(RESERVED_WORDS, OBERON2_RESERVED_WORDS, COMPONENT_PASCAL_RESERVED_WORDS),
+	PredeclaredTypes: names(
+		"ANYPTR", "ANYREC", "BOOLEAN", "BYTE", "CHAR", "INTEGER", "LONGINT", "REAL", "SET",
+		"SHORTCHAR", "SHORTINT", "SHORTREAL",
+	),
+	PredeclaredConstants: names("FALSE", "INF", "TRUE"),
+	PredeclaredProcedures: names(
+		"ABS", "ASH", "BITS", "CAP", "CHR", "ENTIER", "LEN", "LONG", "MAX", "MIN", "ODD", "ORD", "SHORT", "SIZE",
+		"ASSERT", "DEC", "EXCL", "HALT", "INC", "INCL", "NEW",
+	),
+	Extensions: TYPE_BOUND_PROCEDURES | READ_ONLY_EXPORTS | OPEN_ARRAY_TYPES |
+		RECORD_ATTRIBUTES | METHOD_ATTRIBUTES | IN_AND_OUT_PARAMETERS | IN_RECEIVERS |
+		NUMERIC_TYPE_INCLUSION | RETURN_STATEMENTS | CASE_ELSE,
+}
+
+// DIALECTS are the dialects source files can be read against, in the
+// order their reports appeared.
+var DIALECTS = []*Dialect{OBERON07, OBERON2, COMPONENT_PASCAL}
+
+// LookupDialect returns the dialect that option, a value of --dialect,
+// selects.
+func LookupDialect(option string) (*Dialect, bool) {
+	for _, dialect := range DIALECTS {
+		if dialect.Option == option {
+			return dialect, true
+		}
+	}
+	return nil, false
+}
+
+func reservedWords(tables ...map[string]TokenKind) map[string]TokenKind {
+	var words = make(map[string]TokenKind)
+	for _, table := range tables {
+		for lexeme, kind := range table {
+			words[lexeme] = kind
+		}
+	}
+	return words
+}
+
+func names(list ...string) map[string]bool {
+	var set = make(map[string]bool, len(list))
+	for _, name := range list {
+		set[name] = true
+	}
+	return set
+}
+
+func (d *Dialect) String() string {
+	return d.Name
+}
+
+// ReservedWord returns the kind of lexeme if it is a reserved word of d.
+func (d *Dialect) ReservedWord(lexeme string) (TokenKind, bool) {
+	keyword, ok := d.ReservedWords[lexeme]
+	return keyword, ok
+}
+
+// HasToken reports whether source text of d can contain tokens of kind.
+// Every kind but the reserved words of other dialects can occur.
+func (d *Dialect) HasToken(kind TokenKind) bool {
+	if !kind.IsKeyword() {
+		return true
+	}
+	for _, keyword := range d.ReservedWords {
+		if keyword == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether the grammar of d has the construct extension.
+func (d *Dialect) Has(extension Extension) bool {
+	return d.Extensions&extension != 0
+}
+
+// IsPredeclared reports whether name is predeclared in d, as a type, a
+// constant or a procedure.
+func (d *Dialect) IsPredeclared(name string) bool {
+	return d.PredeclaredTypes[name] || d.PredeclaredConstants[name] || d.PredeclaredProcedures[name]
+}
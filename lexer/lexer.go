@@ -1,142 +1,106 @@
 package lexer
 
 import (
-	"fmt"
-)
-
-type Lexemetype int
+	"math"
+	"strconv"
+	"strings"
 
-const (
-	CHAR          int = 1
-	INTEGER           = 2
-	REAL              = 3
-	STRING            = 4
-	COMMENT           = 5
-	KEYWORD           = 6
-	PREDEFINED        = 7
-	IDENT             = 8
-	OP_OR_DELIM       = 9
-	RESERVED_WORD     = 10
+	diag "oberon/diag"
+	source "oberon/source"
 )
 
-var OPERATORS = map[string]bool{
-	"+":  true,
-	"-":  true,
-	"*":  true,
-	"/":  true,
-	"~":  true,
-	"&":  true,
-	".":  true,
-	",":  true,
-	";":  true,
-	"|":  true,
-	"(":  true,
-	"[":  true,
-	"{":  true,
-	":=": true,
-	"^":  true,
-	"=":  true,
-	"#":  true,
-	"<":  true,
-	">":  true,
-	">=": true,
-	"..": true,
-	":":  true,
-	")":  true,
-	"]":  true,
-	"}":  true,
-}
-
-var RESERVED_WORDS = map[string]bool{
-	"ARRAY":     true,
-	"BEGIN":     true,
-	"BY":        true,
-	"CASE":      true,
-	"CONST":     true,
-	"DIV":       true,
-	"DO":        true,
-	"ELSE":      true,
-	"ELSIF":     true,
-	"END":       true,
-	"FALSE":     true,
-	"FOR":       true,
-	"IF":        true,
-	"IMPORT":    true,
-	"IN":        true,
-	"IS":        true,
-	"MOD":       true,
-	"MODULE":    true,
-	"NIL":       true,
-	"OF":        true,
-	"OR":        true,
-	"POINTER":   true,
-	"PROCEDURE": true,
-	"RECORD":    true,
-	"REPEAT":    true,
-	"RETURN":    true,
-	"THEN":      true,
-	"TO":        true,
-	"TRUE":      true,
-	"TYPE":      true,
-	"UNTIL":     true,
-	"VAR":       true,
-	"WHILE":     true,
+var OPERATORS = map[string]TokenKind{
+	"+":  PLUS,
+	"-":  MINUS,
+	"*":  TIMES,
+	"/":  SLASH,
+	"~":  NOT,
+	"&":  AND,
+	".":  PERIOD,
+	",":  COMMA,
+	";":  SEMICOLON,
+	"|":  BAR,
+	"(":  LPAREN,
+	"[":  LBRACK,
+	"{":  LBRACE,
+	":=": BECOMES,
+	"^":  ARROW,
+	"=":  EQL,
+	"#":  NEQ,
+	"<":  LSS,
+	"<=": LEQ,
+	">":  GTR,
+	">=": GEQ,
+	"..": UPTO,
+	":":  COLON,
+	")":  RPAREN,
+	"]":  RBRACK,
+	"}":  RBRACE,
 }
 
-var PREDEFINED_IDENTIFIERS = map[string]bool{
-	"ABS":      true,
-	"ASH":      true,
-	"BOOLEAN":  true,
-	"CAP":      true,
-	"CHAR":     true,
-	"CHR":      true,
-	"COPY":     true,
-	"DEC":      true,
-	"ENTIER":   true,
-	"EXCL":     true,
-	"FALSE":    true,
-	"HALT":     true,
-	"INC":      true,
-	"INCL":     true,
-	"INTEGER":  true,
-	"LEN":      true,
-	"LONG":     true,
-	"LONGINT":  true,
-	"LONGREAL": true,
-	"MAX":      true,
-	"MIN":      true,
-	"NEW":      true,
-	"ODD":      true,
-	"ORD":      true,
-	"REAL":     true,
-	"SET":      true,
-	"SHORT":    true,
-	"SHORTINT": true,
-	"SIZE":     true,
-	"TRUE":     true,
+// RESERVED_WORDS are the reserved words all dialects share. TRUE and
+// FALSE are reserved only in Oberon-07; see the Dialect descriptors.
+var RESERVED_WORDS = map[string]TokenKind{
+	"ARRAY":     ARRAY,
+	"BEGIN":     BEGIN,
+	"BY":        BY,
+	"CASE":      CASE,
+	"CONST":     CONST,
+	"DIV":       DIV,
+	"DO":        DO,
+	"ELSE":      ELSE,
+	"ELSIF":     ELSIF,
+	"END":       END,
+	"FOR":       FOR,
+	"IF":        IF,
+	"IMPORT":    IMPORT,
+	"IN":        IN,
+	"IS":        IS,
+	"MOD":       MOD,
+	"MODULE":    MODULE,
+	"NIL":       NIL,
+	"OF":        OF,
+	"OR":        OR,
+	"POINTER":   POINTER,
+	"PROCEDURE": PROCEDURE,
+	"RECORD":    RECORD,
+	"REPEAT":    REPEAT,
+	"RETURN":    RETURN,
+	"THEN":      THEN,
+	"TO":        TO,
+	"TYPE":      TYPE,
+	"UNTIL":     UNTIL,
+	"VAR":       VAR,
+	"WHILE":     WHILE,
 }
 
-type Lexeme struct {
-	Label  string
-	Typ    Lexemetype
-	Line   int
-	Column int
+// Options selects the dialect and the optional lexical extensions of the
+// scanner.
+type Options struct {
+	Dialect            *Dialect // Oberon-07 if nil
+	UnicodeIdentifiers bool
 }
 
 type LexerResult struct {
-	Lexemes *[]Lexeme
+	File        *source.SourceFile
+	Options     Options
+	Tokens      *[]Token
+	Comments    *[]Token
+	Diagnostics *[]diag.Diagnostic
 }
 
 func isDigit(b byte) bool {
-	return b >= 48 && b <= 57
+	return b >= '0' && b <= '9'
 }
 
+// isHexDigit reports whether b is a hexDigit of the Oberon report; only
+// upper-case letters are hexadecimal digits.
 func isHexDigit(b byte) bool {
-	return b >= 65 && b <= 70
+	return isDigit(b) || (b >= 'A' && b <= 'F')
 }
 
 func isLetter(b byte) bool {
-	return (b >= 65 && b <= 90) || (b >= 97 && b <= 122)
+	return (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z')
 }
 
 func isAlphaNumeric(b byte) bool {
@@ -151,103 +115,77 @@ func isWhitespace(b byte) bool {
 	return (b >= 9 && b <= 13) || b == 32
 }
 
-func isReservedWord(lexeme string) bool {
-	return RESERVED_WORDS[lexeme]
-}
-
-func isPredefinedIdentifier(lexeme string) bool {
-	return PREDEFINED_IDENTIFIERS[lexeme]
+func isOperator(lexeme string) bool {
+	_, ok := OPERATORS[lexeme]
+	return ok
 }
 
-func isOperator(lexeme string) bool {
-	return OPERATORS[lexeme]
+func isStringDelimiter(b byte) bool {
+	return b == '"' || b == '\''
 }
 
-func isString(lexeme string) bool {
-	if len(lexeme) < 2 {
+func isHexDigits(digits string) bool {
+	if len(digits) == 0 || !isDigit(digits[0]) {
 		return false
 	}
-	if lexeme[0] == '"' && lexeme[len(lexeme)-1] == '"' {
-		for i := 1; i < len(lexeme)-1; i++ {
-			if !isAlphaNumeric(lexeme[i]) {
-				return false
-			}
-		}
-		return true
-	}
-	for i := 0; i < len(lexeme)-1; i++ {
-		if !isDigit(lexeme[i]) {
+	for i := 1; i < len(digits); i++ {
+		if !isHexDigit(digits[i]) {
 			return false
 		}
 	}
-	return lexeme[len(lexeme)-1] == 'X'
+	return true
+}
+
+// character constant = digit {hexDigit} "X".
+func isCharConstant(lexeme string) bool {
+	return len(lexeme) > 1 && lexeme[len(lexeme)-1] == 'X' && isHexDigits(lexeme[:len(lexeme)-1])
 }
 
+// integer = digit {digit} | digit {hexDigit} "H".
 func isInteger(lexeme string) bool {
-	var foundHex = false
-	if len(lexeme) == 0 || (!isDigit(byte(lexeme[0])) && !isHexDigit(byte(lexeme[0]))) {
+	if len(lexeme) > 1 && lexeme[len(lexeme)-1] == 'H' {
+		return isHexDigits(lexeme[:len(lexeme)-1])
+	}
+	if len(lexeme) == 0 {
 		return false
 	}
-	for i := 1; i < len(lexeme)-1; i++ {
-		if isDigit(byte(lexeme[i])) {
-			continue
-		} else if isHexDigit(byte(lexeme[i])) {
-			foundHex = true
-			continue
-		} else {
+	for i := 0; i < len(lexeme); i++ {
+		if !isDigit(lexeme[i]) {
 			return false
 		}
 	}
-	if foundHex {
-		return lexeme[len(lexeme)-1] == 'H'
-	}
-	return lexeme[len(lexeme)-1] == 'H' || isDigit(byte(lexeme[len(lexeme)-1]))
+	return true
 }
 
+// real = digit {digit} "." {digit} [ScaleFactor].
+// ScaleFactor = ("E" | "D") ["+" | "-"] digit {digit}.
 func isReal(lexeme string) bool {
-	if len(lexeme) == 0 {
-		return false
-	}
-	if !isDigit(byte(lexeme[0])) {
-		return false
-	}
-	var i = 1
-	for i < len(lexeme) {
-		if !isDigit(byte(lexeme[i])) {
-			break
-		}
+	var i = 0
+	for i < len(lexeme) && isDigit(lexeme[i]) {
 		i += 1
 	}
-	if i == len(lexeme) {
-		return false
-	}
-	if lexeme[i] != '.' {
+	if i == 0 || i == len(lexeme) || lexeme[i] != '.' {
 		return false
 	}
 	i += 1
-	for i < len(lexeme) {
-		if !isDigit(byte(lexeme[i])) {
-			break
-		}
+	for i < len(lexeme) && isDigit(lexeme[i]) {
 		i += 1
 	}
 	if i == len(lexeme) {
 		return true
 	}
-	if len(lexeme)-i < 2 {
+	if lexeme[i] != 'E' && lexeme[i] != 'D' {
 		return false
 	}
-	if lexeme[i] == 'E' || lexeme[i] == 'D' {
-		i += 1
-	}
-	if lexeme[i] == '+' || lexeme[i] == '-' {
+	i += 1
+	if i < len(lexeme) && (lexeme[i] == '+' || lexeme[i] == '-') {
 		i += 1
 	}
 	if i == len(lexeme) {
 		return false
 	}
 	for i < len(lexeme) {
-		if !isDigit(byte(lexeme[i])) {
+		if !isDigit(lexeme[i]) {
 			return false
 		}
 		i += 1
@@ -255,139 +193,83 @@ func isReal(lexeme string) bool {
 	return true
 }
 
-func isNumber(lexeme string) bool {
-	return isInteger(lexeme) || isReal(lexeme)
-}
+// MAX_CHAR is the largest value a character constant may denote.
+const MAX_CHAR = 0xFF
 
-func isIdent(lexeme string) bool {
-	var _isIdent = len(lexeme) > 0 && isLetter(lexeme[0])
-	if !_isIdent {
-		return false
-	}
-	for i := 1; i < len(lexeme); i++ {
-		if !isLetter(lexeme[i]) && !isDigit(lexeme[i]) {
-			return false
+// decodeLiteral stores the value denoted by a literal token's text in the
+// token. It reports an error if the value is not representable.
+func decodeLiteral(token *Token) error {
+	var label = token.Label
+	switch token.Kind {
+	case INTEGER:
+		if label[len(label)-1] == 'H' {
+			value, err := strconv.ParseUint(label[:len(label)-1], 16, 64)
+			if err != nil {
+				return literalError{INTEGER_OVERFLOW, "integer constant too large"}
+			}
+			token.IntValue = int64(value)
+		} else {
+			value, err := strconv.ParseInt(label, 10, 64)
+			if err != nil {
+				return literalError{INTEGER_OVERFLOW, "integer constant too large"}
+			}
+			token.IntValue = value
+		}
+	case REAL:
+		value, err := strconv.ParseFloat(strings.Replace(label, "D", "E", 1), 64)
+		if err != nil && math.IsInf(value, 0) {
+			return literalError{REAL_OVERFLOW, "real constant too large"}
 		}
+		token.RealValue = value
+	case CHAR:
+		value, err := strconv.ParseUint(label[:len(label)-1], 16, 32)
+		if err != nil || value > MAX_CHAR {
+			return literalError{CHAR_OUT_OF_RANGE, "character constant out of range"}
+		}
+		token.IntValue = int64(value)
+		token.StrValue = string(rune(value))
+	case STRING:
+		token.StrValue = label[1 : len(label)-1]
 	}
-	return true
+	return nil
 }
 
-func Lexer(contents []byte, debug bool) (LexerResult, error) {
-	//fmt.Println(fmt.Printf("Total number of characters: %d", len(contents)))
-	var i = 0
-	var LineNo = 1
-	var ColumnNo = 1
-	var currentLexeme = ""
-	var inComment = false
-	var lexemes = new([]Lexeme)
-	var inIdent = false
-	var inNumber = false
-	var inString = false
-	var err = false
-	var errorMessage = ""
-	for i < len(contents) {
-		if i < len(contents)-1 && string(contents[i]) == "(" && string(contents[i+1]) == "*" {
-			inComment = true
-			i += 2
-			ColumnNo += 2
-		} else if i < len(contents)-1 && string(contents[i]) == "*" && string(contents[i+1]) == ")" {
-			inComment = false
-			i += 2
-			ColumnNo += 2
-		} else if !inComment && inNumber && (rune(contents[i]) == '.' || rune(contents[i]) == '+' || rune(contents[i]) == '-') {
-			if i < len(contents)-1 && rune(contents[i+1]) == '.' {
-				if isInteger(currentLexeme) {
-					*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: INTEGER, Line: LineNo, Column: ColumnNo})
-				} else if isReal(currentLexeme) {
-					*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: REAL, Line: LineNo, Column: ColumnNo})
-				} else {
-					errorMessage = fmt.Sprintf("unrecognized token at Line %d, Column %d: %s", LineNo, ColumnNo, currentLexeme)
-					err = true
-					break
-				}
-				inNumber = false
-				currentLexeme = ""
-			} else {
-				currentLexeme += string(contents[i])
-				i += 1
-				ColumnNo += 1
-			}
-		} else if !inComment && (inIdent || inNumber || inString) && (isOperator(string(contents[i])) || isWhitespace(contents[i])) {
-			if isReservedWord(currentLexeme) {
-				*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: RESERVED_WORD, Line: LineNo, Column: ColumnNo})
-			} else if isPredefinedIdentifier(currentLexeme) {
-				*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: PREDEFINED, Line: LineNo, Column: ColumnNo})
-			} else if isString(currentLexeme) {
-				*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: STRING, Line: LineNo, Column: ColumnNo})
-			} else if isInteger(currentLexeme) {
-				*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: INTEGER, Line: LineNo, Column: ColumnNo})
-			} else if isReal(currentLexeme) {
-				*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: REAL, Line: LineNo, Column: ColumnNo})
-			} else if isIdent(currentLexeme) {
-				*lexemes = append(*lexemes, Lexeme{Label: currentLexeme, Typ: IDENT, Line: LineNo, Column: ColumnNo})
-			} else {
-				errorMessage = fmt.Sprintf("unrecognized token at Line %d, Column %d: %s", LineNo, ColumnNo, currentLexeme)
-				err = true
-				break
-			}
-			inIdent = false
-			inNumber = false
-			inString = false
-			currentLexeme = ""
-		} else if !inComment && i < len(contents)-1 && isOperator(string(contents[i])) && isOperator(string(contents[i+1])) {
-			if isOperator(string(contents[i]) + string(contents[i+1])) {
-				*lexemes = append(*lexemes, Lexeme{Label: string(contents[i]) + string(contents[i+1]), Typ: OP_OR_DELIM, Line: LineNo, Column: ColumnNo})
-			} else {
-				*lexemes = append(*lexemes, Lexeme{Label: string(contents[i]), Typ: OP_OR_DELIM, Line: LineNo, Column: ColumnNo})
-				*lexemes = append(*lexemes, Lexeme{Label: string(contents[i+1]), Typ: OP_OR_DELIM, Line: LineNo, Column: ColumnNo})
-			}
-			i += 2
-			ColumnNo += 2
-		} else if !inComment && !inString && isDigit(contents[i]) {
-			currentLexeme += string(contents[i])
-			inNumber = true
-			i += 1
-			ColumnNo += 1
-		} else if !inComment && isOperator(string(contents[i])) {
-			*lexemes = append(*lexemes, Lexeme{Label: string(contents[i]), Typ: OP_OR_DELIM, Line: LineNo, Column: ColumnNo})
-			ColumnNo += 1
-			i += 1
-		} else if isWhitespace(contents[i]) {
-			if contents[i] == 10 {
-				ColumnNo = 1
-				LineNo += 1
-			} else {
-				ColumnNo += 1
-			}
-			i += 1
-		} else if !inComment && !inString && rune(contents[i]) == '"' {
-			currentLexeme += string(contents[i])
-			inString = true
-			i += 1
-			ColumnNo += 1
-		} else if inString && rune(contents[i]) == '"' {
-			currentLexeme += string(contents[i])
-			inString = false
-			i += 1
-			ColumnNo += 1
+// Lexer scans the whole file and returns all of its tokens. Comments are
+// returned separately, in source order, so that the parser never sees
+// them. Lexical errors do not stop the lexer: each one is recorded as a
+// diagnostic and the offending text becomes an ILLEGAL token. The error
+// returned is the first diagnostic, if there is any.
+func Lexer(file *source.SourceFile, options Options, debug bool) (LexerResult, error) {
+	if options.Dialect == nil {
+		options.Dialect = OBERON07
+	}
+	var scanner = NewScanner(file)
+	scanner.ScanComments = true
+	scanner.Dialect = options.Dialect
+	scanner.UnicodeIdentifiers = options.UnicodeIdentifiers
+	var tokens = new([]Token)
+	var comments = new([]Token)
+	for {
+		token := scanner.Next()
+		if token.Kind == EOF {
+			break
+		}
+		if token.Kind == COMMENT {
+			*comments = append(*comments, token)
 		} else {
-			if !inComment {
-				currentLexeme += string(contents[i])
-				inIdent = true
-			}
-			i += 1
-			ColumnNo += 1
+			*tokens = append(*tokens, token)
 		}
 	}
-	if inComment {
-		errorMessage = fmt.Sprintf("unclosed comment at Line %d, Column %d", LineNo, ColumnNo)
-		err = true
-	} else if inString {
-		errorMessage = fmt.Sprintf("unfinished string at Line %d, Column %d", LineNo, ColumnNo)
-		err = true
+	var diagnostics = scanner.Diagnostics()
+	var result = LexerResult{
+		File:        file,
+		Options:     options,
+		Tokens:      tokens,
+		Comments:    comments,
+		Diagnostics: &diagnostics,
 	}
-	if err {
-		return LexerResult{Lexemes: lexemes}, fmt.Errorf(errorMessage)
+	if len(diagnostics) > 0 {
+		return result, diagnostics[0]
 	}
-	return LexerResult{Lexemes: lexemes}, nil
+	return result, nil
 }
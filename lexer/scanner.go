@@ -0,0 +1,420 @@
+package lexer
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	diag "oberon/diag"
+	source "oberon/source"
+)
+
+// Scanner produces the tokens of a source file one at a time. Tokens are
+// only scanned when they are requested through Next or Peek, so callers
+// that stop early never pay for the rest of the file.
+//
+// Comments are skipped unless ScanComments is set, in which case each
+// comment is returned as a COMMENT token whose StrValue holds the text
+// between the outermost delimiters. Source text is UTF-8; identifiers are
+// restricted to ASCII letters and digits unless UnicodeIdentifiers is set.
+// The reserved words are those of Dialect, Oberon-07 unless it is set.
+type Scanner struct {
+	ScanComments       bool
+	UnicodeIdentifiers bool
+	Dialect            *Dialect
+
+	file        *source.SourceFile
+	offset      int
+	lookahead   []Token
+	diagnostics []diag.Diagnostic
+}
+
+func NewScanner(file *source.SourceFile) *Scanner {
+	return &Scanner{file: file, Dialect: OBERON07}
+}
+
+func (s *Scanner) File() *source.SourceFile {
+	return s.file
+}
+
+// Diagnostics returns the lexical errors found in the tokens scanned so
+// far, in source order.
+func (s *Scanner) Diagnostics() []diag.Diagnostic {
+	return s.diagnostics
+}
+
+// Offset returns the byte offset from which the next token will be
+// scanned. Passing it to Reset resumes scanning at the same token.
+func (s *Scanner) Offset() int {
+	if len(s.lookahead) > 0 {
+		return s.lookahead[0].Offset
+	}
+	return s.offset
+}
+
+// Reset discards any buffered tokens and restarts scanning at offset.
+// Diagnostics for text at or after offset are discarded as well, since
+// that text will be scanned again.
+func (s *Scanner) Reset(offset int) {
+	s.offset = offset
+	s.lookahead = s.lookahead[:0]
+	var kept = 0
+	for kept < len(s.diagnostics) && s.diagnostics[kept].Span.Start.Offset < offset {
+		kept++
+	}
+	s.diagnostics = s.diagnostics[:kept]
+}
+
+// Next consumes and returns the next token. Text that is not a valid
+// token is returned as an ILLEGAL token, and scanning carries on after
+// it. Once the end of the file is reached every further call returns an
+// EOF token.
+func (s *Scanner) Next() Token {
+	if len(s.lookahead) > 0 {
+		token := s.lookahead[0]
+		s.lookahead = s.lookahead[1:]
+		return token
+	}
+	return s.scan()
+}
+
+// Peek returns the token n positions ahead without consuming it; Peek(0)
+// is the token the next call to Next will return.
+func (s *Scanner) Peek(n int) Token {
+	for len(s.lookahead) <= n {
+		s.lookahead = append(s.lookahead, s.scan())
+	}
+	return s.lookahead[n]
+}
+
+// error records a diagnostic for the text from offset to the current
+// offset and returns the ILLEGAL token covering it.
+func (s *Scanner) error(code string, offset int, message string) Token {
+	s.diagnostics = append(s.diagnostics, diag.Errorf(code, s.file.Span(offset, s.offset), "%s", message))
+	return newToken(s.file, ILLEGAL, offset, s.offset)
+}
+
+// identRune decodes the rune at the current offset and reports its size
+// if it may continue an identifier, and whether it is a non-ASCII letter
+// or digit.
+func (s *Scanner) identRune() (int, bool, bool) {
+	var contents = s.file.Contents
+	if s.offset >= len(contents) {
+		return 0, false, false
+	}
+	if !isUnicode(contents[s.offset]) {
+		return 1, isAlphaNumeric(contents[s.offset]), false
+	}
+	r, size := utf8.DecodeRune(contents[s.offset:])
+	if r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return size, true, true
+	}
+	return 0, false, false
+}
+
+// scanIdent scans an identifier or reserved word starting at start.
+func (s *Scanner) scanIdent(start int) Token {
+	var contents = s.file.Contents
+	var nonASCII = false
+	for {
+		size, ok, isNonASCII := s.identRune()
+		if !ok {
+			break
+		}
+		nonASCII = nonASCII || isNonASCII
+		s.offset += size
+	}
+	if nonASCII && !s.UnicodeIdentifiers {
+		return s.error(UNICODE_IDENTIFIERS, start, fmt.Sprintf("Unicode letters in identifiers are not enabled: %s", contents[start:s.offset]))
+	}
+	if keyword, ok := s.Dialect.ReservedWord(string(contents[start:s.offset])); ok {
+		return newToken(s.file, keyword, start, s.offset)
+	}
+	return newToken(s.file, IDENT, start, s.offset)
+}
+
+func (s *Scanner) illegal(offset int) Token {
+	return s.error(UNRECOGNIZED_TOKEN, offset, fmt.Sprintf("unrecognized token %q", s.file.Contents[offset:s.offset]))
+}
+
+func (s *Scanner) peekByte(n int) byte {
+	if s.offset+n < len(s.file.Contents) {
+		return s.file.Contents[s.offset+n]
+	}
+	return 0
+}
+
+// isUnicodeLetter reports whether a non-ASCII letter, which may start an
+// identifier, is at the current offset.
+func (s *Scanner) isUnicodeLetter() bool {
+	if s.offset >= len(s.file.Contents) || !isUnicode(s.file.Contents[s.offset]) {
+		return false
+	}
+	r, _ := utf8.DecodeRune(s.file.Contents[s.offset:])
+	return unicode.IsLetter(r)
+}
+
+func (s *Scanner) skipWhitespace() {
+	for s.offset < len(s.file.Contents) && isWhitespace(s.file.Contents[s.offset]) {
+		s.offset++
+	}
+}
+
+// scanComment scans a comment starting at the current offset. Comments
+// nest, so the comment only ends once every "(*" inside it has been
+// matched by a "*)".
+func (s *Scanner) scanComment() Token {
+	var contents = s.file.Contents
+	var start = s.offset
+	var depth = 0
+	for s.offset < len(contents) {
+		if contents[s.offset] == '(' && s.peekByte(1) == '*' {
+			depth++
+			s.offset += 2
+		} else if contents[s.offset] == '*' && s.peekByte(1) == ')' {
+			depth--
+			s.offset += 2
+			if depth == 0 {
+				token := newToken(s.file, COMMENT, start, s.offset)
+				token.StrValue = string(contents[start+2 : s.offset-2])
+				return token
+			}
+		} else {
+			s.offset++
+		}
+	}
+	return s.error(UNCLOSED_COMMENT, start, "unclosed comment")
+}
+
+func (s *Scanner) scan() Token {
+	var contents = s.file.Contents
+	s.skipWhitespace()
+	for s.offset < len(contents) && contents[s.offset] == '(' && s.peekByte(1) == '*' {
+		comment := s.scanComment()
+		if comment.Kind == ILLEGAL || s.ScanComments {
+			return comment
+		}
+		s.skipWhitespace()
+	}
+	var start = s.offset
+	if start >= len(contents) {
+		return newToken(s.file, EOF, start, start)
+	}
+
+	var ch = contents[start]
+	var kind TokenKind
+	switch {
+	case isLetter(ch) || s.isUnicodeLetter():
+		return s.scanIdent(start)
+	case isDigit(ch):
+		return s.scanNumber(start)
+	case isStringDelimiter(ch):
+		s.offset++
+		for s.offset < len(contents) && contents[s.offset] != ch && contents[s.offset] != '\n' && contents[s.offset] != '\r' {
+			s.offset++
+		}
+		if s.offset >= len(contents) || contents[s.offset] != ch {
+			return s.error(UNFINISHED_STRING, start, "unfinished string")
+		}
+		s.offset++
+		if !utf8.Valid(contents[start:s.offset]) {
+			return s.error(INVALID_UTF8, start, "invalid UTF-8 encoding in string")
+		}
+		kind = STRING
+	default:
+		if s.offset+1 < len(contents) && isOperator(string(contents[start:start+2])) {
+			s.offset += 2
+		} else if isOperator(string(ch)) {
+			s.offset++
+		} else if isUnicode(ch) {
+			r, size := utf8.DecodeRune(contents[start:])
+			s.offset += size
+			if r == utf8.RuneError {
+				return s.error(INVALID_UTF8, start, "invalid UTF-8 encoding")
+			}
+			return s.illegal(start)
+		} else {
+			s.offset++
+			return s.illegal(start)
+		}
+		kind = OPERATORS[string(contents[start:s.offset])]
+	}
+	return s.literal(kind, start)
+}
+
+// literal returns the token for a literal spanning from start to the
+// current offset, or an ILLEGAL token if its value cannot be represented.
+func (s *Scanner) literal(kind TokenKind, start int) Token {
+	token := newToken(s.file, kind, start, s.offset)
+	if err := decodeLiteral(&token); err != nil {
+		failure := err.(literalError)
+		return s.error(failure.code, start, fmt.Sprintf("%s: %s", failure.message, token.Label))
+	}
+	return token
+}
+
+// scanNumber scans an integer, real or character constant. The longest
+// run of letters, digits and scale factor characters is taken, so that a
+// malformed number is reported as a single token.
+func (s *Scanner) scanNumber(start int) Token {
+	var contents = s.file.Contents
+	for s.offset < len(contents) && isAlphaNumeric(contents[s.offset]) {
+		s.offset++
+	}
+	if s.offset < len(contents) && contents[s.offset] == '.' && s.peekByte(1) != '.' {
+		s.offset++
+		for s.offset < len(contents) && isDigit(contents[s.offset]) {
+			s.offset++
+		}
+		if s.offset < len(contents) && (contents[s.offset] == 'E' || contents[s.offset] == 'D') {
+			s.offset++
+			if s.offset < len(contents) && (contents[s.offset] == '+' || contents[s.offset] == '-') {
+				s.offset++
+			}
+			for s.offset < len(contents) && isDigit(contents[s.offset]) {
+				s.offset++
+			}
+		}
+	}
+
+	var lexeme = string(contents[start:s.offset])
+	switch {
+	case isInteger(lexeme):
+		return s.literal(INTEGER, start)
+	case isCharConstant(lexeme):
+		return s.literal(CHAR, start)
+	case isReal(lexeme):
+		return s.literal(REAL, start)
+	case isHexDigits(lexeme):
+		return s.error(MISSING_HEX_SUFFIX, start, fmt.Sprintf("hexadecimal constant without \"H\" suffix: %s", lexeme))
+	}
+	return s.error(MALFORMED_NUMBER, start, fmt.Sprintf("malformed number: %s", lexeme))
+}
+
+// LOOKAHEAD is the number of bytes past the end of a token the scanner
+// may read to find where the token ends: a number followed by "." is an
+// integer if the next byte is another ".", and a real otherwise.
+const LOOKAHEAD = 2
+
+// Edit replaces the bytes in [Start, End) of a file with Text.
+type Edit struct {
+	Start int
+	End   int
+	Text  []byte
+}
+
+// Rescan applies edit to the file of a previous lexer result and returns
+// the tokens of the edited file. Only the region around the edit is
+// scanned again: tokens before it are kept as they are, and once the
+// scanner is back in step with the old token stream past the edit the
+// remaining old tokens, comments and diagnostics are reused with their
+// positions shifted.
+func Rescan(previous LexerResult, edit Edit) (LexerResult, error) {
+	var old = previous.File
+	var contents = make([]byte, 0, len(old.Contents)-(edit.End-edit.Start)+len(edit.Text))
+	contents = append(contents, old.Contents[:edit.Start]...)
+	contents = append(contents, edit.Text...)
+	contents = append(contents, old.Contents[edit.End:]...)
+	var file = source.NewSourceFile(old.Name, contents)
+	var delta = len(edit.Text) - (edit.End - edit.Start)
+	var oldTokens = *previous.Tokens
+	var oldComments []Token
+	if previous.Comments != nil {
+		oldComments = *previous.Comments
+	}
+	var oldDiagnostics []diag.Diagnostic
+	if previous.Diagnostics != nil {
+		oldDiagnostics = *previous.Diagnostics
+	}
+	var shift = func(token Token) Token {
+		shifted := newToken(file, token.Kind, token.Offset+delta, token.End+delta)
+		shifted.StrValue = token.StrValue
+		_ = decodeLiteral(&shifted)
+		return shifted
+	}
+
+	// Keep every token the scanner found without reading the edited text;
+	// the tokens touching the edit may change, e.g. when an identifier is
+	// extended or the ".." after an integer becomes the "." of a real.
+	var keep = 0
+	for keep < len(oldTokens) && oldTokens[keep].End+LOOKAHEAD <= edit.Start {
+		keep++
+	}
+	var tokens = make([]Token, keep, len(oldTokens)+1)
+	copy(tokens, oldTokens[:keep])
+
+	var resume = 0
+	if keep > 0 {
+		resume = oldTokens[keep-1].End
+	}
+	var comments []Token
+	for _, comment := range oldComments {
+		if comment.End <= resume {
+			comments = append(comments, comment)
+		}
+	}
+	var diagnostics []diag.Diagnostic
+	for _, diagnostic := range oldDiagnostics {
+		if diagnostic.Span.End.Offset <= resume {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+
+	var scanner = NewScanner(file)
+	scanner.ScanComments = true
+	scanner.Dialect = previous.Options.Dialect
+	scanner.UnicodeIdentifiers = previous.Options.UnicodeIdentifiers
+	scanner.Reset(resume)
+	var next = keep
+	var resynchronized = -1
+	for {
+		token := scanner.Next()
+		if token.Kind == EOF {
+			break
+		}
+		if token.Kind == COMMENT {
+			comments = append(comments, token)
+			continue
+		}
+		tokens = append(tokens, token)
+		if token.Offset < edit.Start+len(edit.Text) {
+			continue
+		}
+		for next < len(oldTokens) && oldTokens[next].Offset+delta < token.Offset {
+			next++
+		}
+		if next < len(oldTokens) && oldTokens[next].Offset >= edit.End &&
+			oldTokens[next].Offset+delta == token.Offset && oldTokens[next].Label == token.Label {
+			resynchronized = oldTokens[next].Offset
+			break
+		}
+	}
+	diagnostics = append(diagnostics, scanner.Diagnostics()...)
+	if resynchronized >= 0 {
+		for _, token := range oldTokens[next+1:] {
+			tokens = append(tokens, shift(token))
+		}
+		for _, comment := range oldComments {
+			if comment.Offset > resynchronized {
+				comments = append(comments, shift(comment))
+			}
+		}
+		for _, diagnostic := range oldDiagnostics {
+			if diagnostic.Span.Start.Offset > resynchronized {
+				diagnostic.Span = file.Span(diagnostic.Span.Start.Offset+delta, diagnostic.Span.End.Offset+delta)
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+	}
+	var result = LexerResult{
+		File:        file,
+		Options:     previous.Options,
+		Tokens:      &tokens,
+		Comments:    &comments,
+		Diagnostics: &diagnostics,
+	}
+	if len(diagnostics) > 0 {
+		return result, diagnostics[0]
+	}
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package lexer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	source "oberon/source"
+)
+
+// FRAGMENTS are the texts the random edits insert: pieces of numbers,
+// strings and comments, whose tokens depend on the text around them.
+var FRAGMENTS = []string{
+	"", " ", "\n", "\r\n", "'a'", "\"", "'", ".", "..", "0", "1.5", "X", "H",
+	"E", "D", "+", "x", "(*", "*)", ":", "=", ";", "END", "0FFH",
+}
+
+// sameElements reports whether the slices a and b have equal elements;
+// an empty slice and a nil one are the same.
+func sameElements(a, b interface{}) bool {
+	if reflect.ValueOf(a).Len() == 0 {
+		return reflect.ValueOf(b).Len() == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// lexEqual reports how the results of Rescan and of the Lexer over the
+// same file differ, or "" if they do not.
+func lexEqual(rescanned, lexed LexerResult) string {
+	switch {
+	case !sameElements(*rescanned.Tokens, *lexed.Tokens):
+		return fmt.Sprintf("tokens differ:\n%v\n%v", *rescanned.Tokens, *lexed.Tokens)
+	case !sameElements(*rescanned.Comments, *lexed.Comments):
+		return fmt.Sprintf("comments differ:\n%v\n%v", *rescanned.Comments, *lexed.Comments)
+	case !sameElements(*rescanned.Diagnostics, *lexed.Diagnostics):
+		return fmt.Sprintf("diagnostics differ:\n%v\n%v", *rescanned.Diagnostics, *lexed.Diagnostics)
+	}
+	return ""
+}
+
+// checkRescan applies edit to the lexed file and compares the result of
+// Rescan with that of lexing the edited file afresh.
+func checkRescan(t *testing.T, previous LexerResult, edit Edit) {
+	t.Helper()
+	rescanned, _ := Rescan(previous, edit)
+	lexed, _ := Lexer(source.NewSourceFile(previous.File.Name, rescanned.File.Contents), previous.Options, false)
+	if difference := lexEqual(rescanned, lexed); difference != "" {
+		t.Fatalf("%s: replacing [%d,%d) with %q: %s", previous.File.Name, edit.Start, edit.End, edit.Text, difference)
+	}
+}
+
+func lexExample(t *testing.T, name string) LexerResult {
+	t.Helper()
+	contents, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, _ := Lexer(source.NewSourceFile(name, contents), Options{}, false)
+	return result
+}
+
+func TestRescanLookahead(t *testing.T) {
+	for _, test := range []struct {
+		text  string
+		edit  Edit
+		token string
+	}{
+		{"x := 0..y", Edit{7, 8, []byte("x")}, "0."},        // "0.x": the real 0. and x
+		{"x := 0.x", Edit{7, 8, []byte(".")}, "0"},          // "0..": the integer 0 and ..
+		{"x := 0 X", Edit{6, 7, nil}, "0X"},                 // the character 0X
+		{"x := 1.5E 3", Edit{9, 10, []byte("+")}, "1.5E+3"}, // a scale factor
+	} {
+		previous, _ := Lexer(source.NewSourceFile("test.ob", []byte(test.text)), Options{}, false)
+		checkRescan(t, previous, test.edit)
+		rescanned, _ := Rescan(previous, test.edit)
+		if label := (*rescanned.Tokens)[2].Label; label != test.token {
+			t.Errorf("%q: replacing [%d,%d) with %q gives %q, not %q", test.text, test.edit.Start, test.edit.End, test.edit.Text, label, test.token)
+		}
+	}
+}
+
+func TestRescanExamples(t *testing.T) {
+	for _, test := range []struct {
+		name, context, old string
+	}{
+		{"semantic_errors.ob", "{0..32}", ".32"},
+		{"semantic_errors.ob", ":= list IS Node", ""},
+		{"literals_test.ob", "1..10:", ".10"},
+		{"case_statements_test.ob", "| \"a\":", "\"a\""},
+		{"case_statements_test.ob", "| a.a:", "a.a"},
+	} {
+		previous := lexExample(t, filepath.Join("../examples", test.name))
+		at := strings.Index(string(previous.File.Contents), test.context)
+		if at < 0 {
+			t.Fatalf("%s: %q not found", test.name, test.context)
+		}
+		// the edit replaces old where it is in the context
+		start := at + strings.Index(test.context, test.old)
+		checkRescan(t, previous, Edit{start, start + len(test.old), []byte("'a'")})
+	}
+}
+
+func TestRescanMatchesLexer(t *testing.T) {
+	names, err := filepath.Glob("../examples/*.ob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var random = rand.New(rand.NewSource(1))
+	for _, name := range names {
+		previous := lexExample(t, name)
+		size := len(previous.File.Contents)
+		for i := 0; i < 200; i++ {
+			start := random.Intn(size + 1)
+			end := start + random.Intn(4)
+			if end > size {
+				end = size
+			}
+			checkRescan(t, previous, Edit{start, end, []byte(FRAGMENTS[random.Intn(len(FRAGMENTS))])})
+		}
+	}
+}
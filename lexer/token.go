@@ -0,0 +1,272 @@
+package lexer
+
+import (
+	"fmt"
+
+	source "oberon/source"
+)
+
+type TokenKind int
+
+const (
+	ILLEGAL TokenKind = iota
+	EOF
+	COMMENT
+
+	literal_beg
+	IDENT
+	INTEGER
+	REAL
+	CHAR
+	STRING
+	literal_end
+
+	operator_beg
+	PLUS      // +
+	MINUS     // -
+	TIMES     // *
+	SLASH     // /
+	NOT       // ~
+	AND       // &
+	PERIOD    // .
+	COMMA     // ,
+	SEMICOLON // ;
+	BAR       // |
+	LPAREN    // (
+	LBRACK    // [
+	LBRACE    // {
+	BECOMES   // :=
+	ARROW     // ^
+	EQL       // =
+	NEQ       // #
+	LSS       // <
+	LEQ       // <=
+	GTR       // >
+	GEQ       // >=
+	UPTO      // ..
+	COLON     // :
+	RPAREN    // )
+	RBRACK    // ]
+	RBRACE    // }
+	operator_end
+
+	keyword_beg
+	ABSTRACT
+	ARRAY
+	BEGIN
+	BY
+	CASE
+	CONST
+	DIV
+	DO
+	ELSE
+	ELSIF
+	EMPTY
+	END
+	EXIT
+	EXTENSIBLE
+	FALSE
+	FOR
+	IF
+	IMPORT
+	IN
+	IS
+	LIMITED
+	LOOP
+	MOD
+	MODULE
+	NIL
+	OF
+	OR
+	OUT
+	POINTER
+	PROCEDURE
+	RECORD
+	REPEAT
+	RETURN
+	THEN
+	TO
+	TRUE
+	TYPE
+	UNTIL
+	VAR
+	WHILE
+	WITH
+	keyword_end
+
+	// TOKEN_KINDS is one past the last token kind, to loop over them all.
+	TOKEN_KINDS
+)
+
+var tokenKindNames = [...]string{
+	ILLEGAL: "ILLEGAL",
+	EOF:     "EOF",
+	COMMENT: "COMMENT",
+
+	IDENT:   "IDENT",
+	INTEGER: "INTEGER",
+	REAL:    "REAL",
+	CHAR:    "CHAR",
+	STRING:  "STRING",
+
+	PLUS:      "+",
+	MINUS:     "-",
+	TIMES:     "*",
+	SLASH:     "/",
+	NOT:       "~",
+	AND:       "&",
+	PERIOD:    ".",
+	COMMA:     ",",
+	SEMICOLON: ";",
+	BAR:       "|",
+	LPAREN:    "(",
+	LBRACK:    "[",
+	LBRACE:    "{",
+	BECOMES:   ":=",
+	ARROW:     "^",
+	EQL:       "=",
+	NEQ:       "#",
+	LSS:       "<",
+	LEQ:       "<=",
+	GTR:       ">",
+	GEQ:       ">=",
+	UPTO:      "..",
+	COLON:     ":",
+	RPAREN:    ")",
+	RBRACK:    "]",
+	RBRACE:    "}",
+
+	ABSTRACT:   "ABSTRACT",
+	ARRAY:      "ARRAY",
+	BEGIN:      "BEGIN",
+	BY:         "BY",
+	CASE:       "CASE",
+	CONST:      "CONST",
+	DIV:        "DIV",
+	DO:         "DO",
+	ELSE:       "ELSE",
+	ELSIF:      "ELSIF",
+	EMPTY:      "EMPTY",
+	END:        "END",
+	EXIT:       "EXIT",
+	EXTENSIBLE: "EXTENSIBLE",
+	FALSE:      "FALSE",
+	FOR:        "FOR",
+	IF:         "IF",
+	IMPORT:     "IMPORT",
+	IN:         "IN",
+	IS:         "IS",
+	LIMITED:    "LIMITED",
+	LOOP:       "LOOP",
+	MOD:        "MOD",
+	MODULE:     "MODULE",
+	NIL:        "NIL",
+	OF:         "OF",
+	OR:         "OR",
+	OUT:        "OUT",
+	POINTER:    "POINTER",
+	PROCEDURE:  "PROCEDURE",
+	RECORD:     "RECORD",
+	REPEAT:     "REPEAT",
+	RETURN:     "RETURN",
+	THEN:       "THEN",
+	TO:         "TO",
+	TRUE:       "TRUE",
+	TYPE:       "TYPE",
+	UNTIL:      "UNTIL",
+	VAR:        "VAR",
+	WHILE:      "WHILE",
+	WITH:       "WITH",
+}
+
+// String returns the source spelling of operators and keywords and the
+// upper-case kind name of every other token kind.
+func (k TokenKind) String() string {
+	if k >= 0 && int(k) < len(tokenKindNames) && tokenKindNames[k] != "" {
+		return tokenKindNames[k]
+	}
+	return fmt.Sprintf("TokenKind(%d)", int(k))
+}
+
+// Precedences of the binary operators, from the loosest to the tightest
+// binding. Operators of equal precedence associate to the left; the
+// relations do not associate at all. The sign of a term binds like an
+// AddOperator and "~" binds tighter than any binary operator.
+const (
+	LOWEST_PRECEDENCE   = 0
+	RELATION_PRECEDENCE = 1
+	ADD_PRECEDENCE      = 2
+	MUL_PRECEDENCE      = 3
+	UNARY_PRECEDENCE    = 4
+)
+
+// Precedence returns the precedence of k as a binary operator, or
+// LOWEST_PRECEDENCE if k is not one.
+func (k TokenKind) Precedence() int {
+	switch k {
+	case EQL, NEQ, LSS, LEQ, GTR, GEQ, IN, IS:
+		return RELATION_PRECEDENCE
+	case PLUS, MINUS, OR:
+		return ADD_PRECEDENCE
+	case TIMES, SLASH, DIV, MOD, AND:
+		return MUL_PRECEDENCE
+	}
+	return LOWEST_PRECEDENCE
+}
+
+func (k TokenKind) IsLiteral() bool {
+	return literal_beg < k && k < literal_end
+}
+
+func (k TokenKind) IsOperator() bool {
+	return operator_beg < k && k < operator_end
+}
+
+func (k TokenKind) IsKeyword() bool {
+	return keyword_beg < k && k < keyword_end
+}
+
+// Token is a single lexeme together with its span in the source file.
+// Offset and End are byte offsets; End is exclusive. Columns are counted
+// in bytes, and additionally in UTF-16 code units for editors. For
+// literals the decoded value is stored in IntValue, RealValue or StrValue.
+type Token struct {
+	Kind           TokenKind
+	Label          string
+	Line           int
+	Column         int
+	UTF16Column    int
+	Offset         int
+	End            int
+	EndLine        int
+	EndColumn      int
+	EndUTF16Column int
+
+	IntValue  int64
+	RealValue float64
+	StrValue  string
+}
+
+func newToken(file *source.SourceFile, kind TokenKind, offset int, end int) Token {
+	var start = file.Position(offset)
+	var stop = file.Position(end)
+	return Token{
+		Kind:           kind,
+		Label:          string(file.Contents[offset:end]),
+		Line:           start.Line,
+		Column:         start.Column,
+		UTF16Column:    start.UTF16Column,
+		Offset:         offset,
+		End:            end,
+		EndLine:        stop.Line,
+		EndColumn:      stop.Column,
+		EndUTF16Column: stop.UTF16Column,
+	}
+}
+
+func (t Token) String() string {
+	if t.Kind.IsLiteral() || t.Kind == COMMENT {
+		return fmt.Sprintf("%s %q (line: %d, column: %d)", t.Kind, t.Label, t.Line, t.Column)
+	}
+	return fmt.Sprintf("%q (line: %d, column: %d)", t.Label, t.Line, t.Column)
+}
@@ -2,30 +2,37 @@ package main
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"strconv"
 
 	"github.com/fatih/color"
 
+	ast "oberon/ast"
+	diag "oberon/diag"
 	lexer "oberon/lexer"
 	parser "oberon/parser"
 	semantic_analyzer "oberon/semantic_analyzer"
+	source "oberon/source"
 )
 
-func readFile(source string) ([]byte, error) {
-	file, err := os.Open(source)
-	if err != nil {
-		log.Fatal(err)
-		return make([]byte, 0), err
+// report writes every diagnostic collected so far in the requested
+// format. Text goes to stderr, followed by a summary of the number of
+// diagnostics; json and sarif go to stdout for tools to consume.
+func report(format string, renderer *diag.Renderer, reporter *diag.Reporter) {
+	var err error
+	switch format {
+	case "json":
+		err = diag.WriteJSON(os.Stdout, reporter.Diagnostics())
+	case "sarif":
+		err = diag.WriteSARIF(os.Stdout, reporter.Diagnostics())
+	default:
+		renderer.Render(reporter.Diagnostics()...)
+		renderer.Summary(reporter)
 	}
-	data := make([]byte, 1000)
-	_, err = file.Read(data)
 	if err != nil {
-		log.Fatal(err)
-		return make([]byte, 0), err
+		color.Red(err.Error())
+		os.Exit(1)
 	}
-	return data, nil
 }
 
 func main() {
@@ -33,33 +40,47 @@ func main() {
 	if arguments.result == ERROR {
 		os.Exit(1)
 	}
-	source := arguments.arguments["source"]
-	contents, err := readFile(source)
-	if err != nil {
-		os.Exit(1)
-	}
-	debug, _ := strconv.ParseBool(arguments.arguments["debug"])
-	lexerResult, err := lexer.Lexer(contents, debug)
+	file, err := source.ReadFile(arguments.arguments["source"])
 	if err != nil {
 		color.Red(err.Error())
 		os.Exit(1)
 	}
+	debug, _ := strconv.ParseBool(arguments.arguments["debug"])
+	dialect, _ := lexer.LookupDialect(arguments.arguments["dialect"])
+	unicodeIdentifiers, _ := strconv.ParseBool(arguments.arguments["unicode-identifiers"])
+	warnIncompleteCase, _ := strconv.ParseBool(arguments.arguments["warn-incomplete-case"])
+	format := arguments.arguments["diagnostics-format"]
+	reporter := diag.NewReporter()
+	renderer := diag.NewRenderer(os.Stderr, !color.NoColor, file)
+	// A lexical error leaves an ILLEGAL token behind and parsing goes on:
+	// every phase reports its diagnostics before the exit status is set.
+	lexerResult, lexErr := lexer.Lexer(file, lexer.Options{Dialect: dialect, UnicodeIdentifiers: unicodeIdentifiers}, debug)
+	reporter.Report(*lexerResult.Diagnostics...)
 	if debug {
-		for _, ch := range *lexerResult.Lexemes {
+		for _, ch := range *lexerResult.Tokens {
 			fmt.Println(ch)
 		}
+		for _, comment := range *lexerResult.Comments {
+			fmt.Println(comment)
+		}
 	}
-	tree, err1 := parser.Parser(lexerResult.Lexemes, debug)
-	if err1 != nil {
-		color.Red(err1.Error())
+	tree, parseErr := parser.Parser(file, lexerResult.Tokens, dialect, reporter, debug)
+	if tree == nil {
+		report(format, renderer, reporter)
 		os.Exit(1)
 	}
 
 	if debug {
-		parser.PrintParserTree(tree, 0)
+		ast.Fprint(os.Stdout, tree)
 	}
 
-	annotated_tree, err2 := semantic_analyzer.Analyze(tree, debug)
-	fmt.Println(annotated_tree)
-	fmt.Println(err2)
+	// Semantic checks still run on the parts of a tree with syntax errors.
+	annotated_tree, err := semantic_analyzer.Analyze(file, tree, semantic_analyzer.Options{Dialect: dialect, WarnIncompleteCase: warnIncompleteCase}, reporter, debug)
+	report(format, renderer, reporter)
+	if lexErr != nil || parseErr != nil || err != nil {
+		os.Exit(1)
+	}
+	if format == "text" {
+		fmt.Println(annotated_tree)
+	}
 }
@@ -0,0 +1,9 @@
+package parser
+
+// Codes of the syntax diagnostics.
+const (
+	UNEXPECTED_TOKEN = "P001"
+	UNEXPECTED_EOF   = "P002"
+	TRAILING_TOKENS  = "P003"
+	DIALECT_FEATURE  = "P004"
+)
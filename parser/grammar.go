@@ -0,0 +1,442 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	lexer "oberon/lexer"
+)
+
+// GRAMMAR is the syntax the parser accepts, in the EBNF of the Oberon-07
+// report. Quoted symbols and upper-case words are tokens; ident, integer,
+// real, character and string are literal tokens; every other name is a
+// production. The parser functions do not read it: it is the source of
+// the FIRST and FOLLOW sets used to say what was expected where parsing
+// failed. It also has the Oberon-2 extensions: receivers, the read-only
+// export mark "-", open array types, the WITH, LOOP, EXIT and RETURN
+// statements and the ELSE of CASE; and those of Component Pascal: record and method
+// attributes and the parameter modes IN and OUT. The parser reports them
+// outside of their dialects.
+const GRAMMAR = `
+module = MODULE ident ";" [ImportList] DeclarationSequence [BEGIN StatementSequence] END ident ".".
+ImportList = IMPORT import {"," import} ";".
+import = ident [":=" ident].
+qualident = [ident "."] ident.
+identdef = ident ["*" | "-"].
+
+DeclarationSequence = [CONST {ConstDeclaration ";"}] [TYPE {TypeDeclaration ";"}] [VAR {VariableDeclaration ";"}] {ProcedureDeclaration ";"}.
+ConstDeclaration = identdef "=" ConstExpression.
+ConstExpression = expression.
+TypeDeclaration = identdef "=" StrucType.
+VariableDeclaration = IdentList ":" type.
+ProcedureDeclaration = ProcedureHeading [";" ProcedureBody ident].
+ProcedureHeading = PROCEDURE [receiver] identdef [FormalParameters] MethodAttributes.
+receiver = "(" [VAR | IN] ident ":" ident ")".
+MethodAttributes = ["," ident] ["," (ABSTRACT | EMPTY | EXTENSIBLE)].
+ProcedureBody = DeclarationSequence [BEGIN StatementSequence] [RETURN expression] END.
+
+type = qualident | StrucType.
+StrucType = ArrayType | RecordType | PointerType | ProcedureType.
+ArrayType = ARRAY [length {"," length}] OF type.
+length = ConstExpression.
+RecordType = [ABSTRACT | EXTENSIBLE | LIMITED] RECORD ["(" BaseType ")"] [FieldListSequence] END.
+BaseType = qualident.
+FieldListSequence = FieldList {";" FieldList}.
+FieldList = IdentList ":" type.
+IdentList = identdef {"," identdef}.
+PointerType = POINTER TO type.
+ProcedureType = PROCEDURE [FormalParameters].
+FormalParameters = "(" [FPSection {";" FPSection}] ")" [":" qualident].
+FPSection = [VAR | IN | OUT] ident {"," ident} ":" FormalType.
+FormalType = {ARRAY OF} qualident.
+
+expression = SimpleExpression [relation SimpleExpression].
+relation = "=" | "#" | "<" | "<=" | ">" | ">=" | IN | IS.
+SimpleExpression = ["+" | "-"] term {AddOperator term}.
+AddOperator = "+" | "-" | OR.
+term = factor {MulOperator factor}.
+MulOperator = "*" | "/" | DIV | MOD | "&".
+factor = number | string | character | NIL | TRUE | FALSE | set | designator [ActualParameters] | "(" expression ")" | "~" factor.
+number = integer | real.
+designator = qualident {selector}.
+selector = "." ident | "[" ExpList "]" | "^" | "(" qualident ")".
+set = "{" [element {"," element}] "}".
+element = expression [".." expression].
+ExpList = expression {"," expression}.
+ActualParameters = "(" [ExpList] ")".
+
+StatementSequence = statement {";" statement}.
+statement = [assignment | ProcedureCall | IfStatement | CaseStatement | WhileStatement | RepeatStatement | ForStatement | WithStatement | LoopStatement | EXIT | ReturnStatement].
+assignment = designator ":=" expression.
+ProcedureCall = designator [ActualParameters].
+IfStatement = IF expression THEN StatementSequence {ELSIF expression THEN StatementSequence} [ELSE StatementSequence] END.
+CaseStatement = CASE expression OF case {"|" case} [ELSE StatementSequence] END.
+case = [CaseLabelList ":" StatementSequence].
+CaseLabelList = LabelRange {"," LabelRange}.
+LabelRange = label [".." label].
+label = integer | string | character | qualident.
+WhileStatement = WHILE expression DO StatementSequence {ELSIF expression DO StatementSequence} END.
+RepeatStatement = REPEAT StatementSequence UNTIL expression.
+ForStatement = FOR ident ":=" expression TO expression [BY ConstExpression] DO StatementSequence END.
+WithStatement = WITH guard DO StatementSequence {"|" guard DO StatementSequence} [ELSE StatementSequence] END.
+guard = qualident ":" qualident.
+LoopStatement = LOOP StatementSequence END.
+ReturnStatement = RETURN [expression].
+`
+
+// LITERAL_TOKENS are the names GRAMMAR uses for literal tokens.
+var LITERAL_TOKENS = map[string]lexer.TokenKind{
+	"ident":     lexer.IDENT,
+	"integer":   lexer.INTEGER,
+	"real":      lexer.REAL,
+	"character": lexer.CHAR,
+	"string":    lexer.STRING,
+}
+
+type ebnfKind int
+
+const (
+	ebnf_token ebnfKind = iota
+	ebnf_name
+	ebnf_sequence
+	ebnf_choice
+	ebnf_option
+	ebnf_repetition
+)
+
+// ebnf is an EBNF expression: a token, a production name, or a
+// sequence, choice, option or repetition of expressions.
+type ebnf struct {
+	kind     ebnfKind
+	token    lexer.TokenKind
+	name     string
+	children []*ebnf
+}
+
+// tokenSet is a set of token kinds.
+type tokenSet map[lexer.TokenKind]bool
+
+// add adds the kinds in other to s and reports whether s changed.
+func (s tokenSet) add(other tokenSet) bool {
+	var changed = false
+	for kind := range other {
+		if !s[kind] {
+			s[kind] = true
+			changed = true
+		}
+	}
+	return changed
+}
+
+// sorted returns the kinds in s in the order of their declaration.
+func (s tokenSet) sorted() []lexer.TokenKind {
+	var kinds []lexer.TokenKind
+	for kind := range s {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+var productions = make(map[string]*ebnf)
+var firstSets = make(map[string]tokenSet)
+var followSets = make(map[string]tokenSet)
+var nullable = make(map[string]bool)
+
+func init() {
+	readGrammar(GRAMMAR)
+	computeFirstSets()
+	computeFollowSets()
+
+	var declarations = make(tokenSet)
+	declarations.add(firstSets["DeclarationSequence"])
+	declarations.add(followSets["DeclarationSequence"])
+	DECLARATION_SYNC = declarations.sorted()
+	declarations.add(followSets["StatementSequence"])
+	STATEMENT_SYNC = declarations.sorted()
+}
+
+// first returns the tokens a phrase of production can start with.
+func first(production string) []lexer.TokenKind {
+	set, ok := firstSets[production]
+	if !ok {
+		panic(fmt.Sprintf("no production %s in GRAMMAR", production))
+	}
+	return set.sorted()
+}
+
+// follow returns the tokens that can follow a phrase of production.
+func follow(production string) []lexer.TokenKind {
+	set, ok := followSets[production]
+	if !ok {
+		panic(fmt.Sprintf("no production %s in GRAMMAR", production))
+	}
+	return set.sorted()
+}
+
+// startsWith reports whether the token at position can start a phrase of
+// production.
+func startsWith(lexemes *[]lexer.Token, position int, production string) bool {
+	return position < len(*lexemes) && firstSets[production][(*lexemes)[position].Kind]
+}
+
+// followedBy reports whether the token at position can follow a phrase
+// of production. The end of the file is taken to follow anything.
+func followedBy(lexemes *[]lexer.Token, position int, production string) bool {
+	return position >= len(*lexemes) || followSets[production][(*lexemes)[position].Kind]
+}
+
+// ----------------------------------------------------------------------------
+// Reading the grammar
+
+// grammarReader reads GRAMMAR. Its symbols are names, quoted tokens and
+// the EBNF punctuation = | . ( ) [ ] { }.
+type grammarReader struct {
+	symbols []string
+	index   int
+}
+
+func readGrammar(grammar string) {
+	var reader = grammarReader{symbols: grammarSymbols(grammar)}
+	var spellings = make(map[string]lexer.TokenKind)
+	for kind := lexer.ILLEGAL; kind < lexer.TOKEN_KINDS; kind++ {
+		if kind.IsOperator() || kind.IsKeyword() {
+			spellings[kind.String()] = kind
+		}
+	}
+	for reader.index < len(reader.symbols) {
+		name := reader.next()
+		reader.want("=")
+		productions[name] = reader.expression(spellings)
+		reader.want(".")
+	}
+	for name, production := range productions {
+		checkNames(name, production)
+	}
+}
+
+func grammarSymbols(grammar string) []string {
+	var symbols []string
+	var runes = []rune(grammar)
+	for i := 0; i < len(runes); {
+		switch c := runes[i]; {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			j := i + 1
+			for runes[j] != '"' {
+				j++
+			}
+			symbols = append(symbols, string(runes[i:j+1]))
+			i = j + 1
+		case unicode.IsLetter(c):
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			symbols = append(symbols, string(runes[i:j]))
+			i = j
+		default:
+			symbols = append(symbols, string(c))
+			i++
+		}
+	}
+	return symbols
+}
+
+func (r *grammarReader) peek() string {
+	if r.index < len(r.symbols) {
+		return r.symbols[r.index]
+	}
+	return ""
+}
+
+func (r *grammarReader) next() string {
+	symbol := r.peek()
+	r.index++
+	return symbol
+}
+
+func (r *grammarReader) want(symbol string) {
+	if next := r.next(); next != symbol {
+		panic(fmt.Sprintf("GRAMMAR: expected %s, found %s", symbol, next))
+	}
+}
+
+// expression = term {"|" term}.
+func (r *grammarReader) expression(spellings map[string]lexer.TokenKind) *ebnf {
+	var choice = &ebnf{kind: ebnf_choice, children: []*ebnf{r.term(spellings)}}
+	for r.peek() == "|" {
+		r.next()
+		choice.children = append(choice.children, r.term(spellings))
+	}
+	if len(choice.children) == 1 {
+		return choice.children[0]
+	}
+	return choice
+}
+
+// term = factor {factor}.
+func (r *grammarReader) term(spellings map[string]lexer.TokenKind) *ebnf {
+	var sequence = &ebnf{kind: ebnf_sequence}
+	for {
+		switch r.peek() {
+		case "|", ".", ")", "]", "}", "":
+			if len(sequence.children) == 1 {
+				return sequence.children[0]
+			}
+			return sequence
+		}
+		sequence.children = append(sequence.children, r.factor(spellings))
+	}
+}
+
+// factor = name | token | "(" expression ")" | "[" expression "]" |
+// "{" expression "}".
+func (r *grammarReader) factor(spellings map[string]lexer.TokenKind) *ebnf {
+	var symbol = r.next()
+	switch symbol {
+	case "(":
+		expression := r.expression(spellings)
+		r.want(")")
+		return expression
+	case "[":
+		expression := r.expression(spellings)
+		r.want("]")
+		return &ebnf{kind: ebnf_option, children: []*ebnf{expression}}
+	case "{":
+		expression := r.expression(spellings)
+		r.want("}")
+		return &ebnf{kind: ebnf_repetition, children: []*ebnf{expression}}
+	}
+	if strings.HasPrefix(symbol, `"`) {
+		symbol = strings.Trim(symbol, `"`)
+	} else if kind, ok := LITERAL_TOKENS[symbol]; ok {
+		return &ebnf{kind: ebnf_token, token: kind}
+	} else if strings.ToUpper(symbol) != symbol {
+		return &ebnf{kind: ebnf_name, name: symbol}
+	}
+	kind, ok := spellings[symbol]
+	if !ok {
+		panic(fmt.Sprintf("GRAMMAR: unknown token %s", symbol))
+	}
+	return &ebnf{kind: ebnf_token, token: kind}
+}
+
+func checkNames(production string, expression *ebnf) {
+	if expression.kind == ebnf_name {
+		if _, ok := productions[expression.name]; !ok {
+			panic(fmt.Sprintf("GRAMMAR: %s refers to undefined %s", production, expression.name))
+		}
+	}
+	for _, child := range expression.children {
+		checkNames(production, child)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// FIRST and FOLLOW sets
+
+// firstOf returns the tokens expression can start with and whether it can
+// be empty, given the sets computed so far.
+func firstOf(expression *ebnf) (tokenSet, bool) {
+	switch expression.kind {
+	case ebnf_token:
+		return tokenSet{expression.token: true}, false
+	case ebnf_name:
+		var set = make(tokenSet)
+		set.add(firstSets[expression.name])
+		return set, nullable[expression.name]
+	case ebnf_sequence:
+		var set = make(tokenSet)
+		for _, child := range expression.children {
+			childSet, childNullable := firstOf(child)
+			set.add(childSet)
+			if !childNullable {
+				return set, false
+			}
+		}
+		return set, true
+	case ebnf_choice:
+		var set = make(tokenSet)
+		var empty = false
+		for _, child := range expression.children {
+			childSet, childNullable := firstOf(child)
+			set.add(childSet)
+			empty = empty || childNullable
+		}
+		return set, empty
+	}
+	set, _ := firstOf(expression.children[0])
+	return set, true
+}
+
+func computeFirstSets() {
+	for name := range productions {
+		firstSets[name] = make(tokenSet)
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, production := range productions {
+			set, empty := firstOf(production)
+			if firstSets[name].add(set) {
+				changed = true
+			}
+			if empty && !nullable[name] {
+				nullable[name] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// addFollow adds to the FOLLOW set of every production named in
+// expression the tokens that can follow it, given that follow can follow
+// expression as a whole. It reports whether a set changed.
+func addFollow(expression *ebnf, follow tokenSet) bool {
+	var changed = false
+	switch expression.kind {
+	case ebnf_name:
+		changed = followSets[expression.name].add(follow)
+	case ebnf_sequence:
+		for i := len(expression.children) - 1; i >= 0; i-- {
+			child := expression.children[i]
+			if addFollow(child, follow) {
+				changed = true
+			}
+			childSet, childNullable := firstOf(child)
+			if childNullable {
+				childSet.add(follow)
+			}
+			follow = childSet
+		}
+	case ebnf_choice, ebnf_option:
+		for _, child := range expression.children {
+			if addFollow(child, follow) {
+				changed = true
+			}
+		}
+	case ebnf_repetition:
+		var set, _ = firstOf(expression.children[0])
+		set.add(follow)
+		changed = addFollow(expression.children[0], set)
+	}
+	return changed
+}
+
+func computeFollowSets() {
+	for name := range productions {
+		followSets[name] = make(tokenSet)
+	}
+	followSets["module"][lexer.EOF] = true
+	for changed := true; changed; {
+		changed = false
+		for name, production := range productions {
+			if addFollow(production, followSets[name]) {
+				changed = true
+			}
+		}
+	}
+}
@@ -3,10 +3,14 @@ package parser
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/op/go-logging"
 
+	ast "oberon/ast"
+	diag "oberon/diag"
 	lexer "oberon/lexer"
+	source "oberon/source"
 )
 
 var PARSER_LOG = logging.MustGetLogger("parser")
@@ -17,3516 +21,3001 @@ var parser_log_format = logging.MustStringFormatter(
 
 var parser_log_backend_formatter = logging.NewBackendFormatter(parser_log_backend, parser_log_format)
 var parserDebug = false
+var parserFile *source.SourceFile
+var parserDialect *lexer.Dialect
+var parserErrors []diag.Diagnostic
+
+// parserIllegal holds the offsets of the ILLEGAL tokens, whose errors the
+// lexer has already reported.
+var parserIllegal map[int]bool
+
+// STATEMENT_SYNC are the tokens at which a statement sequence resumes
+// after a syntax error, besides ";": the tokens that can follow a
+// statement sequence or start or follow a declaration sequence.
+var STATEMENT_SYNC []lexer.TokenKind
+
+// STATEMENT_ENDS are the tokens that end a statement: those after which
+// the statements skipped after a syntax error resume.
+var STATEMENT_ENDS = []lexer.TokenKind{lexer.SEMICOLON, lexer.END, lexer.ELSE, lexer.ELSIF, lexer.UNTIL, lexer.BAR}
+
+// DECLARATION_SYNC are the tokens at which a declaration sequence resumes
+// after a syntax error, besides ";": the tokens that can start or follow
+// a declaration sequence.
+var DECLARATION_SYNC []lexer.TokenKind
+
+// describe names a token the way it is shown in diagnostics.
+func describe(token lexer.Token) string {
+	switch token.Kind {
+	case lexer.IDENT:
+		return fmt.Sprintf("identifier %q", token.Label)
+	case lexer.INTEGER, lexer.REAL:
+		return fmt.Sprintf("number %s", token.Label)
+	case lexer.CHAR:
+		return fmt.Sprintf("character %s", token.Label)
+	case lexer.STRING:
+		return fmt.Sprintf("string %s", token.Label)
+	}
+	return fmt.Sprintf("%q", token.Label)
+}
+
+// span returns the span of the token at position. Once all tokens are
+// consumed it returns the empty span just after the last token.
+func span(lexemes *[]lexer.Token, position int) source.Span {
+	if position < len(*lexemes) {
+		token := (*lexemes)[position]
+		return parserFile.Span(token.Offset, token.End)
+	}
+	var end = 0
+	if len(*lexemes) > 0 {
+		end = (*lexemes)[len(*lexemes)-1].End
+	}
+	return parserFile.Span(end, end)
+}
+
+func parse_error(
+	message string,
+	lexemes *[]lexer.Token,
+	position *int,
+) diag.Diagnostic {
+	if *position < len(*lexemes) {
+		return reservedElsewhere(diag.Errorf(UNEXPECTED_TOKEN, span(lexemes, *position), "expected %s, found %s", message, describe((*lexemes)[*position])), lexemes, *position)
+	} else {
+		return diag.Errorf(UNEXPECTED_EOF, span(lexemes, *position), "expected %s, but reached end of file", message)
+	}
+}
 
-type ParseNode struct {
-	Label    string
-	Children []*ParseNode
+// reservedElsewhere adds a note to diagnostic if the token at position,
+// or the one before it, is an identifier that another dialect reserves,
+// as ABSTRACT in Oberon-07 source: the source may be in that dialect.
+func reservedElsewhere(diagnostic diag.Diagnostic, lexemes *[]lexer.Token, position int) diag.Diagnostic {
+	var from = position - 1
+	if from < 0 {
+		from = 0
+	}
+	for _, token := range (*lexemes)[from : position+1] {
+		if token.Kind != lexer.IDENT {
+			continue
+		}
+		for _, dialect := range lexer.DIALECTS {
+			if _, ok := dialect.ReservedWord(token.Label); ok && dialect != parserDialect {
+				return diagnostic.WithNote(parserFile.Span(token.Offset, token.End), "%s is a reserved word of %s, but an identifier in %s", token.Label, dialect, parserDialect)
+			}
+		}
+	}
+	return diagnostic
 }
 
-func PrintParserTree(root *ParseNode, indentation int) {
-	if nil == root {
+// report_error records a syntax error and lets parsing continue. Only the
+// first error at any offset is kept, so that an error found again by an
+// enclosing production is not reported twice, and none is kept at an
+// ILLEGAL token.
+func report_error(diagnostic diag.Diagnostic) {
+	if parserIllegal[diagnostic.Span.Start.Offset] {
 		return
 	}
-	var space = ""
-	for i := 0; i < indentation; i++ {
-		space += " "
+	for _, previous := range parserErrors {
+		if previous.Span.Start.Offset == diagnostic.Span.Start.Offset {
+			return
+		}
+	}
+	parserErrors = append(parserErrors, diagnostic)
+}
+
+// offset returns the offset of the token at position, or the end of the
+// last token once all tokens are consumed.
+func offset(lexemes *[]lexer.Token, position int) int {
+	return span(lexemes, position).Start.Offset
+}
+
+// at reports whether the token at position is of one of the given kinds.
+// The end of the file is always matched.
+func at(lexemes *[]lexer.Token, position int, kinds ...lexer.TokenKind) bool {
+	if position >= len(*lexemes) {
+		return true
+	}
+	for _, kind := range kinds {
+		if (*lexemes)[position].Kind == kind {
+			return true
+		}
 	}
-	fmt.Println(fmt.Sprintf("%s%s", space, root.Label))
-	for _, child := range root.Children {
-		PrintParserTree(child, indentation+1)
+	return false
+}
+
+// peek returns the kind of the token at position, or EOF once all tokens
+// are consumed.
+func peek(lexemes *[]lexer.Token, position int) lexer.TokenKind {
+	if position >= len(*lexemes) {
+		return lexer.EOF
 	}
+	return (*lexemes)[position].Kind
 }
 
-func parse_error(
+// skip consumes tokens up to one of the given kinds and returns the
+// offsets of the text skipped.
+func skip(lexemes *[]lexer.Token, position *int, kinds ...lexer.TokenKind) (int, int) {
+	var from = offset(lexemes, *position)
+	for !at(lexemes, *position, kinds...) {
+		(*position)++
+	}
+	return from, offset(lexemes, *position)
+}
+
+// describeKind names a token kind the way it is shown in diagnostics.
+func describeKind(kind lexer.TokenKind) string {
+	switch kind {
+	case lexer.IDENT:
+		return "identifier"
+	case lexer.INTEGER, lexer.REAL:
+		return "number"
+	case lexer.CHAR:
+		return "character"
+	case lexer.STRING:
+		return "string"
+	}
+	return fmt.Sprintf("%q", kind.String())
+}
+
+// expected lists token kinds the way they are shown in diagnostics, as in
+// "ELSIF", "ELSE" or "END". Kinds shown alike, such as INTEGER and REAL,
+// are listed once; kinds the dialect has no tokens for are left out.
+func expected(kinds ...lexer.TokenKind) string {
+	var names []string
+	var seen = make(map[string]bool)
+	for _, kind := range kinds {
+		if !parserDialect.HasToken(kind) {
+			continue
+		}
+		name := describeKind(kind)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// extension reports the construct found at span unless the dialect the
+// source is read as has it. The construct is parsed all the same.
+func extension(span source.Span, construct lexer.Extension) {
+	if parserDialect.Has(construct) {
+		return
+	}
+	var names []string
+	for _, dialect := range lexer.DIALECTS {
+		if dialect.Has(construct) {
+			names = append(names, fmt.Sprintf("%s (--dialect=%s)", dialect, dialect.Option))
+		}
+	}
+	report_error(diag.Errorf(DIALECT_FEATURE, span, "%s are not part of %s", construct, parserDialect).
+		WithNote(source.Span{}, "%s are part of %s", construct, strings.Join(names, " and ")))
+}
+
+// CONFUSED maps a token to the one written by mistake for it most often,
+// together with the help shown for the mistake.
+var CONFUSED = map[lexer.TokenKind]struct {
+	mistake lexer.TokenKind
+	help    string
+}{
+	lexer.BECOMES: {lexer.EQL, "assign with the assignment operator"},
+	lexer.EQL:     {lexer.BECOMES, "declare with an equals sign"},
+}
+
+// confused consumes the token at position if it is the one written for
+// kind by mistake, as "=" for ":=", and reports it with a suggestion to
+// replace it. Parsing goes on as if kind had been found.
+func confused(
+	lexemes *[]lexer.Token,
+	position *int,
+	kind lexer.TokenKind,
+	context string,
+) *lexer.Token {
+	confusion, ok := CONFUSED[kind]
+	if !ok || *position >= len(*lexemes) || (*lexemes)[*position].Kind != confusion.mistake {
+		return nil
+	}
+	report_error(parse_error(strings.TrimSpace(expected(kind)+" "+context), lexemes, position).
+		WithSuggestion(span(lexemes, *position), kind.String(), confusion.help))
+	token := &(*lexemes)[*position]
+	(*position)++
+	return token
+}
+
+// expect consumes a token of the given kind. A missing token is reported,
+// followed by context, as in `expected "THEN" after the IF condition`,
+// and not consumed; the offset returned is then that of the token found
+// in its place.
+func expect(
+	lexemes *[]lexer.Token,
+	position *int,
+	kind lexer.TokenKind,
+	context string,
+) int {
+	attempt_log(kind.String(), lexemes, position)
+	token := match(lexemes, position, kind)
+	if token == nil {
+		token = confused(lexemes, position, kind, context)
+	}
+	if token == nil {
+		did_not_match_log(kind.String(), lexemes, position)
+		report_error(parse_error(strings.TrimSpace(expected(kind)+" "+context), lexemes, position))
+		return offset(lexemes, *position)
+	}
+	matched_log(kind.String(), lexemes, position)
+	return token.Offset
+}
+
+// expectIdent consumes an identifier. A missing identifier is reported,
+// with message naming what was expected, and returned as an empty ident.
+func expectIdent(
+	lexemes *[]lexer.Token,
+	position *int,
 	message string,
-	lexemes *[]lexer.Lexeme,
+) *ast.Ident {
+	attempt_log("ident", lexemes, position)
+	token := match(lexemes, position, lexer.IDENT)
+	if token == nil {
+		did_not_match_log("ident", lexemes, position)
+		report_error(parse_error(message, lexemes, position))
+		return &ast.Ident{NamePos: offset(lexemes, *position)}
+	}
+	matched_log("ident", lexemes, position)
+	return newIdent(token)
+}
+
+// closingName consumes the identifier after the END of the procedure or
+// module called name. A missing identifier is reported with a suggestion
+// to add the name after END.
+func closingName(
+	lexemes *[]lexer.Token,
 	position *int,
-) error {
-	if *position < len(*lexemes) {
-		return fmt.Errorf("parse error: expected %s, found %v", message, (*lexemes)[*position])
+	what string,
+	name *ast.Ident,
+) *ast.Ident {
+	attempt_log("ident", lexemes, position)
+	token := match(lexemes, position, lexer.IDENT)
+	if token != nil {
+		matched_log("ident", lexemes, position)
+		return newIdent(token)
+	}
+	did_not_match_log("ident", lexemes, position)
+	if name.Name == "" {
+		report_error(parse_error(what+" name after END", lexemes, position))
+	} else if end := *position - 1; end >= 0 && (*lexemes)[end].Kind == lexer.END {
+		report_error(parse_error(fmt.Sprintf("%s name %s after END", what, name.Name), lexemes, position).
+			WithSuggestion(span(lexemes, end), "END "+name.Name, "end the %s with its name", what))
 	} else {
-		return fmt.Errorf("parse error: expected %s, but reached end of stream", message)
+		report_error(parse_error(fmt.Sprintf("%s name %s", what, name.Name), lexemes, position))
 	}
+	return &ast.Ident{NamePos: offset(lexemes, *position)}
+}
+
+// DESCRIPTIONS name the productions that missing reports as absent.
+var DESCRIPTIONS = map[string]string{
+	"expression":      "an expression",
+	"ConstExpression": "a constant expression",
+	"element":         "a set element",
+	"type":            "a type",
+	"StrucType":       "a structured type",
+	"qualident":       "a qualified identifier",
+	"FPSection":       "a parameter section",
+	"label":           "a case label",
+	"statement":       "a statement",
+}
+
+// missing reports that a phrase of production, named by what, was not
+// found and returns a placeholder for it. A note lists the tokens the
+// phrase can start with.
+func missing(
+	what string,
+	production string,
+	lexemes *[]lexer.Token,
+	position *int,
+) *ast.BadExpr {
+	report_error(parse_error(what, lexemes, position).
+		WithNote(source.Span{}, "%s starts with %s", DESCRIPTIONS[production], expected(first(production)...)))
+	var here = offset(lexemes, *position)
+	return &ast.BadExpr{From: here, To: here}
 }
 
+// debug logs what is being matched at position. The message is only
+// formatted when debugging is on, as the parser logs at every token.
 func debug(
+	action string,
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
 	if !parserDebug {
 		return
 	}
 	if *position < len(*lexemes) {
-		PARSER_LOG.Debug(fmt.Sprintf("%s (current_token: %v, position: %d)", message, ((*lexemes)[*position]), *position))
+		PARSER_LOG.Debug(fmt.Sprintf("%s %s (current_token: %v, position: %d)", action, message, ((*lexemes)[*position]), *position))
 	}
 }
 
 func attempt_log(
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
-	debug(fmt.Sprintf("Attempting to match %s", message), lexemes, position)
+	debug("Attempting to match", message, lexemes, position)
 }
 
 func attempt_optionally_log(
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
-	debug(fmt.Sprintf("Attempting to optionally match %s", message), lexemes, position)
+	debug("Attempting to optionally match", message, lexemes, position)
 }
 
 func did_not_match_log(
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
-	debug(fmt.Sprintf("Did not match %s", message), lexemes, position)
+	debug("Did not match", message, lexemes, position)
 }
 
 func did_not_match_optionally_log(
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
-	debug(fmt.Sprintf("Did not optionally match %s", message), lexemes, position)
+	debug("Did not optionally match", message, lexemes, position)
 }
 
 func matched_log(
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
-	debug(fmt.Sprintf("Matched %s", message), lexemes, position)
+	debug("Matched", message, lexemes, position)
 }
 
 func optionally_matched_log(
 	message string,
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
 ) {
-	debug(fmt.Sprintf("Optionally matched %s", message), lexemes, position)
+	debug("Optionally matched", message, lexemes, position)
 }
 
-func matchReservedWord(
-	lexemes *[]lexer.Lexeme,
+// match consumes the token at position if it is of the given kind.
+func match(
+	lexemes *[]lexer.Token,
 	position *int,
-	terminal string,
-) *ParseNode {
+	kind lexer.TokenKind,
+) *lexer.Token {
 	if *position >= len(*lexemes) {
 		return nil
 	}
-	lexeme := (*lexemes)[*position]
-	if lexeme.Label == terminal {
-		var terminalNode = new(ParseNode)
-		(*terminalNode).Label = lexeme.Label
+	lexeme := &(*lexemes)[*position]
+	if lexeme.Kind == kind {
 		(*position)++
-		return terminalNode
+		return lexeme
 	}
 	return nil
 }
 
-func matchOperator(
-	lexemes *[]lexer.Lexeme,
+// matchAny consumes the token at position if it is of one of the given
+// kinds.
+func matchAny(
+	lexemes *[]lexer.Token,
 	position *int,
-	operator string,
-) *ParseNode {
-	if *position >= len(*lexemes) {
-		return nil
-	}
-	lexeme := (*lexemes)[*position]
-	if lexeme.Typ == lexer.OP_OR_DELIM && lexeme.Label == operator {
-		var terminalNode = new(ParseNode)
-		(*terminalNode).Label = lexeme.Label
-		(*position)++
-		return terminalNode
+	kinds ...lexer.TokenKind,
+) *lexer.Token {
+	for _, kind := range kinds {
+		attempt_log(kind.String(), lexemes, position)
+		if token := match(lexemes, position, kind); token != nil {
+			matched_log(kind.String(), lexemes, position)
+			return token
+		}
+		did_not_match_log(kind.String(), lexemes, position)
 	}
 	return nil
 }
 
-func matchtype(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-	lexemetype lexer.Lexemetype,
-) *ParseNode {
-	if *position >= len(*lexemes) {
-		return nil
-	}
-	lexeme := (*lexemes)[*position]
-	if lexeme.Typ == lexemetype {
-		var terminalNode = new(ParseNode)
-		(*terminalNode).Label = lexeme.Label
-		(*position)++
-		return terminalNode
+func newIdent(token *lexer.Token) *ast.Ident {
+	return &ast.Ident{NamePos: token.Offset, Name: token.Label}
+}
+
+func newBasicLit(token *lexer.Token) *ast.BasicLit {
+	return &ast.BasicLit{
+		ValuePos:  token.Offset,
+		Kind:      token.Kind,
+		Value:     token.Label,
+		IntValue:  token.IntValue,
+		RealValue: token.RealValue,
+		StrValue:  token.StrValue,
 	}
-	return nil
 }
 
 // import = ident [":=" ident].
 func _import(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) *ParseNode {
-	var importNode = new(ParseNode)
-	importNode.Label = "import"
+) *ast.ImportDecl {
+	var importDecl = new(ast.ImportDecl)
 
 	// ident
 	attempt_log("ident", lexemes, position)
-	_identNode := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
+	_identToken := match(lexemes, position, lexer.IDENT)
+	if _identToken == nil {
 		did_not_match_log("ident", lexemes, position)
 		return nil
 	}
 	matched_log("ident", lexemes, position)
-
-	importNode.Children = append(importNode.Children, _identNode)
+	importDecl.Module = newIdent(_identToken)
 
 	// :=
-	attempt_log(":=", lexemes, position)
-	_assignmentOperator := matchOperator(lexemes, position, ":=")
+	attempt_optionally_log(":=", lexemes, position)
+	_assignmentOperator := match(lexemes, position, lexer.BECOMES)
 	if _assignmentOperator != nil {
 		// ident
-		matched_log(":=", lexemes, position)
-		attempt_log("ident", lexemes, position)
-		_identNode := matchtype(lexemes, position, lexer.IDENT)
-		if _identNode == nil {
-			did_not_match_log("ident", lexemes, position)
-			return nil
-		}
-		matched_log("ident", lexemes, position)
-		// importNode.Children = append(importNode.Children, _assignmentOperator)
-		importNode.Children = append(importNode.Children, _identNode)
+		optionally_matched_log(":=", lexemes, position)
+		importDecl.Alias = importDecl.Module
+		importDecl.Module = expectIdent(lexemes, position, "module name after \":=\"")
+	} else {
+		did_not_match_optionally_log(":=", lexemes, position)
 	}
-	return importNode
+	return importDecl
 }
 
 // ImportList = IMPORT import {"," import} ";".
 func importList(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var importListNode = new(ParseNode)
-	importListNode.Label = "importList"
-	var positionCheckpoint = *position
+) []*ast.ImportDecl {
+	var imports []*ast.ImportDecl
 
 	// IMPORT
 	attempt_log("IMPORT", lexemes, position)
-	_importReservedWordNode := matchReservedWord(lexemes, position, "IMPORT")
-	if _importReservedWordNode == nil {
+	_importReservedWord := match(lexemes, position, lexer.IMPORT)
+	if _importReservedWord == nil {
 		did_not_match_log("IMPORT", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("IMPORT", lexemes, position)
-	importListNode.Children = append(importListNode.Children, _importReservedWordNode)
-
-	// import
-	attempt_log("import", lexemes, position)
-	_importNode := _import(lexemes, position)
-	if _importNode == nil {
-		did_not_match_log("import", lexemes, position)
-		return nil, nil
-	}
-	matched_log("import", lexemes, position)
-	importListNode.Children = append(importListNode.Children, _importNode)
 
-	// {"," import}
+	// import {"," import}
 	for {
-		attempt_log(",", lexemes, position)
-		_commaNode := matchOperator(lexemes, position, ",")
-		if _commaNode == nil {
-			did_not_match_log(",", lexemes, position)
-			break
+		attempt_log("import", lexemes, position)
+		_importDecl := _import(lexemes, position)
+		if _importDecl == nil {
+			did_not_match_log("import", lexemes, position)
+			report_error(parse_error("module name", lexemes, position))
+		} else {
+			matched_log("import", lexemes, position)
+			imports = append(imports, _importDecl)
 		}
-		matched_log(",", lexemes, position)
-		_additionalImportNode := _import(lexemes, position)
-		if _additionalImportNode == nil {
-			debug("Did not find additionalImportNode after matching ','", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+
+		attempt_optionally_log(",", lexemes, position)
+		_commaToken := match(lexemes, position, lexer.COMMA)
+		if _commaToken == nil {
+			did_not_match_optionally_log(",", lexemes, position)
+			break
 		}
-		debug("Found additionalImportNode", lexemes, position)
-		// importListNode.Children = append(importListNode.Children, _commaNode)
-		importListNode.Children = append(importListNode.Children, _additionalImportNode)
+		optionally_matched_log(",", lexemes, position)
 	}
 
 	// ;
-	debug("Attempting to match ';'", lexemes, position)
-	_semicolonNode := matchOperator(lexemes, position, ";")
-	if _semicolonNode == nil {
-		debug("Did not match ';'", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	debug("Matched ';'", lexemes, position)
-	// importListNode.Children = append(importListNode.Children, _semicolonNode)
+	expect(lexemes, position, lexer.SEMICOLON, "after the import list")
 
-	return importListNode, nil
+	return imports
 }
 
-// qualident = [ident "."] ident.
+// qualident = [ident "."] ident. The "." is taken only when an identifier
+// follows it.
 func qualident(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var qualidentNode = new(ParseNode)
-	qualidentNode.Label = "qualident"
-	var positionCheckpoint = *position
+) *ast.QualIdent {
+	var qualidentNode = new(ast.QualIdent)
 
 	// [ident "."]
 	attempt_log("ident", lexemes, position)
-	var _identNode = matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
+	var _identToken = match(lexemes, position, lexer.IDENT)
+	if _identToken == nil {
 		did_not_match_log("ident", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	qualidentNode.Children = append(qualidentNode.Children, _identNode)
 	matched_log("ident", lexemes, position)
-	positionCheckpoint = *position
-
-	attempt_log(".", lexemes, position)
-	_dotOperatorNode := matchOperator(lexemes, position, ".")
-	if _dotOperatorNode != nil {
-		matched_log(".", lexemes, position)
+	qualidentNode.Name = newIdent(_identToken)
 
-		attempt_log("ident", lexemes, position)
-		_identNode := matchtype(lexemes, position, lexer.IDENT)
-		if _identNode == nil {
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		// qualidentNode.Children = append(qualidentNode.Children, _dotOperatorNode)
-		qualidentNode.Children = append(qualidentNode.Children, _identNode)
+	attempt_optionally_log(".", lexemes, position)
+	if peek(lexemes, *position) != lexer.PERIOD || peek(lexemes, *position+1) != lexer.IDENT {
+		did_not_match_optionally_log(".", lexemes, position)
+		return qualidentNode
 	}
+	match(lexemes, position, lexer.PERIOD)
+	optionally_matched_log(".", lexemes, position)
 
-	return qualidentNode, nil
+	// ident
+	qualidentNode.Module = qualidentNode.Name
+	qualidentNode.Name = newIdent(match(lexemes, position, lexer.IDENT))
+	matched_log("ident", lexemes, position)
+
+	return qualidentNode
 }
 
 // ExpList = expression {"," expression}.
 func expList(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var expListNode = new(ParseNode)
-	expListNode.Label = "expList"
-	var positionCheckpoint = *position
+) []ast.Expr {
+	var expressions []ast.Expr
 
 	// expression
 	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_expressionNode := expression(lexemes, position)
 	if nil == _expressionNode {
 		did_not_match_log("expression", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("expression", lexemes, position)
-	expListNode.Children = append(expListNode.Children, _expressionNode)
+	expressions = append(expressions, _expressionNode)
 
-	// {, expList}
+	// {"," expression}
 	for {
 		attempt_optionally_log(",", lexemes, position)
-		_commaOperatorNode := matchOperator(lexemes, position, ",")
-		if nil == _commaOperatorNode {
+		_commaOperatorToken := match(lexemes, position, lexer.COMMA)
+		if nil == _commaOperatorToken {
 			did_not_match_optionally_log(",", lexemes, position)
 			break
 		}
 		optionally_matched_log(",", lexemes, position)
 
 		attempt_log("expression", lexemes, position)
-		_expressionNode, err := expression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_expressionNode := expression(lexemes, position)
 		if nil == _expressionNode {
 			did_not_match_log("expression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			expressions = append(expressions, missing("expression after \",\"", "expression", lexemes, position))
+			continue
 		}
 		matched_log("expression", lexemes, position)
+		expressions = append(expressions, _expressionNode)
+	}
+	return expressions
+}
 
-		// expListNode.Children = append(expListNode.Children, _commaOperatorNode)
-		expListNode.Children = append(expListNode.Children, _expressionNode)
+// typeGuardAhead reports whether the tokens at position are a type guard
+// "(" qualident ")" followed by another selector or by ":=". Any other
+// parenthesized qualident that ends a designator is left to
+// ActualParameters, since only the declarations tell a type guard from a
+// call with one argument.
+func typeGuardAhead(lexemes *[]lexer.Token, position int) bool {
+	if peek(lexemes, position) != lexer.LPAREN || peek(lexemes, position+1) != lexer.IDENT {
+		return false
+	}
+	position += 2
+	if peek(lexemes, position) == lexer.PERIOD && peek(lexemes, position+1) == lexer.IDENT {
+		position += 2
+	}
+	if peek(lexemes, position) != lexer.RPAREN {
+		return false
 	}
-	return expListNode, nil
+	switch peek(lexemes, position+1) {
+	case lexer.PERIOD, lexer.LBRACK, lexer.ARROW, lexer.BECOMES:
+		return true
+	}
+	return false
 }
 
 // selector = "." ident | "[" ExpList "]" | "^" | "(" qualident ")".
 func selector(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var selectorNode = new(ParseNode)
-	selectorNode.Label = "selector"
-	var positionCheckpoint = *position
-
-	// "." ident
-	attempt_optionally_log(".", lexemes, position)
-	_dotOperatorNode := matchOperator(lexemes, position, ".")
-	if _dotOperatorNode != nil {
-		optionally_matched_log(".", lexemes, position)
+) ast.Selector {
+	switch peek(lexemes, *position) {
+	case lexer.PERIOD:
+		// "." ident
+		_dotOperatorToken := match(lexemes, position, lexer.PERIOD)
+		matched_log(".", lexemes, position)
 
-		attempt_log("ident", lexemes, position)
-		_identNode := matchtype(lexemes, position, lexer.IDENT)
-		if _identNode == nil {
-			did_not_match_log("ident", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+		return &ast.FieldSelector{
+			Period: _dotOperatorToken.Offset,
+			Name:   expectIdent(lexemes, position, "field name after \".\""),
 		}
-		did_not_match_log("ident", lexemes, position)
-		// selectorNode.Children = append(selectorNode.Children, _dotOperatorNode)
-		selectorNode.Children = append(selectorNode.Children, _identNode)
-
-		return selectorNode, nil
-	} else {
-		did_not_match_optionally_log(".", lexemes, position)
-	}
 
-	// "[" ExpList "]"
-	attempt_optionally_log("[", lexemes, position)
-	_leftBracketNode := matchOperator(lexemes, position, "[")
-	if _leftBracketNode != nil {
-		optionally_matched_log("[", lexemes, position)
+	case lexer.LBRACK:
+		// "[" ExpList "]"
+		_leftBracketToken := match(lexemes, position, lexer.LBRACK)
+		matched_log("[", lexemes, position)
 
 		attempt_log("expList", lexemes, position)
-		_expListNode, err := expList(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _expListNode == nil {
+		_expressions := expList(lexemes, position)
+		if _expressions == nil {
 			did_not_match_log("expList", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_expressions = []ast.Expr{missing("index after \"[\"", "expression", lexemes, position)}
+		} else {
+			matched_log("expList", lexemes, position)
 		}
-		matched_log("expList", lexemes, position)
 
-		attempt_log("]", lexemes, position)
-		_rightBracketNode := matchOperator(lexemes, position, "]")
-		if _rightBracketNode == nil {
-			did_not_match_log("]", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+		return &ast.IndexSelector{
+			Lbrack:  _leftBracketToken.Offset,
+			Indices: _expressions,
+			Rbrack:  expect(lexemes, position, lexer.RBRACK, "to close the index"),
 		}
-		matched_log("]", lexemes, position)
-
-		// selectorNode.Children = append(selectorNode.Children, _leftBracketNode)
-		selectorNode.Children = append(selectorNode.Children, _expListNode)
-		// selectorNode.Children = append(selectorNode.Children, _rightBracketNode)
-
-		return selectorNode, nil
-	} else {
-		did_not_match_optionally_log("[", lexemes, position)
-	}
-
-	// ^
-	attempt_optionally_log("^", lexemes, position)
-	_caratOperatorNode := matchOperator(lexemes, position, "^")
-	if _caratOperatorNode != nil {
-		optionally_matched_log("^", lexemes, position)
-		selectorNode.Children = append(selectorNode.Children, _caratOperatorNode)
-		return selectorNode, nil
-	}
-	did_not_match_optionally_log("^", lexemes, position)
 
-	// "(" qualident ")"
-	attempt_optionally_log("(", lexemes, position)
-	_leftParenNode := matchOperator(lexemes, position, "(")
-	if _leftParenNode != nil {
-		optionally_matched_log("(", lexemes, position)
+	case lexer.ARROW:
+		// ^
+		_caratOperatorToken := match(lexemes, position, lexer.ARROW)
+		matched_log("^", lexemes, position)
+		return &ast.DerefSelector{Arrow: _caratOperatorToken.Offset}
 
-		attempt_log("qualident", lexemes, position)
-		_qualidentNode, err := qualident(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _qualidentNode == nil {
-			did_not_match_log("qualident", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+	case lexer.LPAREN:
+		// "(" qualident ")"
+		if !typeGuardAhead(lexemes, *position) {
+			return nil
 		}
+		_leftParenToken := match(lexemes, position, lexer.LPAREN)
+		matched_log("(", lexemes, position)
+
+		_qualidentNode := qualident(lexemes, position)
 		matched_log("qualident", lexemes, position)
 
-		attempt_log(")", lexemes, position)
-		_rightParenNode := matchOperator(lexemes, position, ")")
-		if _rightParenNode == nil {
-			did_not_match_log(")", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
+		_rightParenToken := match(lexemes, position, lexer.RPAREN)
 		matched_log(")", lexemes, position)
 
-		selectorNode.Children = append(selectorNode.Children, _leftParenNode)
-		selectorNode.Children = append(selectorNode.Children, _qualidentNode)
-		selectorNode.Children = append(selectorNode.Children, _rightParenNode)
-
-		return selectorNode, nil
-	} else {
-		did_not_match_optionally_log("(", lexemes, position)
+		return &ast.TypeGuardSelector{
+			Lparen: _leftParenToken.Offset,
+			Type:   _qualidentNode,
+			Rparen: _rightParenToken.Offset,
+		}
 	}
 
-	return nil, nil
+	return nil
 }
 
 // designator = qualident {selector}.
 func designator(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var designatorNode = new(ParseNode)
-	designatorNode.Label = "designator"
+) *ast.Designator {
+	var designatorNode = new(ast.Designator)
 
 	// qualident
 	attempt_log("qualident", lexemes, position)
-	_qualidentNode, err := qualident(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_qualidentNode := qualident(lexemes, position)
 	if _qualidentNode == nil {
 		did_not_match_log("qualident", lexemes, position)
-		debug("Did not match qualident", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("qualident", lexemes, position)
-	designatorNode.Children = append(designatorNode.Children, _qualidentNode)
+	designatorNode.Qualident = _qualidentNode
 
 	// {selector}
 	for {
-		optionally_matched_log("selector", lexemes, position)
-		_selectorNode, err := selector(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		attempt_optionally_log("selector", lexemes, position)
+		_selectorNode := selector(lexemes, position)
 		if _selectorNode == nil {
 			did_not_match_optionally_log("selector", lexemes, position)
 			break
 		}
 		optionally_matched_log("selector", lexemes, position)
-		designatorNode.Children = append(designatorNode.Children, _selectorNode)
+		designatorNode.Selectors = append(designatorNode.Selectors, _selectorNode)
 	}
-	return designatorNode, nil
+	return designatorNode
 }
 
 // element = expression [".." expression].
 func element(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var elementNode = new(ParseNode)
-	elementNode.Label = "element"
-
+) ast.Expr {
 	// expression
 	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_expressionNode := expression(lexemes, position)
 	if _expressionNode == nil {
 		did_not_match_log("expression", lexemes, position)
-		return nil, err
+		return nil
 	}
 	matched_log("expression", lexemes, position)
-	elementNode.Children = append(elementNode.Children, _expressionNode)
 
-	// [ .. expression ]
+	// [".." expression]
 	attempt_optionally_log("..", lexemes, position)
-	_doubleDotOperator := matchOperator(lexemes, position, "..")
+	_doubleDotOperator := match(lexemes, position, lexer.UPTO)
 	if _doubleDotOperator != nil {
-		attempt_optionally_log("..", lexemes, position)
-		_elementNode, err := expression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _elementNode == nil {
-			return nil, nil
-		}
 		optionally_matched_log("..", lexemes, position)
-		elementNode.Children = append(elementNode.Children, _doubleDotOperator)
-		elementNode.Children = append(elementNode.Children, _elementNode)
+
+		attempt_log("expression", lexemes, position)
+		_highNode := expression(lexemes, position)
+		if _highNode == nil {
+			did_not_match_log("expression", lexemes, position)
+			return &ast.RangeExpr{Low: _expressionNode, High: missing("expression after \"..\"", "expression", lexemes, position)}
+		}
+		matched_log("expression", lexemes, position)
+		return &ast.RangeExpr{Low: _expressionNode, High: _highNode}
 	}
+	did_not_match_optionally_log("..", lexemes, position)
 
-	return elementNode, nil
+	return _expressionNode
 }
 
 // set = "{" [element {"," element}] "}".
 func set(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var setNode = new(ParseNode)
-	setNode.Label = "set"
-	var positionCheckpoint = *position
+) *ast.SetExpr {
+	var setNode = new(ast.SetExpr)
 
 	// {
 	attempt_log("{", lexemes, position)
-	_leftbraceNode := matchOperator(lexemes, position, "{")
-	if _leftbraceNode == nil {
+	_leftBraceToken := match(lexemes, position, lexer.LBRACE)
+	if _leftBraceToken == nil {
 		did_not_match_log("{", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("{", lexemes, position)
-	setNode.Children = append(setNode.Children, _leftbraceNode)
+	setNode.Lbrace = _leftBraceToken.Offset
 
 	// element
 	attempt_optionally_log("element", lexemes, position)
-	_elementNode, err := element(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_elementNode := element(lexemes, position)
 	if _elementNode != nil {
 		optionally_matched_log("element", lexemes, position)
-		setNode.Children = append(setNode.Children, _elementNode)
-		// {, element}
+		setNode.Elements = append(setNode.Elements, _elementNode)
+		// {"," element}
 		for {
-			_commaNode := matchOperator(lexemes, position, ",")
-			if _commaNode == nil {
-				did_not_match_optionally_log("element", lexemes, position)
+			attempt_optionally_log(",", lexemes, position)
+			_commaToken := match(lexemes, position, lexer.COMMA)
+			if _commaToken == nil {
+				did_not_match_optionally_log(",", lexemes, position)
 				break
 			}
-			optionally_matched_log("element", lexemes, position)
+			optionally_matched_log(",", lexemes, position)
 
 			attempt_log("element", lexemes, position)
-			_elementNode, err := element(lexemes, position)
-			if err != nil {
-				return nil, err
-			}
+			_elementNode := element(lexemes, position)
 			if _elementNode == nil {
 				did_not_match_log("element", lexemes, position)
-				*position = positionCheckpoint
-				return nil, nil
+				setNode.Elements = append(setNode.Elements, missing("set element after \",\"", "element", lexemes, position))
+				continue
 			}
 			matched_log("element", lexemes, position)
-			setNode.Children = append(setNode.Children, _commaNode)
-			setNode.Children = append(setNode.Children, _elementNode)
+			setNode.Elements = append(setNode.Elements, _elementNode)
 		}
 	} else {
-		did_not_match_log("element", lexemes, position)
+		did_not_match_optionally_log("element", lexemes, position)
 	}
 
 	// }
-	attempt_log("}", lexemes, position)
-	_rightBraceNode := matchOperator(lexemes, position, "}")
-	if _rightBraceNode == nil {
-		did_not_match_log("}", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("}", lexemes, position)
-	setNode.Children = append(setNode.Children, _rightBraceNode)
+	setNode.Rbrace = expect(lexemes, position, lexer.RBRACE, "to close the set")
 
-	return setNode, nil
+	return setNode
 }
 
-// "(" [ExpList] ")".
+// ActualParameters = "(" [ExpList] ")". The call returned has no Fun; the
+// caller fills in the designator the parameters are passed to.
 func actualParameters(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var actualParametersNode = new(ParseNode)
-	actualParametersNode.Label = "actualParameters"
-	var positionCheckpoint = *position
+) *ast.CallExpr {
+	var callNode = new(ast.CallExpr)
 
 	// "("
 	attempt_log("(", lexemes, position)
-	_leftParenNode := matchOperator(lexemes, position, "(")
-	if _leftParenNode == nil {
+	_leftParenToken := match(lexemes, position, lexer.LPAREN)
+	if _leftParenToken == nil {
 		did_not_match_log("(", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	actualParametersNode.Children = append(actualParametersNode.Children, _leftParenNode)
 	matched_log("(", lexemes, position)
+	callNode.Lparen = _leftParenToken.Offset
 
-	// [expList]
+	// [ExpList]
 	attempt_optionally_log("expList", lexemes, position)
-	_expListNode, err := expList(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expListNode != nil {
+	_expressions := expList(lexemes, position)
+	if _expressions != nil {
+		optionally_matched_log("expList", lexemes, position)
+		callNode.Args = _expressions
+	} else {
 		did_not_match_optionally_log("expList", lexemes, position)
-		actualParametersNode.Children = append(actualParametersNode.Children, _expListNode)
 	}
-	optionally_matched_log("expList", lexemes, position)
 
-	// )
-	attempt_log(")", lexemes, position)
-	_rightParenNode := matchOperator(lexemes, position, ")")
-	if _rightParenNode == nil {
-		did_not_match_log(")", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log(")", lexemes, position)
+	// ")"
+	callNode.Rparen = expect(lexemes, position, lexer.RPAREN, "to close the parameter list")
 
-	actualParametersNode.Children = append(actualParametersNode.Children, _leftParenNode)
-	return actualParametersNode, nil
+	return callNode
 }
 
-// factor = number | string | NIL | TRUE | FALSE | set | designator [ActualParameters] | "(" expression ")" | "~" factor.
+// factor = number | string | character | NIL | TRUE | FALSE | set | designator [ActualParameters] | "(" expression ")" | "~" factor.
 func factor(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var factorNode = new(ParseNode)
-	factorNode.Label = "factor"
-	var positionCheckpoint = *position
-
-	// number
-	{
-		// BUG: given that a term in SimpleExpression may be preceded by a
-		// + or a - already, this is unnecessary, but without this,
-		// -10 and +10 are unrecognized.
-		attempt_optionally_log("+", lexemes, position)
-		_plusOperatorNode := matchOperator(lexemes, position, "+")
-		if _plusOperatorNode == nil {
-			did_not_match_optionally_log("+", lexemes, position)
-			attempt_optionally_log("-", lexemes, position)
-			_minusOperatorNode := matchOperator(lexemes, position, "-")
-			if _minusOperatorNode != nil {
-				optionally_matched_log("-", lexemes, position)
-				factorNode.Children = append(factorNode.Children, _minusOperatorNode)
-			} else {
-				did_not_match_optionally_log("-", lexemes, position)
-			}
-		} else {
-			optionally_matched_log("+", lexemes, position)
-			factorNode.Children = append(factorNode.Children, _plusOperatorNode)
-		}
-
-		attempt_log("integer", lexemes, position)
-		_integerNode := matchtype(lexemes, position, lexer.INTEGER)
-		if _integerNode != nil {
-			matched_log("integer", lexemes, position)
-			factorNode.Children = append(factorNode.Children, _integerNode)
-			debug("Matched integer", lexemes, position)
-			return factorNode, nil
-		}
-		did_not_match_log("integer", lexemes, position)
-
-		attempt_log("real", lexemes, position)
-		_realNode := matchtype(lexemes, position, lexer.REAL)
-		if _realNode != nil {
-			matched_log("real", lexemes, position)
-			factorNode.Children = append(factorNode.Children, _realNode)
-			return factorNode, nil
-		}
-		did_not_match_log("real", lexemes, position)
-
-		*position = positionCheckpoint
-		// TODO: determine whether it is sage to return nil, nil here
-	}
+	lexemes *[]lexer.Token,
+	position *int,
+) ast.Expr {
+	switch peek(lexemes, *position) {
+	case lexer.INTEGER, lexer.REAL, lexer.STRING, lexer.CHAR:
+		// number | string | character
+		_literalToken := matchAny(lexemes, position, lexer.INTEGER, lexer.REAL, lexer.STRING, lexer.CHAR)
+		matched_log(_literalToken.Kind.String(), lexemes, position)
+		return newBasicLit(_literalToken)
+
+	case lexer.NIL:
+		_nilToken := match(lexemes, position, lexer.NIL)
+		matched_log("NIL", lexemes, position)
+		return &ast.NilLit{NilPos: _nilToken.Offset}
 
-	// string
-	attempt_log("string", lexemes, position)
-	_stringNode := matchtype(lexemes, position, lexer.STRING)
-	if _stringNode != nil {
-		matched_log("string", lexemes, position)
-		factorNode.Children = append(factorNode.Children, _stringNode)
-		return factorNode, nil
-	}
-	did_not_match_log("string", lexemes, position)
+	case lexer.TRUE, lexer.FALSE:
+		_boolToken := matchAny(lexemes, position, lexer.TRUE, lexer.FALSE)
+		matched_log(_boolToken.Kind.String(), lexemes, position)
+		return &ast.BoolLit{ValuePos: _boolToken.Offset, Value: _boolToken.Kind == lexer.TRUE}
 
-	// NIL
-	attempt_log("NIL", lexemes, position)
-	_nilNode := matchReservedWord(lexemes, position, "NIL")
-	if _nilNode != nil {
-		matched_log("NIL", lexemes, position)
-		factorNode.Children = append(factorNode.Children, _nilNode)
-		return factorNode, nil
-	}
-	did_not_match_log("NIL", lexemes, position)
-
-	// TRUE
-	attempt_log("TRUE", lexemes, position)
-	_trueNode := matchReservedWord(lexemes, position, "TRUE")
-	if _trueNode != nil {
-		matched_log("TRUE", lexemes, position)
-		factorNode.Children = append(factorNode.Children, _trueNode)
-		return factorNode, nil
-	}
-	did_not_match_log("TRUE", lexemes, position)
-
-	// FALSE
-	attempt_log("FALSE", lexemes, position)
-	_falseNode := matchReservedWord(lexemes, position, "FALSE")
-	if _falseNode != nil {
-		matched_log("FALSE", lexemes, position)
-		debug("Matched FALSE", lexemes, position)
-		factorNode.Children = append(factorNode.Children, _falseNode)
-		return factorNode, nil
-	}
-	did_not_match_log("FALSE", lexemes, position)
-
-	// set
-	attempt_log("set", lexemes, position)
-	_setNode, err := set(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _setNode != nil {
+	case lexer.LBRACE:
+		attempt_log("set", lexemes, position)
+		_setNode := set(lexemes, position)
 		matched_log("set", lexemes, position)
-		factorNode.Children = append(factorNode.Children, _setNode)
-		return factorNode, nil
-	}
-	did_not_match_log("set", lexemes, position)
+		return _setNode
 
-	// designator [ActualParameters]
-	attempt_log("designator", lexemes, position)
-	_designatorNode, err := designator(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _designatorNode != nil {
+	case lexer.IDENT:
+		// designator [ActualParameters]
+		attempt_log("designator", lexemes, position)
+		_designatorNode := designator(lexemes, position)
 		matched_log("designator", lexemes, position)
-		factorNode.Children = append(factorNode.Children, _designatorNode)
 
-		optionally_matched_log("actualParameters", lexemes, position)
-		_actualParametersNode, err := actualParameters(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if nil != _actualParametersNode {
+		attempt_optionally_log("actualParameters", lexemes, position)
+		_callNode := actualParameters(lexemes, position)
+		if nil != _callNode {
 			optionally_matched_log("actualParameters", lexemes, position)
-			factorNode.Children = append(factorNode.Children, _actualParametersNode)
-		} else {
-			did_not_match_optionally_log("actualParameters", lexemes, position)
+			_callNode.Fun = _designatorNode
+			return _callNode
 		}
-		return factorNode, nil
-	}
-	did_not_match_log("designator", lexemes, position)
+		did_not_match_optionally_log("actualParameters", lexemes, position)
+		return _designatorNode
 
-	// "(" expression ")"
-	attempt_log("(", lexemes, position)
-	_leftParenNode := matchOperator(lexemes, position, "(")
-	if _leftParenNode != nil {
+	case lexer.LPAREN:
+		// "(" expression ")"
+		_leftParenToken := match(lexemes, position, lexer.LPAREN)
 		matched_log("(", lexemes, position)
 
 		attempt_log("expression", lexemes, position)
-		_expressionNode, err := expression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_expressionNode := expression(lexemes, position)
 		if nil == _expressionNode {
 			did_not_match_log("expression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_expressionNode = missing("expression after \"(\"", "expression", lexemes, position)
+		} else {
+			matched_log("expression", lexemes, position)
 		}
-		matched_log("expression", lexemes, position)
 
-		attempt_log(")", lexemes, position)
-		_rightParenNode := matchOperator(lexemes, position, ")")
-		if err != nil {
-			return nil, err
-		}
-		if _rightParenNode == nil {
-			did_not_match_log(")", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+		return &ast.ParenExpr{
+			Lparen: _leftParenToken.Offset,
+			X:      _expressionNode,
+			Rparen: expect(lexemes, position, lexer.RPAREN, "to close the parenthesized expression"),
 		}
-		matched_log(")", lexemes, position)
-
-		factorNode.Children = append(factorNode.Children, _leftParenNode)
-		factorNode.Children = append(factorNode.Children, _expressionNode)
-		factorNode.Children = append(factorNode.Children, _rightParenNode)
 
-		return factorNode, nil
-	}
+	case lexer.NOT:
+		// "~" factor
+		_tildeOperatorToken := match(lexemes, position, lexer.NOT)
+		matched_log("~", lexemes, position)
 
-	// "~" factor
-	attempt_log("~", lexemes, position)
-	_tildeOperator := matchOperator(lexemes, position, "~")
-	if _tildeOperator == nil {
-		did_not_match_log("~", lexemes, position)
-		return nil, nil
-	}
-	matched_log("~", lexemes, position)
+		attempt_log("factor", lexemes, position)
+		_factorNode := factor(lexemes, position)
+		if nil == _factorNode {
+			did_not_match_log("factor", lexemes, position)
+			_factorNode = missing("expression after \"~\"", "expression", lexemes, position)
+		} else {
+			matched_log("factor", lexemes, position)
+		}
 
-	attempt_log("factor", lexemes, position)
-	_factorNode, err := factor(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if nil == _factorNode {
-		did_not_match_log("factor", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		return &ast.UnaryExpr{OpPos: _tildeOperatorToken.Offset, Op: lexer.NOT, X: _factorNode}
 	}
-	matched_log("factor", lexemes, position)
-
-	factorNode.Children = append(factorNode.Children, _tildeOperator)
-	factorNode.Children = append(factorNode.Children, _factorNode)
 
-	return factorNode, nil
+	return nil
 }
 
-func mulOperator(
-	lexemes *[]lexer.Lexeme,
+// operator consumes the current token if it is a binary operator of the
+// given precedence.
+func operator(
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var mulOperatorNode = new(ParseNode)
-	mulOperatorNode.Label = "mulOperator"
-
-	attempt_log("*", lexemes, position)
-	_asterixOperatorNode := matchOperator(lexemes, position, "*")
-	if _asterixOperatorNode != nil {
-		matched_log("*", lexemes, position)
-		mulOperatorNode.Children = append(mulOperatorNode.Children, _asterixOperatorNode)
-		return mulOperatorNode, nil
-	}
-	matched_log("*", lexemes, position)
-
-	attempt_log("/", lexemes, position)
-	_divOperatorNode := matchOperator(lexemes, position, "/")
-	if _divOperatorNode != nil {
-		matched_log("/", lexemes, position)
-		mulOperatorNode.Children = append(mulOperatorNode.Children, _divOperatorNode)
-		return mulOperatorNode, nil
-	}
-	matched_log("/", lexemes, position)
-
-	attempt_log("DIV", lexemes, position)
-	_divNode := matchReservedWord(lexemes, position, "DIV")
-	if _divNode != nil {
-		matched_log("DIV", lexemes, position)
-		mulOperatorNode.Children = append(mulOperatorNode.Children, _divNode)
-		return mulOperatorNode, nil
-	}
-	matched_log("DIV", lexemes, position)
-
-	attempt_log("MOD", lexemes, position)
-	_modeOperatorNode := matchReservedWord(lexemes, position, "MOD")
-	if _modeOperatorNode != nil {
-		matched_log("MOD", lexemes, position)
-		mulOperatorNode.Children = append(mulOperatorNode.Children, _modeOperatorNode)
-		return mulOperatorNode, nil
-	}
-	matched_log("MOD", lexemes, position)
-
-	attempt_log("&", lexemes, position)
-	_ampersandOperatorNode := matchOperator(lexemes, position, "&")
-	if _ampersandOperatorNode != nil {
-		matched_log("&", lexemes, position)
-		mulOperatorNode.Children = append(mulOperatorNode.Children, _ampersandOperatorNode)
-		return mulOperatorNode, nil
+	precedence int,
+) *lexer.Token {
+	if peek(lexemes, *position).Precedence() != precedence {
+		return nil
 	}
-	matched_log("&", lexemes, position)
+	_operatorToken := &(*lexemes)[*position]
+	(*position)++
+	return _operatorToken
+}
 
-	return nil, nil
+// MulOperator = "*" | "/" | DIV | MOD | "&".
+func mulOperator(
+	lexemes *[]lexer.Token,
+	position *int,
+) *lexer.Token {
+	return operator(lexemes, position, lexer.MUL_PRECEDENCE)
 }
 
+// term = factor {MulOperator factor}.
 func term(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var termNode = new(ParseNode)
-	termNode.Label = "term"
-	var positionCheckpoint = *position
-
+) ast.Expr {
 	attempt_log("factor", lexemes, position)
-	_factorNode, err := factor(lexemes, position)
-	if err != nil {
-		debug("Error matching factor", lexemes, position)
-		return nil, err
-	}
-	if _factorNode == nil {
+	_termNode := factor(lexemes, position)
+	if _termNode == nil {
 		did_not_match_log("factor", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	debug("Matched factor", lexemes, position)
-	termNode.Children = append(termNode.Children, _factorNode)
+	matched_log("factor", lexemes, position)
+
 	for {
 		attempt_optionally_log("mulOperator", lexemes, position)
-		_mulOperatorNode, err := mulOperator(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if nil == _mulOperatorNode {
+		_mulOperatorToken := mulOperator(lexemes, position)
+		if nil == _mulOperatorToken {
 			did_not_match_optionally_log("mulOperator", lexemes, position)
 			break
 		}
+		optionally_matched_log("mulOperator", lexemes, position)
 
 		attempt_log("factor", lexemes, position)
-		_factorNode, err := factor(lexemes, position)
-		if err != nil {
-			*position = positionCheckpoint
-			return nil, err
-		}
+		_factorNode := factor(lexemes, position)
 		if nil == _factorNode {
 			did_not_match_log("factor", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_factorNode = missing(fmt.Sprintf("expression after %q", _mulOperatorToken.Label), "expression", lexemes, position)
+		} else {
+			matched_log("factor", lexemes, position)
 		}
-		matched_log("factor", lexemes, position)
 
-		termNode.Children = append(termNode.Children, _mulOperatorNode)
-		termNode.Children = append(termNode.Children, _factorNode)
-		positionCheckpoint = *position
+		_termNode = &ast.BinaryExpr{
+			X:     _termNode,
+			OpPos: _mulOperatorToken.Offset,
+			Op:    _mulOperatorToken.Kind,
+			Y:     _factorNode,
+		}
 	}
-	return termNode, nil
+	return _termNode
 }
 
+// AddOperator = "+" | "-" | OR.
 func addOperator(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var addOperatorNode = new(ParseNode)
-	addOperatorNode.Label = "addOperator"
-
-	attempt_optionally_log("+", lexemes, position)
-	_plusOperatorNode := matchOperator(lexemes, position, "+")
-	if _plusOperatorNode != nil {
-		matched_log("+", lexemes, position)
-		addOperatorNode.Children = append(addOperatorNode.Children, _plusOperatorNode)
-		return addOperatorNode, nil
-	} else {
-		did_not_match_optionally_log("+", lexemes, position)
-	}
-
-	attempt_optionally_log("-", lexemes, position)
-	_minusOperatorNode := matchOperator(lexemes, position, "-")
-	if _minusOperatorNode != nil {
-		matched_log("-", lexemes, position)
-		addOperatorNode.Children = append(addOperatorNode.Children, _minusOperatorNode)
-		return addOperatorNode, nil
-	} else {
-		did_not_match_optionally_log("-", lexemes, position)
-	}
-
-	attempt_optionally_log("OR", lexemes, position)
-	_orOperatorNode := matchReservedWord(lexemes, position, "OR")
-	if _orOperatorNode != nil {
-		matched_log("OR", lexemes, position)
-		addOperatorNode.Children = append(addOperatorNode.Children, _orOperatorNode)
-		return addOperatorNode, nil
-	} else {
-		did_not_match_optionally_log("OR", lexemes, position)
-	}
-
-	return nil, nil
+	lexemes *[]lexer.Token,
+	position *int,
+) *lexer.Token {
+	return operator(lexemes, position, lexer.ADD_PRECEDENCE)
 }
 
+// SimpleExpression = ["+" | "-"] term {AddOperator term}.
 func simpleExpression(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var simpleExpressionNode = new(ParseNode)
-	simpleExpressionNode.Label = "simpleExpression"
-	var positionCheckpoint = *position
-
-	attempt_optionally_log("+", lexemes, position)
-	_plusOperatorNode := matchOperator(lexemes, position, "+")
-	if nil == _plusOperatorNode {
-		did_not_match_optionally_log("+", lexemes, position)
-
-		attempt_optionally_log("-", lexemes, position)
-		_minusOperatorNode := matchOperator(lexemes, position, "-")
-		if nil != _minusOperatorNode {
-			optionally_matched_log("-", lexemes, position)
-			simpleExpressionNode.Children = append(simpleExpressionNode.Children, _minusOperatorNode)
-		} else {
-			did_not_match_optionally_log("-", lexemes, position)
-		}
+	lexemes *[]lexer.Token,
+	position *int,
+) ast.Expr {
+	attempt_optionally_log("sign", lexemes, position)
+	_signToken := matchAny(lexemes, position, lexer.PLUS, lexer.MINUS)
+	if _signToken != nil {
+		optionally_matched_log("sign", lexemes, position)
 	} else {
-		optionally_matched_log("+", lexemes, position)
-		simpleExpressionNode.Children = append(simpleExpressionNode.Children, _plusOperatorNode)
+		did_not_match_optionally_log("sign", lexemes, position)
 	}
 
 	attempt_log("term", lexemes, position)
-	_termNode, err := term(lexemes, position)
-	if err != nil {
-		*position = positionCheckpoint
-		return nil, err
-	}
-	if nil == _termNode {
+	_simpleExpressionNode := term(lexemes, position)
+	if nil == _simpleExpressionNode {
 		did_not_match_log("term", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		if _signToken == nil {
+			return nil
+		}
+		_simpleExpressionNode = missing(fmt.Sprintf("expression after %q", _signToken.Label), "expression", lexemes, position)
+	} else {
+		matched_log("term", lexemes, position)
+	}
+	if _signToken != nil {
+		_simpleExpressionNode = &ast.UnaryExpr{
+			OpPos: _signToken.Offset,
+			Op:    _signToken.Kind,
+			X:     _simpleExpressionNode,
+		}
 	}
-	simpleExpressionNode.Children = append(simpleExpressionNode.Children, _termNode)
-	matched_log("term", lexemes, position)
 
 	for {
 		attempt_optionally_log("addOperator", lexemes, position)
-		_addOperatorNode, err := addOperator(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if nil == _addOperatorNode {
+		_addOperatorToken := addOperator(lexemes, position)
+		if nil == _addOperatorToken {
 			did_not_match_optionally_log("addOperator", lexemes, position)
 			break
 		}
 		optionally_matched_log("addOperator", lexemes, position)
 
 		attempt_log("term", lexemes, position)
-		_termNode, err := term(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_termNode := term(lexemes, position)
 		if nil == _termNode {
 			did_not_match_log("term", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_termNode = missing(fmt.Sprintf("expression after %q", _addOperatorToken.Label), "expression", lexemes, position)
+		} else {
+			matched_log("term", lexemes, position)
+		}
+
+		_simpleExpressionNode = &ast.BinaryExpr{
+			X:     _simpleExpressionNode,
+			OpPos: _addOperatorToken.Offset,
+			Op:    _addOperatorToken.Kind,
+			Y:     _termNode,
 		}
-		matched_log("term", lexemes, position)
-		simpleExpressionNode.Children = append(simpleExpressionNode.Children, _addOperatorNode)
-		simpleExpressionNode.Children = append(simpleExpressionNode.Children, _termNode)
 	}
-	return simpleExpressionNode, nil
+	return _simpleExpressionNode
 }
 
+// relation = "=" | "#" | "<" | "<=" | ">" | ">=" | IN | IS.
 func relation(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var relationOperatorNode = new(ParseNode)
-	relationOperatorNode.Label = "relation"
-
-	attempt_log("=", lexemes, position)
-	_equalOperatorNode := matchOperator(lexemes, position, "=")
-	if _equalOperatorNode != nil {
-		matched_log("=", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _equalOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log("=", lexemes, position)
-
-	attempt_log("#", lexemes, position)
-	_hashOperatorNode := matchOperator(lexemes, position, "#")
-	if _hashOperatorNode != nil {
-		matched_log("#", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _hashOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log("#", lexemes, position)
-
-	attempt_log("<", lexemes, position)
-	_lessThanOperatorNode := matchOperator(lexemes, position, "<")
-	if _lessThanOperatorNode != nil {
-		matched_log("<", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _lessThanOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log("<", lexemes, position)
-
-	attempt_log("<=", lexemes, position)
-	_lessThanEqualOperatorNode := matchOperator(lexemes, position, "<=")
-	if _lessThanEqualOperatorNode != nil {
-		matched_log("<=", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _lessThanEqualOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log("<=", lexemes, position)
-
-	attempt_log(">", lexemes, position)
-	_greaterThanOperatorNode := matchOperator(lexemes, position, ">")
-	if _greaterThanOperatorNode != nil {
-		matched_log(">", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _greaterThanOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log(">", lexemes, position)
-
-	attempt_log(">=", lexemes, position)
-	_greaterThanEqualOperatorNode := matchOperator(lexemes, position, ">=")
-	if _greaterThanEqualOperatorNode != nil {
-		matched_log(">=", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _greaterThanEqualOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log(">=", lexemes, position)
-
-	attempt_log("IN", lexemes, position)
-	_inOperatorNode := matchReservedWord(lexemes, position, "IN")
-	if _inOperatorNode != nil {
-		matched_log("IN", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _inOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log("IN", lexemes, position)
-
-	attempt_log("IS", lexemes, position)
-	_isOperatorNode := matchReservedWord(lexemes, position, "IS")
-	if _isOperatorNode != nil {
-		matched_log("IS", lexemes, position)
-		relationOperatorNode.Children = append(relationOperatorNode.Children, _isOperatorNode)
-		return relationOperatorNode, nil
-	}
-	did_not_match_log("IS", lexemes, position)
-
-	return nil, nil
+) *lexer.Token {
+	return operator(lexemes, position, lexer.RELATION_PRECEDENCE)
 }
 
+// expression = SimpleExpression [relation SimpleExpression].
+//
+// The right side of IS is a type, so it is parsed as a qualident.
 func expression(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var expressionNode = new(ParseNode)
-	expressionNode.Label = "expression"
-	var positionCheckpoint = *position
-
+) ast.Expr {
 	attempt_log("simpleExpression", lexemes, position)
-	_simpleExpressionNode, err := simpleExpression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_simpleExpressionNode := simpleExpression(lexemes, position)
 	if _simpleExpressionNode == nil {
 		did_not_match_log("simpleExpression", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	expressionNode.Children = append(expressionNode.Children, _simpleExpressionNode)
 	matched_log("simpleExpression", lexemes, position)
 
 	attempt_optionally_log("relation", lexemes, position)
-	_relationNode, err := relation(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _relationNode != nil {
+	_relationToken := relation(lexemes, position)
+	if _relationToken != nil {
 		optionally_matched_log("relation", lexemes, position)
 
-		attempt_log("simpleExpression", lexemes, position)
-		_simpleExpressionNode, err := simpleExpression(lexemes, position)
-		if err != nil {
-			return nil, err
+		if _relationToken.Kind == lexer.IS {
+			// expression IS qualident
+			var _typeNode ast.Expr
+			attempt_log("qualident", lexemes, position)
+			_qualidentNode := qualident(lexemes, position)
+			if _qualidentNode == nil {
+				did_not_match_log("qualident", lexemes, position)
+				_typeNode = missing("type after IS", "qualident", lexemes, position)
+			} else {
+				matched_log("qualident", lexemes, position)
+				_typeNode = _qualidentNode
+			}
+
+			return &ast.BinaryExpr{
+				X:     _simpleExpressionNode,
+				OpPos: _relationToken.Offset,
+				Op:    _relationToken.Kind,
+				Y:     _typeNode,
+			}
 		}
-		if _simpleExpressionNode == nil {
+
+		attempt_log("simpleExpression", lexemes, position)
+		_rightNode := simpleExpression(lexemes, position)
+		if _rightNode == nil {
 			did_not_match_log("simpleExpression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_rightNode = missing(fmt.Sprintf("expression after %q", _relationToken.Label), "expression", lexemes, position)
+		} else {
+			matched_log("simpleExpression", lexemes, position)
 		}
-		matched_log("simpleExpression", lexemes, position)
 
-		expressionNode.Children = append(expressionNode.Children, _relationNode)
-		expressionNode.Children = append(expressionNode.Children, _simpleExpressionNode)
+		return &ast.BinaryExpr{
+			X:     _simpleExpressionNode,
+			OpPos: _relationToken.Offset,
+			Op:    _relationToken.Kind,
+			Y:     _rightNode,
+		}
 	}
-	return expressionNode, nil
+	did_not_match_optionally_log("relation", lexemes, position)
+
+	return _simpleExpressionNode
 }
 
+// length = ConstExpression.
 func length(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
+) ast.Expr {
 	return constExpression(lexemes, position)
 }
 
+// type = qualident | StrucType.
 func _type(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var _typeNode = new(ParseNode)
-	_typeNode.Label = "type"
-
-	attempt_log("qualident", lexemes, position)
-	_qualidentNode, err := qualident(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _qualidentNode != nil {
+) ast.Type {
+	if peek(lexemes, *position) == lexer.IDENT {
+		attempt_log("qualident", lexemes, position)
+		_qualidentNode := qualident(lexemes, position)
 		matched_log("qualident", lexemes, position)
-		_typeNode.Children = append(_typeNode.Children, _qualidentNode)
-		return _typeNode, nil
+		return _qualidentNode
 	}
-	did_not_match_log("qualident", lexemes, position)
 
 	attempt_log("structype", lexemes, position)
-	_structypeNode, err := structype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _structypeNode != nil {
-		matched_log("structype", lexemes, position)
-		_typeNode.Children = append(_typeNode.Children, _structypeNode)
-		return _typeNode, nil
+	_structypeNode := structype(lexemes, position)
+	if _structypeNode == nil {
+		did_not_match_log("structype", lexemes, position)
+		return nil
 	}
-	did_not_match_log("structype", lexemes, position)
-
-	return nil, nil
+	matched_log("structype", lexemes, position)
+	return _structypeNode
 }
 
+// ArrayType = ARRAY [length {"," length}] OF type. Open array types,
+// without lengths, are Oberon-2 only.
 func arraytype(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var arraytypeNode = new(ParseNode)
-	arraytypeNode.Label = "arraytype"
-	var positionCheckpoint = *position
-	var positionCheckpoint1 = *position
+) *ast.ArrayType {
+	var arraytypeNode = new(ast.ArrayType)
 
 	attempt_log("ARRAY", lexemes, position)
-	_arrayReservedWord := matchReservedWord(lexemes, position, "ARRAY")
+	_arrayReservedWord := match(lexemes, position, lexer.ARRAY)
 	if _arrayReservedWord == nil {
 		did_not_match_log("ARRAY", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("ARRAY", lexemes, position)
+	arraytypeNode.Array = _arrayReservedWord.Offset
 
-	attempt_log("length", lexemes, position)
-	_lengthNode, err := length(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _lengthNode == nil {
-		did_not_match_log("length", lexemes, position)
-		return nil, nil
-	}
-	arraytypeNode.Children = append(arraytypeNode.Children, _arrayReservedWord)
-	arraytypeNode.Children = append(arraytypeNode.Children, _lengthNode)
-	matched_log("length", lexemes, position)
-
-	for {
-		attempt_optionally_log(",", lexemes, position)
-		_commaOperatorNode := matchOperator(lexemes, position, ",")
-		if err != nil {
-			return nil, err
-		}
-		if _commaOperatorNode == nil {
-			did_not_match_optionally_log(",", lexemes, position)
-			break
-		}
-		optionally_matched_log(",", lexemes, position)
+	if peek(lexemes, *position) == lexer.OF {
+		// ARRAY OF type
+		extension(span(lexemes, *position), lexer.OPEN_ARRAY_TYPES)
+	} else {
+		for {
+			attempt_log("length", lexemes, position)
+			_lengthNode := length(lexemes, position)
+			if _lengthNode == nil {
+				did_not_match_log("length", lexemes, position)
+				_lengthNode = missing("array length", "ConstExpression", lexemes, position)
+			} else {
+				matched_log("length", lexemes, position)
+			}
+			arraytypeNode.Lengths = append(arraytypeNode.Lengths, _lengthNode)
 
-		attempt_log("length", lexemes, position)
-		_lengthNode, err := length(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _lengthNode == nil {
-			did_not_match_log("length", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			attempt_optionally_log(",", lexemes, position)
+			_commaOperatorToken := match(lexemes, position, lexer.COMMA)
+			if _commaOperatorToken == nil {
+				did_not_match_optionally_log(",", lexemes, position)
+				break
+			}
+			optionally_matched_log(",", lexemes, position)
 		}
-		arraytypeNode.Children = append(arraytypeNode.Children, _commaOperatorNode)
-		arraytypeNode.Children = append(arraytypeNode.Children, _lengthNode)
-		matched_log("length", lexemes, position)
 	}
 
-	attempt_log("OF", lexemes, position)
-	_ofReservedWordNode := matchReservedWord(lexemes, position, "OF")
-	if _ofReservedWordNode == nil {
-		did_not_match_log("OF", lexemes, position)
-		*position = positionCheckpoint1
-		return nil, nil
-	}
-	matched_log("OF", lexemes, position)
+	expect(lexemes, position, lexer.OF, "after the array length")
 
-	matched_log("type", lexemes, position)
-	_typeNode, err := _type(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	attempt_log("type", lexemes, position)
+	_typeNode := _type(lexemes, position)
 	if _typeNode == nil {
 		did_not_match_log("type", lexemes, position)
-		*position = positionCheckpoint1
-		return nil, nil
+		_typeNode = missing("element type after OF", "type", lexemes, position)
+	} else {
+		matched_log("type", lexemes, position)
 	}
-	matched_log("type", lexemes, position)
+	arraytypeNode.Elem = _typeNode
 
-	arraytypeNode.Children = append(arraytypeNode.Children, _ofReservedWordNode)
-	arraytypeNode.Children = append(arraytypeNode.Children, _typeNode)
-
-	return arraytypeNode, nil
+	return arraytypeNode
 }
 
+// BaseType = qualident.
 func basetype(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
+) *ast.QualIdent {
 	return qualident(lexemes, position)
 }
 
+// IdentList = identdef {"," identdef}.
 func identList(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var identListNode = new(ParseNode)
-	identListNode.Label = "identList"
-	var positionCheckpoint = *position
+) []*ast.IdentDef {
+	var identDefs []*ast.IdentDef
 
 	attempt_log("identdef", lexemes, position)
-	_identDefNode, err := identdef(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_identDefNode := identdef(lexemes, position)
 	if _identDefNode == nil {
 		did_not_match_log("identdef", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	identListNode.Children = append(identListNode.Children, _identDefNode)
 	matched_log("identdef", lexemes, position)
+	identDefs = append(identDefs, _identDefNode)
 
 	for {
 		attempt_optionally_log(",", lexemes, position)
-		_commaOperatorNode := matchOperator(lexemes, position, ",")
-		if _commaOperatorNode == nil {
+		_commaOperatorToken := match(lexemes, position, lexer.COMMA)
+		if _commaOperatorToken == nil {
 			did_not_match_optionally_log(",", lexemes, position)
 			break
 		}
 		optionally_matched_log(",", lexemes, position)
 
 		attempt_log("identdef", lexemes, position)
-		_identDefNode, err := identdef(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_identDefNode := identdef(lexemes, position)
 		if _identDefNode == nil {
 			did_not_match_log("identdef", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			report_error(parse_error("identifier after \",\"", lexemes, position))
+			continue
 		}
-
-		identListNode.Children = append(identListNode.Children, _commaOperatorNode)
-		identListNode.Children = append(identListNode.Children, _identDefNode)
+		matched_log("identdef", lexemes, position)
+		identDefs = append(identDefs, _identDefNode)
 	}
-	return identListNode, nil
-
+	return identDefs
 }
 
+// FieldList = IdentList ":" type.
 func fieldList(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var fieldListNode = new(ParseNode)
-	fieldListNode.Label = "fieldList"
-	var positionCheckpoint = *position
+) *ast.FieldList {
+	var fieldListNode = new(ast.FieldList)
 
 	attempt_log("identList", lexemes, position)
-	_identListNode, err := identList(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _identListNode == nil {
+	_identDefs := identList(lexemes, position)
+	if _identDefs == nil {
 		did_not_match_log("identList", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("identList", lexemes, position)
+	fieldListNode.Names = _identDefs
 
-	attempt_log(":", lexemes, position)
-	_colonNode := matchOperator(lexemes, position, ":")
-	if _colonNode == nil {
-		did_not_match_log(":", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log(":", lexemes, position)
+	expect(lexemes, position, lexer.COLON, "after the field names")
 
 	attempt_log("type", lexemes, position)
-	_typeNode, err := _type(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_typeNode := _type(lexemes, position)
 	if _typeNode == nil {
 		did_not_match_log("type", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		_typeNode = missing("field type after \":\"", "type", lexemes, position)
+	} else {
+		matched_log("type", lexemes, position)
 	}
-	matched_log("type", lexemes, position)
-
-	fieldListNode.Children = append(fieldListNode.Children, _identListNode)
-	fieldListNode.Children = append(fieldListNode.Children, _colonNode)
-	fieldListNode.Children = append(fieldListNode.Children, _typeNode)
+	fieldListNode.Type = _typeNode
 
-	return fieldListNode, nil
+	return fieldListNode
 }
 
+// FieldListSequence = FieldList {";" FieldList}.
 func fieldListSequence(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var fieldListSequenceNode = new(ParseNode)
-	fieldListSequenceNode.Label = "fieldListSequence"
+) []*ast.FieldList {
+	var fieldLists []*ast.FieldList
 
-	attempt_log("fieldListSequence", lexemes, position)
-	_fieldListNode, err := fieldList(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	attempt_log("fieldList", lexemes, position)
+	_fieldListNode := fieldList(lexemes, position)
 	if _fieldListNode == nil {
-		attempt_log("fieldListSequence", lexemes, position)
-		return nil, nil
+		did_not_match_log("fieldList", lexemes, position)
+		return nil
 	}
-	fieldListSequenceNode.Children = append(fieldListSequenceNode.Children, _fieldListNode)
-	attempt_log("fieldListSequence", lexemes, position)
+	matched_log("fieldList", lexemes, position)
+	fieldLists = append(fieldLists, _fieldListNode)
 
 	for {
 		attempt_optionally_log(";", lexemes, position)
-		_semicolonNode := matchOperator(lexemes, position, ";")
-		if _semicolonNode == nil {
+		_semicolonToken := match(lexemes, position, lexer.SEMICOLON)
+		if _semicolonToken == nil {
 			did_not_match_optionally_log(";", lexemes, position)
 			break
 		}
-		attempt_optionally_log(";", lexemes, position)
+		optionally_matched_log(";", lexemes, position)
 
 		attempt_log("fieldList", lexemes, position)
-		_fieldListNode, err := fieldList(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_fieldListNode := fieldList(lexemes, position)
 		if _fieldListNode == nil {
 			did_not_match_log("fieldList", lexemes, position)
 			break
 		}
-		fieldListSequenceNode.Children = append(fieldListSequenceNode.Children, _semicolonNode)
-		fieldListSequenceNode.Children = append(fieldListSequenceNode.Children, _fieldListNode)
 		matched_log("fieldList", lexemes, position)
+		fieldLists = append(fieldLists, _fieldListNode)
 	}
-	return fieldListSequenceNode, nil
+	return fieldLists
 }
 
+// RecordType = [ABSTRACT | EXTENSIBLE | LIMITED] RECORD ["(" BaseType ")"]
+// [FieldListSequence] END. The record attributes are Component Pascal
+// only.
 func recordtype(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var recordtypeNode = new(ParseNode)
-	recordtypeNode.Label = "recordtype"
-	var positionCheckpoint = *position
+	lexemes *[]lexer.Token,
+	position *int,
+) *ast.RecordType {
+	var recordtypeNode = new(ast.RecordType)
+
+	attempt_optionally_log("record attribute", lexemes, position)
+	_attributeToken := matchAny(lexemes, position, lexer.ABSTRACT, lexer.EXTENSIBLE, lexer.LIMITED)
+	if _attributeToken != nil {
+		optionally_matched_log(_attributeToken.Kind.String(), lexemes, position)
+		extension(parserFile.Span(_attributeToken.Offset, _attributeToken.End), lexer.RECORD_ATTRIBUTES)
+		recordtypeNode.Attr = _attributeToken.Kind
+		recordtypeNode.AttrPos = _attributeToken.Offset
+	} else {
+		did_not_match_optionally_log("record attribute", lexemes, position)
+	}
 
 	attempt_log("RECORD", lexemes, position)
-	_recordReservedWordNode := matchReservedWord(lexemes, position, "RECORD")
-	if _recordReservedWordNode == nil {
+	_recordReservedWordToken := match(lexemes, position, lexer.RECORD)
+	if _recordReservedWordToken == nil {
 		did_not_match_log("RECORD", lexemes, position)
-		return nil, nil
+		if _attributeToken != nil {
+			report_error(parse_error(fmt.Sprintf("\"RECORD\" after %s", _attributeToken.Kind), lexemes, position))
+		}
+		return nil
 	}
 	matched_log("RECORD", lexemes, position)
+	recordtypeNode.Record = _recordReservedWordToken.Offset
 
 	attempt_optionally_log("(", lexemes, position)
-	_leftParenNode := matchOperator(lexemes, position, "(")
-	if _leftParenNode != nil {
-		matched_log("(", lexemes, position)
+	_leftParenToken := match(lexemes, position, lexer.LPAREN)
+	if _leftParenToken != nil {
+		optionally_matched_log("(", lexemes, position)
+
 		attempt_log("basetype", lexemes, position)
-		_basetypeNode, err := basetype(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_basetypeNode := basetype(lexemes, position)
 		if _basetypeNode == nil {
 			did_not_match_log("basetype", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("basetype", lexemes, position)
-
-		attempt_log(")", lexemes, position)
-		_rightParenNode := matchOperator(lexemes, position, ")")
-		if _rightParenNode == nil {
-			did_not_match_log(")", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			report_error(parse_error("base type after \"(\"", lexemes, position))
+		} else {
+			matched_log("basetype", lexemes, position)
+			recordtypeNode.Base = _basetypeNode
 		}
-		matched_log(")", lexemes, position)
 
-		recordtypeNode.Children = append(recordtypeNode.Children, _leftParenNode)
-		recordtypeNode.Children = append(recordtypeNode.Children, _basetypeNode)
-		recordtypeNode.Children = append(recordtypeNode.Children, _rightParenNode)
+		expect(lexemes, position, lexer.RPAREN, "to close the base type")
 	} else {
-		did_not_match_log("(", lexemes, position)
+		did_not_match_optionally_log("(", lexemes, position)
 	}
 
 	attempt_optionally_log("fieldListSequence", lexemes, position)
-	_fieldListSequenceNode, err := fieldListSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _fieldListSequenceNode != nil {
+	_fieldLists := fieldListSequence(lexemes, position)
+	if _fieldLists != nil {
 		optionally_matched_log("fieldListSequence", lexemes, position)
-		recordtypeNode.Children = append(recordtypeNode.Children, _fieldListSequenceNode)
+		recordtypeNode.Fields = _fieldLists
 	} else {
 		did_not_match_optionally_log("fieldListSequence", lexemes, position)
 	}
 
-	attempt_log("END", lexemes, position)
-	_endReservedWordNode := matchReservedWord(lexemes, position, "END")
-	if _endReservedWordNode == nil {
-		did_not_match_log("END", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("END", lexemes, position)
-	recordtypeNode.Children = append(recordtypeNode.Children, _endReservedWordNode)
+	recordtypeNode.EndPos = expect(lexemes, position, lexer.END, "to close the record")
 
-	return recordtypeNode, nil
+	return recordtypeNode
 }
 
+// PointerType = POINTER TO type.
 func pointertype(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var pointertypeNode = new(ParseNode)
-	pointertypeNode.Label = "pointertype"
-	var positionCheckpoint = *position
+) *ast.PointerType {
+	var pointertypeNode = new(ast.PointerType)
 
 	attempt_log("POINTER", lexemes, position)
-	_pointertypeNode := matchReservedWord(lexemes, position, "POINTER")
-	if _pointertypeNode == nil {
+	_pointerToken := match(lexemes, position, lexer.POINTER)
+	if _pointerToken == nil {
 		did_not_match_log("POINTER", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("POINTER", lexemes, position)
+	pointertypeNode.Pointer = _pointerToken.Offset
 
-	attempt_log("TO", lexemes, position)
-	_toNode := matchReservedWord(lexemes, position, "TO")
-	if _toNode == nil {
-		did_not_match_log("TO", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("TO", lexemes, position)
+	expect(lexemes, position, lexer.TO, "after POINTER")
 
 	attempt_log("type", lexemes, position)
-	_typeNode, err := _type(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_typeNode := _type(lexemes, position)
 	if _typeNode == nil {
 		did_not_match_log("type", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		_typeNode = missing("pointer base type after TO", "type", lexemes, position)
+	} else {
+		matched_log("type", lexemes, position)
 	}
-	matched_log("type", lexemes, position)
+	pointertypeNode.Base = _typeNode
 
-	pointertypeNode.Children = append(pointertypeNode.Children, _pointertypeNode)
-	pointertypeNode.Children = append(pointertypeNode.Children, _toNode)
-	pointertypeNode.Children = append(pointertypeNode.Children, _typeNode)
-
-	return pointertypeNode, nil
+	return pointertypeNode
 }
 
+// FormalType = {ARRAY OF} qualident. Open arrays are returned as an
+// ArrayType without lengths.
 func formaltype(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var formaltypeNode = new(ParseNode)
-	formaltypeNode.Label = "formaltype"
-	var positionCheckpoint = *position
-
+) ast.Type {
 	attempt_log("ARRAY", lexemes, position)
-	_arrayReservedNode := matchReservedWord(lexemes, position, "ARRAY")
-	if _arrayReservedNode != nil {
+	_arrayReservedToken := match(lexemes, position, lexer.ARRAY)
+	if _arrayReservedToken != nil {
 		matched_log("ARRAY", lexemes, position)
 
-		attempt_log("OF", lexemes, position)
-		_ofReservedNode := matchReservedWord(lexemes, position, "OF")
-		if _ofReservedNode == nil {
-			did_not_match_log("OF", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+		expect(lexemes, position, lexer.OF, "after ARRAY")
+
+		attempt_log("formaltype", lexemes, position)
+		_elemNode := formaltype(lexemes, position)
+		if _elemNode == nil {
+			did_not_match_log("formaltype", lexemes, position)
+			_elemNode = missing("parameter type", "qualident", lexemes, position)
+		} else {
+			matched_log("formaltype", lexemes, position)
 		}
-		matched_log("OF", lexemes, position)
-		formaltypeNode.Children = append(formaltypeNode.Children, _arrayReservedNode)
-		formaltypeNode.Children = append(formaltypeNode.Children, _ofReservedNode)
-	} else {
-		did_not_match_log("ARRAY", lexemes, position)
+
+		return &ast.ArrayType{Array: _arrayReservedToken.Offset, Elem: _elemNode}
 	}
+	did_not_match_log("ARRAY", lexemes, position)
 
 	attempt_log("qualident", lexemes, position)
-	_qualidentNode, err := qualident(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_qualidentNode := qualident(lexemes, position)
 	if _qualidentNode == nil {
 		did_not_match_log("qualident", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		return nil
 	}
-	formaltypeNode.Children = append(formaltypeNode.Children, _qualidentNode)
 	matched_log("qualident", lexemes, position)
 
-	return formaltypeNode, nil
+	return _qualidentNode
 }
 
+// FPSection = [VAR | IN | OUT] ident {"," ident} ":" FormalType. IN and
+// OUT are Component Pascal only.
 func fpSection(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var fpSectionNode = new(ParseNode)
-	fpSectionNode.Label = "fpSection"
-	var positionCheckpoint = *position
+) *ast.FPSection {
+	var fpSectionNode = &ast.FPSection{Var: ast.NO_POS}
 
-	attempt_log("VAR", lexemes, position)
-	_varNode := matchReservedWord(lexemes, position, "VAR")
-	if _varNode != nil {
-		matched_log("VAR", lexemes, position)
-		fpSectionNode.Children = append(fpSectionNode.Children, _varNode)
+	attempt_optionally_log("VAR", lexemes, position)
+	_varToken := matchAny(lexemes, position, lexer.VAR, lexer.IN, lexer.OUT)
+	if _varToken != nil {
+		optionally_matched_log(_varToken.Kind.String(), lexemes, position)
+		if _varToken.Kind != lexer.VAR {
+			extension(parserFile.Span(_varToken.Offset, _varToken.End), lexer.IN_AND_OUT_PARAMETERS)
+		}
+		fpSectionNode.Var = _varToken.Offset
+		fpSectionNode.Mode = _varToken.Kind
+		fpSectionNode.Names = append(fpSectionNode.Names, expectIdent(lexemes, position, "parameter name after "+_varToken.Label))
 	} else {
-		did_not_match_log("VAR", lexemes, position)
-	}
+		did_not_match_optionally_log("VAR", lexemes, position)
 
-	attempt_log("ident", lexemes, position)
-	_identNode := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
-		did_not_match_log("ident", lexemes, position)
-		return nil, nil
+		attempt_log("ident", lexemes, position)
+		_identToken := match(lexemes, position, lexer.IDENT)
+		if _identToken == nil {
+			did_not_match_log("ident", lexemes, position)
+			return nil
+		}
+		matched_log("ident", lexemes, position)
+		fpSectionNode.Names = append(fpSectionNode.Names, newIdent(_identToken))
 	}
-	matched_log("ident", lexemes, position)
-	fpSectionNode.Children = append(fpSectionNode.Children, _identNode)
 
 	for {
 		attempt_optionally_log(",", lexemes, position)
-		_commaNode := matchOperator(lexemes, position, ",")
-		if _commaNode == nil {
+		_commaToken := match(lexemes, position, lexer.COMMA)
+		if _commaToken == nil {
 			did_not_match_optionally_log(",", lexemes, position)
 			break
 		}
 		optionally_matched_log(",", lexemes, position)
-
-		attempt_log("ident", lexemes, position)
-		_identNode := matchtype(lexemes, position, lexer.IDENT)
-		if _identNode == nil {
-			did_not_match_log("ident", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("ident", lexemes, position)
-
-		fpSectionNode.Children = append(fpSectionNode.Children, _commaNode)
-		fpSectionNode.Children = append(fpSectionNode.Children, _identNode)
+		fpSectionNode.Names = append(fpSectionNode.Names, expectIdent(lexemes, position, "parameter name after \",\""))
 	}
 
-	attempt_log(":", lexemes, position)
-	_colonNode := matchOperator(lexemes, position, ":")
-	if _colonNode == nil {
-		did_not_match_log(":", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	fpSectionNode.Children = append(fpSectionNode.Children, _colonNode)
-	matched_log(":", lexemes, position)
+	expect(lexemes, position, lexer.COLON, "after the parameter names")
 
 	attempt_log("formaltype", lexemes, position)
-	_formaltypeNode, err := formaltype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_formaltypeNode := formaltype(lexemes, position)
 	if _formaltypeNode == nil {
 		did_not_match_log("formaltype", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		_formaltypeNode = missing("parameter type after \":\"", "FormalType", lexemes, position)
+	} else {
+		matched_log("formaltype", lexemes, position)
 	}
-	matched_log("formaltype", lexemes, position)
-	fpSectionNode.Children = append(fpSectionNode.Children, _formaltypeNode)
+	fpSectionNode.Type = _formaltypeNode
 
-	return fpSectionNode, nil
+	return fpSectionNode
 }
 
+// FormalParameters = "(" [FPSection {";" FPSection}] ")" [":" qualident].
 func formalParameters(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var formalParametersNode = new(ParseNode)
-	formalParametersNode.Label = "formalParameters"
-	var positionCheckpoint = *position
+) *ast.FormalParameters {
+	var formalParametersNode = new(ast.FormalParameters)
 
 	attempt_log("(", lexemes, position)
-	_leftParenNode := matchOperator(lexemes, position, "(")
-	if _leftParenNode == nil {
+	_leftParenToken := match(lexemes, position, lexer.LPAREN)
+	if _leftParenToken == nil {
 		did_not_match_log("(", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("(", lexemes, position)
-	formalParametersNode.Children = append(formalParametersNode.Children, _leftParenNode)
+	formalParametersNode.Lparen = _leftParenToken.Offset
 
-	attempt_optionally_log("fpSectionNode", lexemes, position)
-	_fpSectionNode, err := fpSection(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	attempt_optionally_log("fpSection", lexemes, position)
+	_fpSectionNode := fpSection(lexemes, position)
 	if _fpSectionNode != nil {
-		optionally_matched_log("fpSectionNode", lexemes, position)
-		formalParametersNode.Children = append(formalParametersNode.Children, _fpSectionNode)
+		optionally_matched_log("fpSection", lexemes, position)
+		formalParametersNode.Sections = append(formalParametersNode.Sections, _fpSectionNode)
 		for {
 			attempt_optionally_log(";", lexemes, position)
-			_semicolonNode := matchOperator(lexemes, position, ";")
-			if _semicolonNode == nil {
+			_semicolonToken := match(lexemes, position, lexer.SEMICOLON)
+			if _semicolonToken == nil {
 				did_not_match_optionally_log(";", lexemes, position)
 				break
 			}
 			optionally_matched_log(";", lexemes, position)
 
 			attempt_log("fpSection", lexemes, position)
-			_fpSectionNode, err := fpSection(lexemes, position)
-			if err != nil {
-				return nil, err
-			}
+			_fpSectionNode := fpSection(lexemes, position)
 			if _fpSectionNode == nil {
 				did_not_match_log("fpSection", lexemes, position)
-				*position = positionCheckpoint
-				return nil, nil
+				report_error(parse_error("parameter section after \";\"", lexemes, position))
+				break
 			}
 			matched_log("fpSection", lexemes, position)
-
-			formalParametersNode.Children = append(formalParametersNode.Children, _semicolonNode)
-			formalParametersNode.Children = append(formalParametersNode.Children, _fpSectionNode)
+			formalParametersNode.Sections = append(formalParametersNode.Sections, _fpSectionNode)
 		}
+	} else {
+		did_not_match_optionally_log("fpSection", lexemes, position)
 	}
 
-	attempt_log(")", lexemes, position)
-	_rightParenNode := matchOperator(lexemes, position, ")")
-	if _rightParenNode == nil {
-		did_not_match_log(")", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log(")", lexemes, position)
-	formalParametersNode.Children = append(formalParametersNode.Children, _rightParenNode)
+	formalParametersNode.Rparen = expect(lexemes, position, lexer.RPAREN, "to close the parameter list")
+
+	attempt_optionally_log(":", lexemes, position)
+	_colonToken := match(lexemes, position, lexer.COLON)
+	if _colonToken != nil {
+		optionally_matched_log(":", lexemes, position)
 
-	attempt_log(":", lexemes, position)
-	_colonNode := matchOperator(lexemes, position, ":")
-	if _colonNode != nil {
-		matched_log(":", lexemes, position)
 		attempt_log("qualident", lexemes, position)
-		_qualidentNode, err := qualident(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_qualidentNode := qualident(lexemes, position)
 		if _qualidentNode == nil {
 			did_not_match_log("qualident", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			report_error(parse_error("result type after \":\"", lexemes, position))
+		} else {
+			matched_log("qualident", lexemes, position)
+			formalParametersNode.Result = _qualidentNode
 		}
-		matched_log("qualident", lexemes, position)
-		formalParametersNode.Children = append(formalParametersNode.Children, _colonNode)
-		formalParametersNode.Children = append(formalParametersNode.Children, _qualidentNode)
 	} else {
-		did_not_match_log(":", lexemes, position)
+		did_not_match_optionally_log(":", lexemes, position)
 	}
-	return formalParametersNode, nil
+	return formalParametersNode
 }
 
+// ProcedureType = PROCEDURE [FormalParameters].
 func proceduretype(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var proceduretypeNode = new(ParseNode)
-	proceduretypeNode.Label = "proceduretype"
+) *ast.ProcedureType {
+	var proceduretypeNode = new(ast.ProcedureType)
 
 	attempt_log("PROCEDURE", lexemes, position)
-	_procedureNode := matchReservedWord(lexemes, position, "PROCEDURE")
-	if _procedureNode == nil {
+	_procedureToken := match(lexemes, position, lexer.PROCEDURE)
+	if _procedureToken == nil {
 		did_not_match_log("PROCEDURE", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	proceduretypeNode.Children = append(proceduretypeNode.Children, _procedureNode)
 	matched_log("PROCEDURE", lexemes, position)
+	proceduretypeNode.Procedure = _procedureToken.Offset
 
 	attempt_optionally_log("formalParameters", lexemes, position)
-	_formalParametersNode, err := formalParameters(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_formalParametersNode := formalParameters(lexemes, position)
 	if _formalParametersNode != nil {
-		matched_log("formalParameters", lexemes, position)
-		proceduretypeNode.Children = append(proceduretypeNode.Children, _formalParametersNode)
+		optionally_matched_log("formalParameters", lexemes, position)
+		proceduretypeNode.Params = _formalParametersNode
 	} else {
 		did_not_match_optionally_log("formalParameters", lexemes, position)
 	}
-	return proceduretypeNode, nil
+	return proceduretypeNode
 }
 
+// StrucType = ArrayType | RecordType | PointerType | ProcedureType.
 func structype(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var structypeNode = new(ParseNode)
-	structypeNode.Label = "structype"
-
-	attempt_log("arraytype", lexemes, position)
-	_arraytypeNode, err := arraytype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _arraytypeNode != nil {
-		matched_log("arraytype", lexemes, position)
-		structypeNode.Children = append(structypeNode.Children, _arraytypeNode)
-		return structypeNode, nil
-	}
-	did_not_match_log("arraytype", lexemes, position)
-
-	attempt_log("recordtype", lexemes, position)
-	_recordtypeNode, err := recordtype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _recordtypeNode != nil {
-		matched_log("recordtype", lexemes, position)
-		structypeNode.Children = append(structypeNode.Children, _recordtypeNode)
-		return structypeNode, nil
-	}
-	did_not_match_log("recordtype", lexemes, position)
-
-	attempt_log("pointertype", lexemes, position)
-	_pointertypeNode, err := pointertype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _pointertypeNode != nil {
-		matched_log("pointertype", lexemes, position)
-		structypeNode.Children = append(structypeNode.Children, _pointertypeNode)
-		return structypeNode, nil
-	}
-	did_not_match_log("pointertype", lexemes, position)
-
-	attempt_log("proceduretype", lexemes, position)
-	_proceduretypeNode, err := proceduretype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _proceduretypeNode != nil {
-		matched_log("proceduretype", lexemes, position)
-		structypeNode.Children = append(structypeNode.Children, _proceduretypeNode)
-		return structypeNode, nil
+	lexemes *[]lexer.Token,
+	position *int,
+) ast.Type {
+	switch peek(lexemes, *position) {
+	case lexer.ARRAY:
+		return arraytype(lexemes, position)
+	case lexer.ABSTRACT, lexer.EXTENSIBLE, lexer.LIMITED, lexer.RECORD:
+		// A nil *ast.RecordType must not be returned as a non-nil Type.
+		_recordtypeNode := recordtype(lexemes, position)
+		if _recordtypeNode == nil {
+			return nil
+		}
+		return _recordtypeNode
+	case lexer.POINTER:
+		return pointertype(lexemes, position)
+	case lexer.PROCEDURE:
+		return proceduretype(lexemes, position)
 	}
-	did_not_match_log("proceduretype", lexemes, position)
-
-	return nil, nil
+	return nil
 }
 
+// TypeDeclaration = identdef "=" StrucType.
 func typeDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var typeDeclarationNode = new(ParseNode)
-	typeDeclarationNode.Label = "typeDeclaration"
-	var positionCheckpoint = *position
+) *ast.TypeDecl {
+	var typeDeclarationNode = new(ast.TypeDecl)
 
 	attempt_log("identdef", lexemes, position)
-	_identDefNode, err := identdef(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_identDefNode := identdef(lexemes, position)
 	if _identDefNode == nil {
 		did_not_match_log("identdef", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("identdef", lexemes, position)
+	typeDeclarationNode.Name = _identDefNode
 
-	attempt_log("=", lexemes, position)
-	_equalOperatorNode := matchOperator(lexemes, position, "=")
-	if _equalOperatorNode == nil {
-		did_not_match_log("=", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("=", lexemes, position)
+	expect(lexemes, position, lexer.EQL, "after the type name")
 
 	attempt_log("structype", lexemes, position)
-	_structypeNode, err := structype(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_structypeNode := structype(lexemes, position)
 	if _structypeNode == nil {
 		did_not_match_log("structype", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		_structypeNode = missing("type after \"=\"", "StrucType", lexemes, position)
+	} else {
+		matched_log("structype", lexemes, position)
 	}
-	matched_log("structype", lexemes, position)
+	typeDeclarationNode.Type = _structypeNode
 
-	typeDeclarationNode.Children = append(typeDeclarationNode.Children, _identDefNode)
-	typeDeclarationNode.Children = append(typeDeclarationNode.Children, _equalOperatorNode)
-	typeDeclarationNode.Children = append(typeDeclarationNode.Children, _structypeNode)
-
-	return typeDeclarationNode, nil
+	return typeDeclarationNode
 }
 
+// identdef = ident ["*" | "-"]. The read-only mark "-" is Oberon-2 only.
 func identdef(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var identdefNode = new(ParseNode)
-	identdefNode.Label = "identdef"
+) *ast.IdentDef {
+	var identdefNode = &ast.IdentDef{Star: ast.NO_POS}
 
 	attempt_log("ident", lexemes, position)
-	_identNode := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
+	_identToken := match(lexemes, position, lexer.IDENT)
+	if _identToken == nil {
 		did_not_match_log("ident", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	identdefNode.Children = append(identdefNode.Children, _identNode)
 	matched_log("ident", lexemes, position)
+	identdefNode.Name = newIdent(_identToken)
 
-	attempt_log("*", lexemes, position)
-	_asterixNode := matchOperator(lexemes, position, "*")
-	if _asterixNode != nil {
-		did_not_match_log("*", lexemes, position)
-		identdefNode.Children = append(identdefNode.Children, _asterixNode)
+	attempt_optionally_log("*", lexemes, position)
+	_asteriskToken := matchAny(lexemes, position, lexer.TIMES, lexer.MINUS)
+	if _asteriskToken != nil {
+		optionally_matched_log(_asteriskToken.Kind.String(), lexemes, position)
+		identdefNode.Star = _asteriskToken.Offset
+		if _asteriskToken.Kind == lexer.MINUS {
+			extension(parserFile.Span(_asteriskToken.Offset, _asteriskToken.End), lexer.READ_ONLY_EXPORTS)
+			identdefNode.ReadOnly = true
+		}
+	} else {
+		did_not_match_optionally_log("*", lexemes, position)
 	}
-	matched_log("*", lexemes, position)
 
-	return identdefNode, nil
+	return identdefNode
 }
 
+// ConstExpression = expression.
 func constExpression(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
+) ast.Expr {
 	return expression(lexemes, position)
 }
 
+// assignment = designator ":=" expression. The designator, and the ":="
+// or the "=" written for it by mistake, have been parsed by statement.
 func assignment(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var assignmentNode = new(ParseNode)
-	assignmentNode.Label = "assignment"
-	var positionCheckpoint = *position
+	lhs *ast.Designator,
+	assign *lexer.Token,
+) *ast.AssignStmt {
+	var assignmentNode = &ast.AssignStmt{Lhs: lhs, Assign: assign.Offset}
 
-	attempt_log("designator", lexemes, position)
-	_designatorNode, err := designator(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _designatorNode == nil {
-		did_not_match_log("designator", lexemes, position)
-		return nil, nil
+	attempt_log("expression", lexemes, position)
+	_expressionNode := expression(lexemes, position)
+	if _expressionNode == nil {
+		did_not_match_log("expression", lexemes, position)
+		_expressionNode = missing("expression after \":=\"", "expression", lexemes, position)
+	} else {
+		matched_log("expression", lexemes, position)
 	}
-	matched_log("designator", lexemes, position)
+	assignmentNode.Rhs = _expressionNode
 
-	attempt_log(":=", lexemes, position)
-	_colonEqualOperatorNode := matchOperator(lexemes, position, ":=")
-	if _colonEqualOperatorNode == nil {
-		did_not_match_log(":=", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log(":=", lexemes, position)
-
-	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expressionNode == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
-
-	assignmentNode.Children = append(assignmentNode.Children, _designatorNode)
-	assignmentNode.Children = append(assignmentNode.Children, _colonEqualOperatorNode)
-	assignmentNode.Children = append(assignmentNode.Children, _expressionNode)
-
-	return assignmentNode, nil
+	return assignmentNode
 }
 
+// ProcedureCall = designator [ActualParameters]. The designator has been
+// parsed by statement. A call without a parameter list has NO_POS
+// parentheses.
 func procedureCall(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var procedureCallNode = new(ParseNode)
-	procedureCallNode.Label = "procedureCall"
-
-	attempt_log("designator", lexemes, position)
-	_designatorNode, err := designator(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _designatorNode == nil {
-		did_not_match_log("designator", lexemes, position)
-		return nil, nil
+	_designatorNode *ast.Designator,
+) *ast.CallStmt {
+	attempt_optionally_log("actualParameters", lexemes, position)
+	_callNode := actualParameters(lexemes, position)
+	if _callNode != nil {
+		optionally_matched_log("actualParameters", lexemes, position)
+	} else {
+		did_not_match_optionally_log("actualParameters", lexemes, position)
+		_callNode = &ast.CallExpr{Lparen: ast.NO_POS, Rparen: ast.NO_POS}
 	}
-	procedureCallNode.Children = append(procedureCallNode.Children, _designatorNode)
-	matched_log("designator", lexemes, position)
+	_callNode.Fun = _designatorNode
 
-	attempt_log("actualParameters", lexemes, position)
-	_actualParametersNode, err := actualParameters(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _actualParametersNode != nil {
-		did_not_match_log("actualParameters", lexemes, position)
-		procedureCallNode.Children = append(procedureCallNode.Children, _actualParametersNode)
-	}
-	matched_log("actualParameters", lexemes, position)
+	return &ast.CallStmt{Call: _callNode}
+}
 
-	return procedureCallNode, nil
+// requiredExpression parses an expression that must be present, such as
+// the condition after IF. A missing expression is reported followed by
+// context, as in "expected expression after IF".
+func requiredExpression(
+	lexemes *[]lexer.Token,
+	position *int,
+	context string,
+) ast.Expr {
+	attempt_log("expression", lexemes, position)
+	_expressionNode := expression(lexemes, position)
+	if _expressionNode == nil {
+		did_not_match_log("expression", lexemes, position)
+		return missing("expression "+context, "expression", lexemes, position)
+	}
+	matched_log("expression", lexemes, position)
+	return _expressionNode
 }
 
+// IfStatement = IF expression THEN StatementSequence
+// {ELSIF expression THEN StatementSequence}
+// [ELSE StatementSequence] END.
 func ifStatement(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var ifStatementNode = new(ParseNode)
-	ifStatementNode.Label = "ifStatement"
-	var positionCheckpoint = *position
+) *ast.IfStmt {
+	var ifStatementNode = new(ast.IfStmt)
 
 	attempt_log("IF", lexemes, position)
-	_ifReservedWordNode := matchReservedWord(lexemes, position, "IF")
-	if _ifReservedWordNode == nil {
+	_ifReservedWordToken := match(lexemes, position, lexer.IF)
+	if _ifReservedWordToken == nil {
 		did_not_match_log("IF", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("IF", lexemes, position)
+	ifStatementNode.If = _ifReservedWordToken.Offset
 
-	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		attempt_log("expression", lexemes, position)
-		return nil, err
-	}
-	if _expressionNode == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
+	_expressionNode := requiredExpression(lexemes, position, "after IF")
+	ifStatementNode.Cond = _expressionNode
 
-	attempt_log("THEN", lexemes, position)
-	_thenReservedWordNode := matchReservedWord(lexemes, position, "THEN")
-	if _thenReservedWordNode == nil {
-		did_not_match_log("THEN", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("THEN", lexemes, position)
+	expect(lexemes, position, lexer.THEN, "after the IF condition")
 
-	attempt_log("statementSequence", lexemes, position)
-	_statementSequenceNode, err := statementSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _statementSequenceNode == nil {
-		did_not_match_log("statementSequence", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	ifStatementNode.Children = append(ifStatementNode.Children, _ifReservedWordNode)
-	ifStatementNode.Children = append(ifStatementNode.Children, _expressionNode)
-	ifStatementNode.Children = append(ifStatementNode.Children, _thenReservedWordNode)
-	ifStatementNode.Children = append(ifStatementNode.Children, _statementSequenceNode)
-	matched_log("statementSequence", lexemes, position)
+	_statements := statements(lexemes, position, lexer.ELSIF, lexer.ELSE, lexer.END)
+	ifStatementNode.Body = _statements
 
 	for {
 		attempt_optionally_log("ELSIF", lexemes, position)
-		_elsifReservedWordNode := matchReservedWord(lexemes, position, "ELSIF")
-		if _elsifReservedWordNode == nil {
+		_elsifReservedWordToken := match(lexemes, position, lexer.ELSIF)
+		if _elsifReservedWordToken == nil {
 			did_not_match_optionally_log("ELSIF", lexemes, position)
 			break
 		}
 		optionally_matched_log("ELSIF", lexemes, position)
 
-		attempt_log("expression", lexemes, position)
-		_expressionNode, err := expression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _expressionNode == nil {
-			did_not_match_log("expression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("expression", lexemes, position)
+		_expressionNode := requiredExpression(lexemes, position, "after ELSIF")
 
-		attempt_log("THEN", lexemes, position)
-		_thenReservedWordNode := matchReservedWord(lexemes, position, "THEN")
-		if _thenReservedWordNode == nil {
-			did_not_match_optionally_log("THEN", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("THEN", lexemes, position)
+		expect(lexemes, position, lexer.THEN, "after the ELSIF condition")
 
-		attempt_log("statementSequence", lexemes, position)
-		_statementSequenceNode, err := statementSequence(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _statementSequenceNode == nil {
-			did_not_match_log("statementSequence", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("statementSequence", lexemes, position)
+		_statements := statements(lexemes, position, lexer.ELSIF, lexer.ELSE, lexer.END)
 
-		ifStatementNode.Children = append(ifStatementNode.Children, _elsifReservedWordNode)
-		ifStatementNode.Children = append(ifStatementNode.Children, _expressionNode)
-		ifStatementNode.Children = append(ifStatementNode.Children, _thenReservedWordNode)
-		ifStatementNode.Children = append(ifStatementNode.Children, _statementSequenceNode)
+		ifStatementNode.Elsifs = append(ifStatementNode.Elsifs, &ast.Elsif{
+			Elsif: _elsifReservedWordToken.Offset,
+			Cond:  _expressionNode,
+			Body:  _statements,
+		})
 	}
 
-	attempt_log("ELSE", lexemes, position)
-	_elseReservedWordNode := matchReservedWord(lexemes, position, "ELSE")
-	if _elseReservedWordNode != nil {
-		matched_log("ELSE", lexemes, position)
+	attempt_optionally_log("ELSE", lexemes, position)
+	_elseReservedWordToken := match(lexemes, position, lexer.ELSE)
+	if _elseReservedWordToken != nil {
+		optionally_matched_log("ELSE", lexemes, position)
 
-		attempt_log("statementSequence", lexemes, position)
-		_statementSequenceNode1, err := statementSequence(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _statementSequenceNode1 == nil {
-			did_not_match_log("statementSequence", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		ifStatementNode.Children = append(ifStatementNode.Children, _elseReservedWordNode)
-		ifStatementNode.Children = append(ifStatementNode.Children, _statementSequenceNode1)
-		matched_log("statementSequence", lexemes, position)
+		_statements := statements(lexemes, position, lexer.END)
+		ifStatementNode.Else = _statements
 	} else {
-		did_not_match_log("ELSE", lexemes, position)
+		did_not_match_optionally_log("ELSE", lexemes, position)
 	}
 
-	attempt_log("END", lexemes, position)
-	_endReservedWordNode := matchReservedWord(lexemes, position, "END")
-	if _endReservedWordNode == nil {
-		did_not_match_log("END", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	ifStatementNode.Children = append(ifStatementNode.Children, _endReservedWordNode)
-	matched_log("END", lexemes, position)
+	ifStatementNode.EndPos = expect(lexemes, position, lexer.END, "to close the IF statement")
 
-	return ifStatementNode, nil
+	return ifStatementNode
 }
 
+// label = integer | string | character | qualident.
 func label(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var labelNode = new(ParseNode)
-	labelNode.Label = "label"
-
-	attempt_log("INTEGER", lexemes, position)
-	_integerNode := matchtype(lexemes, position, lexer.INTEGER)
-	if _integerNode != nil {
-		matched_log("INTEGER", lexemes, position)
-		labelNode.Children = append(labelNode.Children, _integerNode)
-		return labelNode, nil
-	}
-	did_not_match_log("INTEGER", lexemes, position)
-
-	attempt_log("STRING", lexemes, position)
-	_stringNode := matchtype(lexemes, position, lexer.STRING)
-	if _stringNode != nil {
-		matched_log("STRING", lexemes, position)
-		labelNode.Children = append(labelNode.Children, _stringNode)
-		return labelNode, nil
-	}
-	did_not_match_log("STRING", lexemes, position)
-
-	attempt_log("qualident", lexemes, position)
-	_qualidentNode, err := qualident(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _qualidentNode != nil {
+	lexemes *[]lexer.Token,
+	position *int,
+) ast.Expr {
+	switch peek(lexemes, *position) {
+	case lexer.INTEGER, lexer.STRING, lexer.CHAR:
+		_literalToken := matchAny(lexemes, position, lexer.INTEGER, lexer.STRING, lexer.CHAR)
+		matched_log(_literalToken.Kind.String(), lexemes, position)
+		return newBasicLit(_literalToken)
+	case lexer.IDENT:
+		attempt_log("qualident", lexemes, position)
+		_qualidentNode := qualident(lexemes, position)
 		matched_log("qualident", lexemes, position)
-		labelNode.Children = append(labelNode.Children, _qualidentNode)
-		return labelNode, nil
+		return _qualidentNode
 	}
-	did_not_match_log("qualident", lexemes, position)
 
-	return nil, nil
+	return nil
 }
 
+// LabelRange = label [".." label].
 func labelRange(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var labelRangeNode = new(ParseNode)
-	labelRangeNode.Label = "labelRange"
-	var positionCheckpoint = *position
-
+) ast.Expr {
 	attempt_log("label", lexemes, position)
-	_labelNode, err := label(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_labelNode := label(lexemes, position)
 	if _labelNode == nil {
 		did_not_match_log("label", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	labelRangeNode.Children = append(labelRangeNode.Children, _labelNode)
 	matched_log("label", lexemes, position)
 
 	attempt_optionally_log("..", lexemes, position)
-	_doubleDotOperatorNode := matchOperator(lexemes, position, "..")
-	if _doubleDotOperatorNode != nil {
+	_doubleDotOperatorToken := match(lexemes, position, lexer.UPTO)
+	if _doubleDotOperatorToken != nil {
 		optionally_matched_log("..", lexemes, position)
 
 		attempt_log("label", lexemes, position)
-		_labelNode, err := label(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _labelNode == nil {
+		_highNode := label(lexemes, position)
+		if _highNode == nil {
 			did_not_match_log("label", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_highNode = missing("label after \"..\"", "label", lexemes, position)
+		} else {
+			matched_log("label", lexemes, position)
 		}
-		matched_log("label", lexemes, position)
 
-		labelRangeNode.Children = append(labelRangeNode.Children, _doubleDotOperatorNode)
-		labelRangeNode.Children = append(labelRangeNode.Children, _labelNode)
-	} else {
-		did_not_match_optionally_log("..", lexemes, position)
+		return &ast.RangeExpr{Low: _labelNode, High: _highNode}
 	}
+	did_not_match_optionally_log("..", lexemes, position)
 
-	return labelRangeNode, nil
+	return _labelNode
 }
 
+// CaseLabelList = LabelRange {"," LabelRange}.
 func caseLabelList(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var caseLabelListNode = new(ParseNode)
-	caseLabelListNode.Label = "caseLabelList"
-	var positionCheckpoint = *position
+) []ast.Expr {
+	var labels []ast.Expr
 
 	attempt_log("labelRange", lexemes, position)
-	_labelRangeNode, err := labelRange(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_labelRangeNode := labelRange(lexemes, position)
 	if _labelRangeNode == nil {
 		did_not_match_log("labelRange", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	caseLabelListNode.Children = append(caseLabelListNode.Children, _labelRangeNode)
 	matched_log("labelRange", lexemes, position)
+	labels = append(labels, _labelRangeNode)
 
 	for {
 		attempt_optionally_log(",", lexemes, position)
-		_commaOperatorNode := matchOperator(lexemes, position, ",")
-		if _commaOperatorNode == nil {
+		_commaOperatorToken := match(lexemes, position, lexer.COMMA)
+		if _commaOperatorToken == nil {
 			did_not_match_optionally_log(",", lexemes, position)
 			break
 		}
 		optionally_matched_log(",", lexemes, position)
 
 		attempt_log("labelRange", lexemes, position)
-		_labelRangeNode, err := labelRange(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_labelRangeNode := labelRange(lexemes, position)
 		if _labelRangeNode == nil {
 			did_not_match_log("labelRange", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_labelRangeNode = missing("label after \",\"", "label", lexemes, position)
+		} else {
+			matched_log("labelRange", lexemes, position)
 		}
-		caseLabelListNode.Children = append(caseLabelListNode.Children, _commaOperatorNode)
-		caseLabelListNode.Children = append(caseLabelListNode.Children, _labelRangeNode)
-		matched_log("labelRange", lexemes, position)
+		labels = append(labels, _labelRangeNode)
 	}
 
-	return caseLabelListNode, nil
+	return labels
 }
 
+// case = [CaseLabelList ":" StatementSequence]. An empty case is returned
+// as a clause without labels.
 func _case(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var _caseNode = new(ParseNode)
-	_caseNode.Label = "case"
+) *ast.CaseClause {
+	var _caseNode = new(ast.CaseClause)
 
 	attempt_log("caseLabelList", lexemes, position)
-	_caseLabelListNode, err := caseLabelList(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _caseLabelListNode == nil {
+	_labels := caseLabelList(lexemes, position)
+	if _labels == nil {
 		did_not_match_log("caseLabelList", lexemes, position)
-		return _caseNode, nil
+		return _caseNode
 	}
 	matched_log("caseLabelList", lexemes, position)
+	_caseNode.Labels = _labels
 
-	attempt_log(":", lexemes, position)
-	_colonOperatorNode := matchOperator(lexemes, position, ":")
-	if _colonOperatorNode == nil {
-		did_not_match_log(":", lexemes, position)
-		return nil, nil
-	}
-	matched_log(":", lexemes, position)
+	_caseNode.Colon = expect(lexemes, position, lexer.COLON, "after the case labels")
 
-	attempt_log("statementSequence", lexemes, position)
-	_statementSequenceNode, err := statementSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _statementSequenceNode == nil {
-		did_not_match_log("statementSequence", lexemes, position)
-		return nil, nil
-	}
-	matched_log("statementSequence", lexemes, position)
+	_statements := statements(lexemes, position, lexer.BAR, lexer.ELSE, lexer.END)
+	_caseNode.Body = _statements
 
-	_caseNode.Children = append(_caseNode.Children, _caseLabelListNode)
-	_caseNode.Children = append(_caseNode.Children, _colonOperatorNode)
-	_caseNode.Children = append(_caseNode.Children, _statementSequenceNode)
-
-	return _caseNode, nil
+	return _caseNode
 }
 
+// CaseStatement = CASE expression OF case {"|" case} [ELSE
+// StatementSequence] END.
 func caseStatement(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var caseStatementNode = new(ParseNode)
-	caseStatementNode.Label = "caseStatement"
-	var positionCheckpoint = *position
+) *ast.CaseStmt {
+	var caseStatementNode = new(ast.CaseStmt)
 
 	attempt_log("CASE", lexemes, position)
-	_caseReservedWordNode := matchReservedWord(lexemes, position, "CASE")
-	if _caseReservedWordNode == nil {
+	_caseReservedWordToken := match(lexemes, position, lexer.CASE)
+	if _caseReservedWordToken == nil {
 		did_not_match_log("CASE", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("CASE", lexemes, position)
+	caseStatementNode.Case = _caseReservedWordToken.Offset
 
-	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expressionNode == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
-
-	attempt_log("OF", lexemes, position)
-	_ofReservedWordNode := matchReservedWord(lexemes, position, "OF")
-	if _ofReservedWordNode == nil {
-		did_not_match_log("OF", lexemes, position)
-		return nil, nil
-	}
-
-	attempt_log("case", lexemes, position)
-	_caseNode, err := _case(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _caseNode == nil {
-		did_not_match_log("case", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("case", lexemes, position)
+	_expressionNode := requiredExpression(lexemes, position, "after CASE")
+	caseStatementNode.X = _expressionNode
 
-	caseStatementNode.Children = append(caseStatementNode.Children, _caseReservedWordNode)
-	caseStatementNode.Children = append(caseStatementNode.Children, _expressionNode)
-	caseStatementNode.Children = append(caseStatementNode.Children, _ofReservedWordNode)
-	caseStatementNode.Children = append(caseStatementNode.Children, _caseNode)
+	expect(lexemes, position, lexer.OF, "after the CASE expression")
 
 	for {
+		attempt_log("case", lexemes, position)
+		_caseNode := _case(lexemes, position)
+		matched_log("case", lexemes, position)
+		if len(_caseNode.Labels) > 0 {
+			caseStatementNode.Clauses = append(caseStatementNode.Clauses, _caseNode)
+		}
+
 		attempt_optionally_log("|", lexemes, position)
-		_verticalBarReservedWordNode := matchOperator(lexemes, position, "|")
-		if _verticalBarReservedWordNode == nil {
+		_verticalBarToken := match(lexemes, position, lexer.BAR)
+		if _verticalBarToken == nil {
 			did_not_match_optionally_log("|", lexemes, position)
 			break
 		}
-		attempt_log("|", lexemes, position)
-
-		matched_log("case", lexemes, position)
-		_caseNode, err := _case(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _caseNode == nil {
-			did_not_match_log("case", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("case", lexemes, position)
-
-		caseStatementNode.Children = append(caseStatementNode.Children, _verticalBarReservedWordNode)
-		caseStatementNode.Children = append(caseStatementNode.Children, _caseNode)
+		optionally_matched_log("|", lexemes, position)
 	}
 
-	matched_log("END", lexemes, position)
-	_endReservedWordNode := matchReservedWord(lexemes, position, "END")
-	if _endReservedWordNode == nil {
-		did_not_match_log("END", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+	attempt_optionally_log("ELSE", lexemes, position)
+	_elseReservedWordToken := match(lexemes, position, lexer.ELSE)
+	if _elseReservedWordToken != nil {
+		optionally_matched_log("ELSE", lexemes, position)
+		extension(parserFile.Span(_elseReservedWordToken.Offset, _elseReservedWordToken.End), lexer.CASE_ELSE)
+
+		_statements := statements(lexemes, position, lexer.END)
+		caseStatementNode.Else = _statements
+	} else {
+		did_not_match_optionally_log("ELSE", lexemes, position)
 	}
-	caseStatementNode.Children = append(caseStatementNode.Children, _endReservedWordNode)
-	matched_log("END", lexemes, position)
 
-	return caseStatementNode, nil
+	caseStatementNode.EndPos = expect(lexemes, position, lexer.END, "to close the CASE statement")
 
+	return caseStatementNode
 }
 
+// RepeatStatement = REPEAT StatementSequence UNTIL expression.
 func repeatStatement(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var repeatStatementNode = new(ParseNode)
-	repeatStatementNode.Label = "repeatStatement"
-	var positionCheckpoint = *position
+) *ast.RepeatStmt {
+	var repeatStatementNode = new(ast.RepeatStmt)
 
 	attempt_log("REPEAT", lexemes, position)
-	_repeatReservedWordNode := matchReservedWord(lexemes, position, "REPEAT")
-	if _repeatReservedWordNode == nil {
+	_repeatReservedWordToken := match(lexemes, position, lexer.REPEAT)
+	if _repeatReservedWordToken == nil {
 		did_not_match_log("REPEAT", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("REPEAT", lexemes, position)
+	repeatStatementNode.Repeat = _repeatReservedWordToken.Offset
 
-	attempt_log("statementSequence", lexemes, position)
-	_statementSequenceNode, err := statementSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _statementSequenceNode == nil {
-		did_not_match_log("statementSequence", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("statementSequence", lexemes, position)
-
-	attempt_log("UNTIL", lexemes, position)
-	_untilReservedWordNode := matchReservedWord(lexemes, position, "UNTIL")
-	if _untilReservedWordNode == nil {
-		did_not_match_log("UNTIL", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("UNTIL", lexemes, position)
+	_statements := statements(lexemes, position, lexer.UNTIL)
+	repeatStatementNode.Body = _statements
 
-	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expressionNode == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
+	repeatStatementNode.Until = expect(lexemes, position, lexer.UNTIL, "to close the REPEAT statement")
 
-	repeatStatementNode.Children = append(repeatStatementNode.Children, _repeatReservedWordNode)
-	repeatStatementNode.Children = append(repeatStatementNode.Children, _statementSequenceNode)
-	repeatStatementNode.Children = append(repeatStatementNode.Children, _untilReservedWordNode)
-	repeatStatementNode.Children = append(repeatStatementNode.Children, _expressionNode)
+	_expressionNode := requiredExpression(lexemes, position, "after UNTIL")
+	repeatStatementNode.Cond = _expressionNode
 
-	return repeatStatementNode, nil
+	return repeatStatementNode
 }
 
+// ForStatement = FOR ident ":=" expression TO expression [BY ConstExpression]
+// DO StatementSequence END.
 func forStatement(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var forStatementNode = new(ParseNode)
-	forStatementNode.Label = "forStatement"
-	var positionCheckpoint = *position
+) *ast.ForStmt {
+	var forStatementNode = new(ast.ForStmt)
 
 	attempt_log("FOR", lexemes, position)
-	_forReservedWordNode := matchReservedWord(lexemes, position, "FOR")
-	if _forReservedWordNode == nil {
+	_forReservedWordToken := match(lexemes, position, lexer.FOR)
+	if _forReservedWordToken == nil {
 		did_not_match_log("FOR", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("FOR", lexemes, position)
+	forStatementNode.For = _forReservedWordToken.Offset
 
-	attempt_log("ident", lexemes, position)
-	_identNode := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
-		did_not_match_log("ident", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("ident", lexemes, position)
+	forStatementNode.Var = expectIdent(lexemes, position, "control variable after FOR")
 
-	attempt_log(":=", lexemes, position)
-	_colonEqualOperatorNode := matchOperator(lexemes, position, ":=")
-	if _colonEqualOperatorNode == nil {
-		did_not_match_log(":=", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log(":=", lexemes, position)
+	expect(lexemes, position, lexer.BECOMES, "after the control variable")
 
-	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expressionNode == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
+	_lowNode := requiredExpression(lexemes, position, "after \":=\"")
+	forStatementNode.Low = _lowNode
 
-	attempt_log("TO", lexemes, position)
-	_toReservedWordNode := matchReservedWord(lexemes, position, "TO")
-	if _toReservedWordNode == nil {
-		did_not_match_log("TO", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("TO", lexemes, position)
+	expect(lexemes, position, lexer.TO, "after the initial value")
 
-	attempt_log("expression", lexemes, position)
-	_expressionNode1, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expressionNode1 == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
-
-	forStatementNode.Children = append(forStatementNode.Children, _forReservedWordNode)
-	forStatementNode.Children = append(forStatementNode.Children, _identNode)
-	forStatementNode.Children = append(forStatementNode.Children, _colonEqualOperatorNode)
-	forStatementNode.Children = append(forStatementNode.Children, _expressionNode)
-	forStatementNode.Children = append(forStatementNode.Children, _toReservedWordNode)
-	forStatementNode.Children = append(forStatementNode.Children, _expressionNode1)
+	_highNode := requiredExpression(lexemes, position, "after TO")
+	forStatementNode.High = _highNode
 
 	attempt_optionally_log("BY", lexemes, position)
-	_byReservedWordNode := matchReservedWord(lexemes, position, "BY")
-	if _byReservedWordNode != nil {
+	_byReservedWordToken := match(lexemes, position, lexer.BY)
+	if _byReservedWordToken != nil {
 		optionally_matched_log("BY", lexemes, position)
 
 		attempt_log("constExpression", lexemes, position)
-		_constExpressionNode, err := constExpression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_constExpressionNode := constExpression(lexemes, position)
 		if _constExpressionNode == nil {
 			did_not_match_log("constExpression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+			_constExpressionNode = missing("step after BY", "ConstExpression", lexemes, position)
+		} else {
+			matched_log("constExpression", lexemes, position)
 		}
-		forStatementNode.Children = append(forStatementNode.Children, _byReservedWordNode)
-		forStatementNode.Children = append(forStatementNode.Children, _constExpressionNode)
-		matched_log("constExpression", lexemes, position)
+		forStatementNode.By = _constExpressionNode
 	} else {
 		did_not_match_optionally_log("BY", lexemes, position)
 	}
 
-	attempt_log("DO", lexemes, position)
-	_doReservedWordNode := matchReservedWord(lexemes, position, "DO")
-	if _doReservedWordNode == nil {
-		did_not_match_log("DO", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("DO", lexemes, position)
+	expect(lexemes, position, lexer.DO, "after the FOR range")
 
-	attempt_log("statementSequence", lexemes, position)
-	_statementSequenceNode, err := statementSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _statementSequenceNode == nil {
-		did_not_match_log("statementSequence", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("statementSequence", lexemes, position)
-
-	attempt_log("END", lexemes, position)
-	_endReservedWordNode := matchReservedWord(lexemes, position, "END")
-	if _endReservedWordNode == nil {
-		did_not_match_log("END", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("END", lexemes, position)
+	_statements := statements(lexemes, position, lexer.END)
+	forStatementNode.Body = _statements
 
-	forStatementNode.Children = append(forStatementNode.Children, _doReservedWordNode)
-	forStatementNode.Children = append(forStatementNode.Children, _statementSequenceNode)
-	forStatementNode.Children = append(forStatementNode.Children, _endReservedWordNode)
+	forStatementNode.EndPos = expect(lexemes, position, lexer.END, "to close the FOR statement")
 
-	return forStatementNode, nil
+	return forStatementNode
 }
 
+// WhileStatement = WHILE expression DO StatementSequence
+// {ELSIF expression DO StatementSequence} END.
 func whileStatement(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var whileStatementNode = new(ParseNode)
-	whileStatementNode.Label = "whileStatement"
-	var positionCheckpoint = *position
+) *ast.WhileStmt {
+	var whileStatementNode = new(ast.WhileStmt)
 
 	attempt_log("WHILE", lexemes, position)
-	_whileReservedWordNode := matchReservedWord(lexemes, position, "WHILE")
-	if _whileReservedWordNode == nil {
+	_whileReservedWordToken := match(lexemes, position, lexer.WHILE)
+	if _whileReservedWordToken == nil {
 		did_not_match_log("WHILE", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("WHILE", lexemes, position)
+	whileStatementNode.While = _whileReservedWordToken.Offset
 
-	attempt_log("expression", lexemes, position)
-	_expressionNode, err := expression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _expressionNode == nil {
-		did_not_match_log("expression", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("expression", lexemes, position)
+	_expressionNode := requiredExpression(lexemes, position, "after WHILE")
+	whileStatementNode.Cond = _expressionNode
 
-	attempt_log("DO", lexemes, position)
-	_doReservedWordNode := matchReservedWord(lexemes, position, "DO")
-	if _doReservedWordNode == nil {
-		did_not_match_log("DO", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("DO", lexemes, position)
+	expect(lexemes, position, lexer.DO, "after the WHILE condition")
 
-	attempt_log("statementSequence", lexemes, position)
-	_statementSequenceNode, err := statementSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _statementSequenceNode == nil {
-		did_not_match_log("statementSequence", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	whileStatementNode.Children = append(whileStatementNode.Children, _whileReservedWordNode)
-	whileStatementNode.Children = append(whileStatementNode.Children, _expressionNode)
-	whileStatementNode.Children = append(whileStatementNode.Children, _doReservedWordNode)
-	whileStatementNode.Children = append(whileStatementNode.Children, _statementSequenceNode)
-	matched_log("statementSequence", lexemes, position)
+	_statements := statements(lexemes, position, lexer.ELSIF, lexer.END)
+	whileStatementNode.Body = _statements
 
 	for {
-		attempt_log("ELSIF", lexemes, position)
-		_elsifReservedWordNode := matchReservedWord(lexemes, position, "ELSIF")
-		if _elsifReservedWordNode == nil {
-			did_not_match_log("ELSIF", lexemes, position)
+		attempt_optionally_log("ELSIF", lexemes, position)
+		_elsifReservedWordToken := match(lexemes, position, lexer.ELSIF)
+		if _elsifReservedWordToken == nil {
+			did_not_match_optionally_log("ELSIF", lexemes, position)
 			break
 		}
-		matched_log("ELSIF", lexemes, position)
+		optionally_matched_log("ELSIF", lexemes, position)
 
-		attempt_log("expression", lexemes, position)
-		_expressionNode, err := expression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _expressionNode == nil {
-			did_not_match_log("expression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("expression", lexemes, position)
+		_expressionNode := requiredExpression(lexemes, position, "after ELSIF")
 
-		attempt_log("DO", lexemes, position)
-		_doReservedWordNode := matchReservedWord(lexemes, position, "DO")
-		if _doReservedWordNode == nil {
-			did_not_match_log("DO", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("DO", lexemes, position)
+		expect(lexemes, position, lexer.DO, "after the ELSIF condition")
 
-		attempt_log("statementSequence", lexemes, position)
-		_statementSequenceNode, err := statementSequence(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _statementSequenceNode == nil {
-			did_not_match_log("statementSequence", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("statementSequence", lexemes, position)
+		_statements := statements(lexemes, position, lexer.ELSIF, lexer.END)
 
-		whileStatementNode.Children = append(whileStatementNode.Children, _elsifReservedWordNode)
-		whileStatementNode.Children = append(whileStatementNode.Children, _expressionNode)
-		whileStatementNode.Children = append(whileStatementNode.Children, _doReservedWordNode)
-		whileStatementNode.Children = append(whileStatementNode.Children, _statementSequenceNode)
+		whileStatementNode.Elsifs = append(whileStatementNode.Elsifs, &ast.Elsif{
+			Elsif: _elsifReservedWordToken.Offset,
+			Cond:  _expressionNode,
+			Body:  _statements,
+		})
 	}
 
-	attempt_log("END", lexemes, position)
-	_endReservedWordNode := matchReservedWord(lexemes, position, "END")
-	if _endReservedWordNode == nil {
-		attempt_log("END", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	whileStatementNode.Children = append(whileStatementNode.Children, _endReservedWordNode)
-	attempt_log("END", lexemes, position)
+	whileStatementNode.EndPos = expect(lexemes, position, lexer.END, "to close the WHILE statement")
 
-	return whileStatementNode, nil
+	return whileStatementNode
 }
 
-func statement(
-	lexemes *[]lexer.Lexeme,
+// guard = qualident ":" qualident. It starts a clause of a WITH statement;
+// the clause goes on with DO StatementSequence.
+func withClause(
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var statementNode = new(ParseNode)
-	statementNode.Label = "statement"
+	context string,
+) *ast.WithClause {
+	var withClauseNode = new(ast.WithClause)
 
-	attempt_log("assignment", lexemes, position)
-	_assignmentNode, err := assignment(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _assignmentNode != nil {
-		matched_log("assignment", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _assignmentNode)
-		return statementNode, nil
+	attempt_log("qualident", lexemes, position)
+	_variableNode := qualident(lexemes, position)
+	if _variableNode == nil {
+		did_not_match_log("qualident", lexemes, position)
+		withClauseNode.Var = missing("variable "+context, "qualident", lexemes, position)
+	} else {
+		matched_log("qualident", lexemes, position)
+		withClauseNode.Var = _variableNode
 	}
-	did_not_match_log("assignment", lexemes, position)
 
-	attempt_log("procedureCall", lexemes, position)
-	_procedureCallNode, err := procedureCall(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _procedureCallNode != nil {
-		matched_log("procedureCall", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _procedureCallNode)
-		return statementNode, nil
-	}
-	did_not_match_log("procedureCall", lexemes, position)
+	withClauseNode.Colon = expect(lexemes, position, lexer.COLON, "after the guarded variable")
 
-	attempt_log("ifStatement", lexemes, position)
-	_ifStatementNode, err := ifStatement(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _ifStatementNode != nil {
-		matched_log("ifStatement", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _ifStatementNode)
-		return statementNode, nil
+	attempt_log("qualident", lexemes, position)
+	_typeNode := qualident(lexemes, position)
+	if _typeNode == nil {
+		did_not_match_log("qualident", lexemes, position)
+		withClauseNode.Type = missing("type after \":\"", "qualident", lexemes, position)
+	} else {
+		matched_log("qualident", lexemes, position)
+		withClauseNode.Type = _typeNode
 	}
-	did_not_match_log("ifStatement", lexemes, position)
 
-	attempt_log("caseStatement", lexemes, position)
-	_caseStatementNode, err := caseStatement(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _caseStatementNode != nil {
-		matched_log("caseStatement", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _caseStatementNode)
-		return statementNode, nil
-	}
-	did_not_match_log("caseStatement", lexemes, position)
+	expect(lexemes, position, lexer.DO, "after the guard")
 
-	attempt_log("whileStatement", lexemes, position)
-	_whiteStatementNode, err := whileStatement(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _whiteStatementNode != nil {
-		matched_log("whileStatement", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _whiteStatementNode)
-		return statementNode, nil
+	_statements := statements(lexemes, position, lexer.BAR, lexer.ELSE, lexer.END)
+	withClauseNode.Body = _statements
+
+	return withClauseNode
+}
+
+// WithStatement = WITH guard DO StatementSequence {"|" guard DO
+// StatementSequence} [ELSE StatementSequence] END.
+func withStatement(
+	lexemes *[]lexer.Token,
+	position *int,
+) *ast.WithStmt {
+	var withStatementNode = new(ast.WithStmt)
+
+	attempt_log("WITH", lexemes, position)
+	_withReservedWordToken := match(lexemes, position, lexer.WITH)
+	if _withReservedWordToken == nil {
+		did_not_match_log("WITH", lexemes, position)
+		return nil
 	}
-	did_not_match_log("whileStatement", lexemes, position)
+	matched_log("WITH", lexemes, position)
+	withStatementNode.With = _withReservedWordToken.Offset
 
-	attempt_log("repeatStatement", lexemes, position)
-	_repeatStatementNode, err := repeatStatement(lexemes, position)
-	if err != nil {
-		return nil, err
+	var context = "after WITH"
+	for {
+		attempt_log("withClause", lexemes, position)
+		_withClauseNode := withClause(lexemes, position, context)
+		matched_log("withClause", lexemes, position)
+		withStatementNode.Clauses = append(withStatementNode.Clauses, _withClauseNode)
+
+		attempt_optionally_log("|", lexemes, position)
+		_verticalBarToken := match(lexemes, position, lexer.BAR)
+		if _verticalBarToken == nil {
+			did_not_match_optionally_log("|", lexemes, position)
+			break
+		}
+		optionally_matched_log("|", lexemes, position)
+		context = "after \"|\""
 	}
-	if _repeatStatementNode != nil {
-		matched_log("repeatStatement", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _repeatStatementNode)
-		return statementNode, nil
+
+	attempt_optionally_log("ELSE", lexemes, position)
+	_elseReservedWordToken := match(lexemes, position, lexer.ELSE)
+	if _elseReservedWordToken != nil {
+		optionally_matched_log("ELSE", lexemes, position)
+
+		_statements := statements(lexemes, position, lexer.END)
+		withStatementNode.Else = _statements
+	} else {
+		did_not_match_optionally_log("ELSE", lexemes, position)
 	}
-	did_not_match_log("repeatStatement", lexemes, position)
 
-	attempt_log("forStatement", lexemes, position)
-	_forStatementNode, err := forStatement(lexemes, position)
-	if err != nil {
-		return nil, err
+	withStatementNode.EndPos = expect(lexemes, position, lexer.END, "to close the WITH statement")
+
+	return withStatementNode
+}
+
+// ReturnStatement = RETURN [expression].
+func returnStatement(
+	lexemes *[]lexer.Token,
+	position *int,
+) *ast.ReturnStmt {
+	var returnStatementNode = new(ast.ReturnStmt)
+
+	attempt_log("RETURN", lexemes, position)
+	_returnReservedWordToken := match(lexemes, position, lexer.RETURN)
+	if _returnReservedWordToken == nil {
+		did_not_match_log("RETURN", lexemes, position)
+		return nil
 	}
-	if _forStatementNode != nil {
-		matched_log("forStatement", lexemes, position)
-		statementNode.Children = append(statementNode.Children, _forStatementNode)
-		return statementNode, nil
+	matched_log("RETURN", lexemes, position)
+	returnStatementNode.Return = _returnReservedWordToken.Offset
+
+	if !startsWith(lexemes, *position, "expression") {
+		did_not_match_optionally_log("expression", lexemes, position)
+		return returnStatementNode
 	}
-	did_not_match_log("forStatement", lexemes, position)
+	attempt_optionally_log("expression", lexemes, position)
+	_expressionNode := expression(lexemes, position)
+	optionally_matched_log("expression", lexemes, position)
+	returnStatementNode.X = _expressionNode
 
-	return statementNode, nil
+	return returnStatementNode
 }
 
-func statementSequence(
-	lexemes *[]lexer.Lexeme,
+// LoopStatement = LOOP StatementSequence END.
+func loopStatement(
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var statementSequenceNode = new(ParseNode)
-	statementSequenceNode.Label = "statementSequence"
+) *ast.LoopStmt {
+	var loopStatementNode = new(ast.LoopStmt)
 
-	attempt_log("statement", lexemes, position)
-	_statementNode, err := statement(lexemes, position)
-	if err != nil {
-		return nil, err
+	attempt_log("LOOP", lexemes, position)
+	_loopReservedWordToken := match(lexemes, position, lexer.LOOP)
+	if _loopReservedWordToken == nil {
+		did_not_match_log("LOOP", lexemes, position)
+		return nil
 	}
-	if _statementNode == nil {
-		attempt_log("statement", lexemes, position)
-		return nil, nil
+	matched_log("LOOP", lexemes, position)
+	loopStatementNode.Loop = _loopReservedWordToken.Offset
+
+	_statements := statements(lexemes, position, lexer.END)
+	loopStatementNode.Body = _statements
+
+	loopStatementNode.EndPos = expect(lexemes, position, lexer.END, "to close the LOOP statement")
+
+	return loopStatementNode
+}
+
+// statement = [assignment | ProcedureCall | IfStatement | CaseStatement |
+// WhileStatement | RepeatStatement | ForStatement | WithStatement |
+// LoopStatement | EXIT | ReturnStatement]. In Oberon-07 a RETURN ends
+// the procedure body rather than a statement. The statement is
+// chosen by its first token; an assignment and a procedure call both
+// start with a designator and are told apart by the ":=" that follows it.
+// A token that can follow a statement makes it the empty statement; any
+// other token starts no statement and nil is returned.
+func statement(
+	lexemes *[]lexer.Token,
+	position *int,
+) ast.Stmt {
+	switch peek(lexemes, *position) {
+	case lexer.IDENT:
+		attempt_log("designator", lexemes, position)
+		_designatorNode := designator(lexemes, position)
+		matched_log("designator", lexemes, position)
+
+		attempt_optionally_log(":=", lexemes, position)
+		_colonEqualOperatorToken := match(lexemes, position, lexer.BECOMES)
+		if _colonEqualOperatorToken == nil {
+			_colonEqualOperatorToken = confused(lexemes, position, lexer.BECOMES, "in an assignment")
+		}
+		if _colonEqualOperatorToken != nil {
+			optionally_matched_log(":=", lexemes, position)
+			return assignment(lexemes, position, _designatorNode, _colonEqualOperatorToken)
+		}
+		did_not_match_optionally_log(":=", lexemes, position)
+		return procedureCall(lexemes, position, _designatorNode)
+	case lexer.IF:
+		return ifStatement(lexemes, position)
+	case lexer.CASE:
+		return caseStatement(lexemes, position)
+	case lexer.WHILE:
+		return whileStatement(lexemes, position)
+	case lexer.REPEAT:
+		return repeatStatement(lexemes, position)
+	case lexer.FOR:
+		return forStatement(lexemes, position)
+	case lexer.WITH:
+		return withStatement(lexemes, position)
+	case lexer.LOOP:
+		return loopStatement(lexemes, position)
+	case lexer.EXIT:
+		_exitReservedWordToken := match(lexemes, position, lexer.EXIT)
+		matched_log("EXIT", lexemes, position)
+		return &ast.ExitStmt{Exit: _exitReservedWordToken.Offset}
+	case lexer.RETURN:
+		if parserDialect.Has(lexer.RETURN_STATEMENTS) {
+			return returnStatement(lexemes, position)
+		}
+	}
+
+	if followedBy(lexemes, *position, "statement") {
+		return &ast.EmptyStmt{At: offset(lexemes, *position)}
 	}
-	statementSequenceNode.Children = append(statementSequenceNode.Children, _statementNode)
-	matched_log("statement", lexemes, position)
+	return nil
+}
+
+// StatementSequence = statement {";" statement}. Since a statement may be
+// empty, a statement sequence always matches; empty statements are kept
+// as EmptyStmt. A token that starts no statement is reported and skipped
+// up to the next ";" or STATEMENT_SYNC token as a BadStmt. A statement
+// followed by anything but ";" or a token
+// of STATEMENT_SYNC is a syntax error, reported as expecting ";" or one of
+// terminators. When the token can start a statement, only the ";" is
+// taken to be missing; otherwise the tokens up to the next ";" or
+// STATEMENT_SYNC token are skipped and kept as a BadStmt.
+func statementSequence(
+	lexemes *[]lexer.Token,
+	position *int,
+	terminators ...lexer.TokenKind,
+) []ast.Stmt {
+	var statements []ast.Stmt
 
-	// we deviate here from the spec to allow the final statement in
-	// a statementSequence to be followed by an optional semi-colon
 	for {
+		attempt_log("statement", lexemes, position)
+		_statementNode := statement(lexemes, position)
+		if _statementNode == nil {
+			did_not_match_log("statement", lexemes, position)
+			report_error(parse_error("statement", lexemes, position).
+				WithNote(source.Span{}, "%s starts with %s", DESCRIPTIONS["statement"], expected(statementStarts()...)))
+			from, to := skip(lexemes, position, append([]lexer.TokenKind{lexer.SEMICOLON}, STATEMENT_SYNC...)...)
+			statements = append(statements, &ast.BadStmt{From: from, To: to})
+			if match(lexemes, position, lexer.SEMICOLON) == nil {
+				break
+			}
+			continue
+		}
+		matched_log("statement", lexemes, position)
+		statements = append(statements, _statementNode)
+
 		attempt_optionally_log(";", lexemes, position)
-		_semicolonNode := matchOperator(lexemes, position, ";")
-		if _semicolonNode == nil {
-			did_not_match_optionally_log(";", lexemes, position)
+		_semicolonToken := match(lexemes, position, lexer.SEMICOLON)
+		if _semicolonToken != nil {
+			optionally_matched_log(";", lexemes, position)
+			continue
+		}
+		did_not_match_optionally_log(";", lexemes, position)
+		if at(lexemes, *position, STATEMENT_SYNC...) {
 			break
 		}
-		statementSequenceNode.Children = append(statementSequenceNode.Children, _semicolonNode)
-		optionally_matched_log(";", lexemes, position)
 
-		attempt_log("statement", lexemes, position)
-		_statementNode, err := statement(lexemes, position)
-		if err != nil {
-			return nil, err
+		var acceptable = append([]lexer.TokenKind{lexer.SEMICOLON}, terminators...)
+		if startsWith(lexemes, *position, "statement") {
+			var end = (*lexemes)[*position-1].End
+			report_error(parse_error(expected(acceptable...), lexemes, position).
+				WithSuggestion(parserFile.Span(end, end), ";", "separate the statements with a semicolon"))
+			continue
 		}
-		if _statementNode == nil {
-			did_not_match_log("statement", lexemes, position)
+		report_error(parse_error(expected(acceptable...), lexemes, position))
+		from, to := skip(lexemes, position, append([]lexer.TokenKind{lexer.SEMICOLON}, STATEMENT_SYNC...)...)
+		statements = append(statements, &ast.BadStmt{From: from, To: to})
+		if match(lexemes, position, lexer.SEMICOLON) == nil {
 			break
 		}
-		statementSequenceNode.Children = append(statementSequenceNode.Children, _statementNode)
-		matched_log("statement", lexemes, position)
 	}
 
-	return statementSequenceNode, nil
+	return statements
 }
 
-func procedureBody(
-	lexemes *[]lexer.Lexeme,
+// statementStarts returns the tokens a statement can start with in the
+// dialect parsed: RETURN ends the procedure body in Oberon-07.
+func statementStarts() []lexer.TokenKind {
+	var kinds []lexer.TokenKind
+	for _, kind := range first("statement") {
+		if kind != lexer.RETURN || parserDialect.Has(lexer.RETURN_STATEMENTS) {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// statements parses the statement sequence of a construct that ends at
+// one of terminators. The sequence may also stop at a token that ends
+// the statements of other constructs, such as an ELSE inside a WHILE; it
+// is reported and skipped as a BadStmt, along with the tokens after it up
+// to the end of the statement, and parsing goes on. END and the end of
+// the file are never skipped.
+func statements(
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var procedureBodyNode = new(ParseNode)
-	procedureBodyNode.Label = "procedureBody"
-	var positionCheckpoint = *position
+	terminators ...lexer.TokenKind,
+) []ast.Stmt {
+	var result []ast.Stmt
+	for {
+		attempt_log("statementSequence", lexemes, position)
+		_statements := statementSequence(lexemes, position, terminators...)
+		matched_log("statementSequence", lexemes, position)
+		result = append(result, _statements...)
 
-	attempt_log("declarationSequence", lexemes, position)
-	_declarationSequenceNode, err := declarationSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _declarationSequenceNode == nil {
-		did_not_match_log("declarationSequence", lexemes, position)
-		return nil, nil
+		if at(lexemes, *position, append(terminators, lexer.END)...) {
+			return result
+		}
+		report_error(parse_error(expected(append([]lexer.TokenKind{lexer.SEMICOLON}, terminators...)...), lexemes, position))
+		var from = offset(lexemes, *position)
+		(*position)++
+		_, to := skip(lexemes, position, STATEMENT_ENDS...)
+		result = append(result, &ast.BadStmt{From: from, To: to})
 	}
+}
+
+// ProcedureBody = DeclarationSequence [BEGIN StatementSequence]
+// [RETURN expression] END. The parts of the body are filled into decl.
+func procedureBody(
+	lexemes *[]lexer.Token,
+	position *int,
+	decl *ast.ProcDecl,
+) {
+	attempt_log("declarationSequence", lexemes, position)
+	_declarations := declarationSequence(lexemes, position, lexer.BEGIN, lexer.RETURN, lexer.END)
 	matched_log("declarationSequence", lexemes, position)
-	procedureBodyNode.Children = append(procedureBodyNode.Children, _declarationSequenceNode)
+	decl.Decls = _declarations
 
 	attempt_optionally_log("BEGIN", lexemes, position)
-	_beginReservedWordNode := matchReservedWord(lexemes, position, "BEGIN")
-	if _beginReservedWordNode != nil {
+	_beginReservedWordToken := match(lexemes, position, lexer.BEGIN)
+	if _beginReservedWordToken != nil {
 		optionally_matched_log("BEGIN", lexemes, position)
-		attempt_log("statementSequence", lexemes, position)
-		_statementSequenceNode, err := statementSequence(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _statementSequenceNode == nil {
-			did_not_match_log("statementSequence", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("statementSequence", lexemes, position)
-		procedureBodyNode.Children = append(procedureBodyNode.Children, _beginReservedWordNode)
-		procedureBodyNode.Children = append(procedureBodyNode.Children, _statementSequenceNode)
+
+		_statements := statements(lexemes, position, lexer.RETURN, lexer.END)
+		decl.Body = _statements
 	} else {
 		did_not_match_optionally_log("BEGIN", lexemes, position)
 	}
 
 	attempt_optionally_log("RETURN", lexemes, position)
-	_returnReservedWordNode := matchReservedWord(lexemes, position, "RETURN")
-	if _returnReservedWordNode != nil {
+	_returnReservedWordToken := match(lexemes, position, lexer.RETURN)
+	if _returnReservedWordToken != nil {
 		optionally_matched_log("RETURN", lexemes, position)
-		attempt_log("expression", lexemes, position)
-		_expression, err := expression(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _expression == nil {
-			did_not_match_log("expression", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
-		}
-		matched_log("expression", lexemes, position)
-		procedureBodyNode.Children = append(procedureBodyNode.Children, _returnReservedWordNode)
-		procedureBodyNode.Children = append(procedureBodyNode.Children, _expression)
+
+		_expressionNode := requiredExpression(lexemes, position, "after RETURN")
+		decl.Return = _expressionNode
+	} else {
+		did_not_match_optionally_log("RETURN", lexemes, position)
 	}
 
-	attempt_log("END", lexemes, position)
-	_endReservedWordNode := matchReservedWord(lexemes, position, "END")
-	if _endReservedWordNode == nil {
-		did_not_match_log("END", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+	decl.EndPos = expect(lexemes, position, lexer.END, "to close procedure "+decl.Name.Name.Name)
+}
+
+// receiver = "(" [VAR | IN] ident ":" ident ")". IN is Component Pascal
+// only.
+func receiver(
+	lexemes *[]lexer.Token,
+	position *int,
+) *ast.Receiver {
+	var receiverNode = &ast.Receiver{Var: ast.NO_POS}
+
+	attempt_log("(", lexemes, position)
+	_leftParenToken := match(lexemes, position, lexer.LPAREN)
+	if _leftParenToken == nil {
+		did_not_match_log("(", lexemes, position)
+		return nil
+	}
+	matched_log("(", lexemes, position)
+	receiverNode.Lparen = _leftParenToken.Offset
+
+	attempt_optionally_log("VAR", lexemes, position)
+	_varToken := matchAny(lexemes, position, lexer.VAR, lexer.IN)
+	if _varToken != nil {
+		optionally_matched_log(_varToken.Kind.String(), lexemes, position)
+		if _varToken.Kind == lexer.IN {
+			extension(parserFile.Span(_varToken.Offset, _varToken.End), lexer.IN_RECEIVERS)
+		}
+		receiverNode.Var = _varToken.Offset
+		receiverNode.Mode = _varToken.Kind
+	} else {
+		did_not_match_optionally_log("VAR", lexemes, position)
 	}
-	matched_log("END", lexemes, position)
 
-	return procedureBodyNode, nil
+	receiverNode.Name = expectIdent(lexemes, position, "receiver name")
+
+	expect(lexemes, position, lexer.COLON, "after the receiver name")
+
+	receiverNode.Type = expectIdent(lexemes, position, "receiver type after \":\"")
+
+	receiverNode.Rparen = expect(lexemes, position, lexer.RPAREN, "to close the receiver")
+
+	return receiverNode
 }
 
+// MethodAttributes = ["," NEW] ["," (ABSTRACT | EMPTY | EXTENSIBLE)]. The
+// Component Pascal attributes of a procedure are filled into decl.
+func methodAttributes(
+	lexemes *[]lexer.Token,
+	position *int,
+	decl *ast.ProcDecl,
+) {
+	decl.New = ast.NO_POS
+	for peek(lexemes, *position) == lexer.COMMA {
+		_commaToken := match(lexemes, position, lexer.COMMA)
+		switch kind := peek(lexemes, *position); {
+		case kind == lexer.IDENT && (*lexemes)[*position].Label == "NEW" && decl.New == ast.NO_POS && decl.Attr == lexer.ILLEGAL:
+			decl.New = offset(lexemes, *position)
+		case (kind == lexer.ABSTRACT || kind == lexer.EMPTY || kind == lexer.EXTENSIBLE) && decl.Attr == lexer.ILLEGAL:
+			decl.Attr = kind
+			decl.AttrPos = offset(lexemes, *position)
+		default:
+			did_not_match_log("method attribute", lexemes, position)
+			report_error(parse_error("NEW, ABSTRACT, EMPTY or EXTENSIBLE after \",\"", lexemes, position))
+			return
+		}
+		matched_log("method attribute", lexemes, position)
+		extension(parserFile.Span(_commaToken.Offset, (*lexemes)[*position].End), lexer.METHOD_ATTRIBUTES)
+		(*position)++
+	}
+}
+
+// ProcedureHeading = PROCEDURE [receiver] identdef [FormalParameters]
+// MethodAttributes.
 func procedureHeading(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var procedureHeadingNode = new(ParseNode)
-	procedureHeadingNode.Label = "procedureHeading"
-	var positionCheckpoint = *position
+) *ast.ProcDecl {
+	var procedureHeadingNode = new(ast.ProcDecl)
 
 	attempt_log("PROCEDURE", lexemes, position)
-	_procedureReservedWordNode := matchReservedWord(lexemes, position, "PROCEDURE")
-	if _procedureReservedWordNode == nil {
+	_procedureReservedWordToken := match(lexemes, position, lexer.PROCEDURE)
+	if _procedureReservedWordToken == nil {
 		did_not_match_log("PROCEDURE", lexemes, position)
-		return nil, nil
+		return nil
+	}
+	matched_log("PROCEDURE", lexemes, position)
+	procedureHeadingNode.Procedure = _procedureReservedWordToken.Offset
+
+	attempt_optionally_log("receiver", lexemes, position)
+	_receiverNode := receiver(lexemes, position)
+	if _receiverNode != nil {
+		optionally_matched_log("receiver", lexemes, position)
+		extension(ast.Span(parserFile, _receiverNode), lexer.TYPE_BOUND_PROCEDURES)
+		procedureHeadingNode.Receiver = _receiverNode
+	} else {
+		did_not_match_optionally_log("receiver", lexemes, position)
 	}
-	matched_log("END", lexemes, position)
 
 	attempt_log("identdef", lexemes, position)
-	_identDefNode, err := identdef(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_identDefNode := identdef(lexemes, position)
 	if _identDefNode == nil {
 		did_not_match_log("identdef", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		_identDefNode = &ast.IdentDef{Name: expectIdent(lexemes, position, "procedure name after PROCEDURE"), Star: ast.NO_POS}
+	} else {
+		matched_log("identdef", lexemes, position)
 	}
-	procedureHeadingNode.Children = append(procedureHeadingNode.Children, _procedureReservedWordNode)
-	procedureHeadingNode.Children = append(procedureHeadingNode.Children, _identDefNode)
-	matched_log("identdef", lexemes, position)
+	procedureHeadingNode.Name = _identDefNode
 
-	attempt_log("formalParameters", lexemes, position)
-	_formalParametersNode, err := formalParameters(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	attempt_optionally_log("formalParameters", lexemes, position)
+	_formalParametersNode := formalParameters(lexemes, position)
 	if _formalParametersNode != nil {
-		did_not_match_log("formalParameters", lexemes, position)
-		procedureHeadingNode.Children = append(procedureHeadingNode.Children, _formalParametersNode)
+		optionally_matched_log("formalParameters", lexemes, position)
+		procedureHeadingNode.Params = _formalParametersNode
+	} else {
+		did_not_match_optionally_log("formalParameters", lexemes, position)
 	}
-	matched_log("formalParameters", lexemes, position)
 
-	return procedureHeadingNode, nil
+	methodAttributes(lexemes, position, procedureHeadingNode)
+
+	return procedureHeadingNode
 }
 
+// ProcedureDeclaration = ProcedureHeading [";" ProcedureBody ident]. Only
+// the ABSTRACT and EMPTY procedures of Component Pascal have no body.
 func procedureDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var procedureDeclarationNode = new(ParseNode)
-	procedureDeclarationNode.Label = "procedureDeclaration"
-	var positionCheckpoint = *position
-
+) *ast.ProcDecl {
 	attempt_log("procedureHeading", lexemes, position)
-	_pocedureHeadingNode, err := procedureHeading(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _pocedureHeadingNode == nil {
+	procedureDeclarationNode := procedureHeading(lexemes, position)
+	if procedureDeclarationNode == nil {
 		did_not_match_log("procedureHeading", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("procedureHeading", lexemes, position)
 
-	attempt_log(";", lexemes, position)
-	_semicolonNode := matchOperator(lexemes, position, ";")
-	if _semicolonNode == nil {
-		did_not_match_log(";", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+	if !procedureDeclarationNode.HasBody() {
+		procedureDeclarationNode.EndPos = ast.NO_POS
+		return procedureDeclarationNode
 	}
-	matched_log(";", lexemes, position)
+
+	expect(lexemes, position, lexer.SEMICOLON, "after the procedure heading")
 
 	attempt_log("procedureBody", lexemes, position)
-	_procedureBodyNode, err := procedureBody(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _procedureBodyNode == nil {
-		did_not_match_log("procedureBody", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
+	procedureBody(lexemes, position, procedureDeclarationNode)
 	matched_log("procedureBody", lexemes, position)
 
-	attempt_log("ident", lexemes, position)
-	_identNode := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
-		did_not_match_log("ident", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log("ident", lexemes, position)
-
-	procedureDeclarationNode.Children = append(procedureDeclarationNode.Children, _pocedureHeadingNode)
-	procedureDeclarationNode.Children = append(procedureDeclarationNode.Children, _semicolonNode)
-	procedureDeclarationNode.Children = append(procedureDeclarationNode.Children, _procedureBodyNode)
-	procedureDeclarationNode.Children = append(procedureDeclarationNode.Children, _identNode)
+	procedureDeclarationNode.EndName = closingName(lexemes, position, "procedure", procedureDeclarationNode.Name.Name)
 
-	return procedureDeclarationNode, nil
+	return procedureDeclarationNode
 }
 
+// VariableDeclaration = IdentList ":" type.
 func varDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var varDeclarationNode = new(ParseNode)
-	varDeclarationNode.Label = "varDeclaration"
-	var positionCheckpoint = *position
+) *ast.VarDecl {
+	var varDeclarationNode = new(ast.VarDecl)
 
-	attempt_log("ident", lexemes, position)
-	_identListNode, err := identList(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _identListNode == nil {
-		did_not_match_log("ident", lexemes, position)
-		return nil, nil
+	attempt_log("identList", lexemes, position)
+	_identDefs := identList(lexemes, position)
+	if _identDefs == nil {
+		did_not_match_log("identList", lexemes, position)
+		return nil
 	}
-	matched_log("ident", lexemes, position)
+	matched_log("identList", lexemes, position)
+	varDeclarationNode.Names = _identDefs
 
-	attempt_log(":", lexemes, position)
-	_colonNode := matchOperator(lexemes, position, ":")
-	if _colonNode == nil {
-		did_not_match_log(":", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
-	}
-	matched_log(":", lexemes, position)
+	expect(lexemes, position, lexer.COLON, "after the variable names")
 
 	attempt_log("type", lexemes, position)
-	_typeNode, err := _type(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_typeNode := _type(lexemes, position)
 	if _typeNode == nil {
 		did_not_match_log("type", lexemes, position)
-		*position = positionCheckpoint
-		return nil, nil
+		_typeNode = missing("type after \":\"", "type", lexemes, position)
+	} else {
+		matched_log("type", lexemes, position)
 	}
-	matched_log("type", lexemes, position)
-
-	varDeclarationNode.Children = append(varDeclarationNode.Children, _identListNode)
-	varDeclarationNode.Children = append(varDeclarationNode.Children, _colonNode)
-	varDeclarationNode.Children = append(varDeclarationNode.Children, _typeNode)
+	varDeclarationNode.Type = _typeNode
 
-	return varDeclarationNode, nil
+	return varDeclarationNode
 }
 
+// ConstDeclaration = identdef "=" ConstExpression.
 func constDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var constDeclarationNode = new(ParseNode)
-	constDeclarationNode.Label = "constDeclaration"
+) *ast.ConstDecl {
+	var constDeclarationNode = new(ast.ConstDecl)
 
-	matched_log("identdef", lexemes, position)
-	_identDefNode, err := identdef(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	attempt_log("identdef", lexemes, position)
+	_identDefNode := identdef(lexemes, position)
 	if _identDefNode == nil {
 		did_not_match_log("identdef", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("identdef", lexemes, position)
+	constDeclarationNode.Name = _identDefNode
 
-	attempt_log("=", lexemes, position)
-	_assignmentNode := matchOperator(lexemes, position, "=")
-	if _assignmentNode == nil {
-		did_not_match_log("=", lexemes, position)
-		return nil, nil
-	}
-	matched_log("=", lexemes, position)
+	expect(lexemes, position, lexer.EQL, "after the constant name")
 
 	attempt_log("constExpression", lexemes, position)
-	_constExpressionNode, err := constExpression(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
+	_constExpressionNode := constExpression(lexemes, position)
 	if _constExpressionNode == nil {
 		did_not_match_log("constExpression", lexemes, position)
-		return nil, nil
+		_constExpressionNode = missing("constant expression after \"=\"", "ConstExpression", lexemes, position)
+	} else {
+		matched_log("constExpression", lexemes, position)
 	}
-	matched_log("constExpression", lexemes, position)
+	constDeclarationNode.Value = _constExpressionNode
 
-	constDeclarationNode.Children =
-		append(constDeclarationNode.Children, _identDefNode)
-	constDeclarationNode.Children =
-		append(constDeclarationNode.Children, _assignmentNode)
-	constDeclarationNode.Children =
-		append(constDeclarationNode.Children, _constExpressionNode)
+	return constDeclarationNode
+}
 
-	return constDeclarationNode, nil
+// declarationEnd consumes the ";" closing a declaration of production.
+// When it is missing before the start of another such declaration, only
+// the ";" is taken to be missing; otherwise the tokens up to the next ";"
+// or DECLARATION_SYNC token are skipped and returned as a BadDecl.
+func declarationEnd(
+	lexemes *[]lexer.Token,
+	position *int,
+	production string,
+) *ast.BadDecl {
+	attempt_log(";", lexemes, position)
+	if match(lexemes, position, lexer.SEMICOLON) != nil {
+		matched_log(";", lexemes, position)
+		return nil
+	}
+	did_not_match_log(";", lexemes, position)
+	var diagnostic = parse_error(expected(lexer.SEMICOLON)+" after the declaration", lexemes, position)
+	if startsWith(lexemes, *position, production) {
+		var end = (*lexemes)[*position-1].End
+		report_error(diagnostic.WithSuggestion(parserFile.Span(end, end), ";", "end the declaration with a semicolon"))
+		return nil
+	}
+	report_error(diagnostic)
+	from, to := skip(lexemes, position, append([]lexer.TokenKind{lexer.SEMICOLON}, DECLARATION_SYNC...)...)
+	match(lexemes, position, lexer.SEMICOLON)
+	if to == from {
+		return nil
+	}
+	return &ast.BadDecl{From: from, To: to}
 }
 
+// [CONST {ConstDeclaration ";"}]
 func declarationSequence_constSequence(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-	declarationSequenceNode *ParseNode,
-) (*ParseNode, error) {
-	var declarationSequence_constSequenceNode = new(ParseNode)
-	declarationSequence_constSequenceNode.Label = "declarationSequence_constSequence"
+) []ast.Decl {
+	var declarations []ast.Decl
 
-	// [CONST {ConstDeclaration ";"}]
 	attempt_log("CONST", lexemes, position)
-	_constReservedWordNode := matchReservedWord(lexemes, position, "CONST")
-	if _constReservedWordNode == nil {
+	_constReservedWordToken := match(lexemes, position, lexer.CONST)
+	if _constReservedWordToken == nil {
 		did_not_match_log("CONST", lexemes, position)
-		return nil, nil
+		return nil
 	}
 	matched_log("CONST", lexemes, position)
-	declarationSequence_constSequenceNode.Children = append(declarationSequence_constSequenceNode.Children, _constReservedWordNode)
 
 	for {
-		attempt_log("constDeclaration", lexemes, position)
-		_constDeclarationNode, err := constDeclaration(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		attempt_optionally_log("constDeclaration", lexemes, position)
+		_constDeclarationNode := constDeclaration(lexemes, position)
 		if _constDeclarationNode == nil {
 			did_not_match_optionally_log("constDeclaration", lexemes, position)
 			break
 		}
 		optionally_matched_log("constDeclaration", lexemes, position)
+		declarations = append(declarations, _constDeclarationNode)
 
-		attempt_log(";", lexemes, position)
-		_semicolonNode := matchOperator(lexemes, position, ";")
-		if _semicolonNode == nil {
-			did_not_match_optionally_log(";", lexemes, position)
-			return nil, nil
+		if _badDeclarationNode := declarationEnd(lexemes, position, "ConstDeclaration"); _badDeclarationNode != nil {
+			declarations = append(declarations, _badDeclarationNode)
 		}
-		matched_log(";", lexemes, position)
-
-		declarationSequence_constSequenceNode.Children =
-			append(declarationSequence_constSequenceNode.Children, _constDeclarationNode)
-		declarationSequence_constSequenceNode.Children =
-			append(declarationSequence_constSequenceNode.Children, _semicolonNode)
 	}
-	return declarationSequence_constSequenceNode, nil
+	return declarations
 }
 
+// [TYPE {TypeDeclaration ";"}]
 func declarationSequence_typeDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-	declarationSequenceNode *ParseNode,
-) (*ParseNode, error) {
-	var _typeDeclarationSequenceNode = new(ParseNode)
-	_typeDeclarationSequenceNode.Label = "declarationSequence_typeDeclaration"
+) []ast.Decl {
+	var declarations []ast.Decl
 
-	// [TYPE {typeDeclaration ";"}]
 	attempt_log("TYPE", lexemes, position)
-	_typeReservedWordNode := matchReservedWord(lexemes, position, "TYPE")
-	if _typeReservedWordNode == nil {
+	_typeReservedWordToken := match(lexemes, position, lexer.TYPE)
+	if _typeReservedWordToken == nil {
 		did_not_match_log("TYPE", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	_typeDeclarationSequenceNode.Children = append(_typeDeclarationSequenceNode.Children, _typeReservedWordNode)
 	matched_log("TYPE", lexemes, position)
 
 	for {
-		attempt_log("typeDeclaration", lexemes, position)
-		_typeDeclarationNode, err := typeDeclaration(lexemes, position)
-		if err != nil {
-			return _typeDeclarationSequenceNode, err
-		}
+		attempt_optionally_log("typeDeclaration", lexemes, position)
+		_typeDeclarationNode := typeDeclaration(lexemes, position)
 		if _typeDeclarationNode == nil {
-			did_not_match_log("typeDeclaration", lexemes, position)
+			did_not_match_optionally_log("typeDeclaration", lexemes, position)
 			break
 		}
-		matched_log("typeDeclaration", lexemes, position)
+		optionally_matched_log("typeDeclaration", lexemes, position)
+		declarations = append(declarations, _typeDeclarationNode)
 
-		attempt_log(";", lexemes, position)
-		_semicolonNode := matchOperator(lexemes, position, ";")
-		if _semicolonNode == nil {
-			did_not_match_log(";", lexemes, position)
-			return nil, nil
+		if _badDeclarationNode := declarationEnd(lexemes, position, "TypeDeclaration"); _badDeclarationNode != nil {
+			declarations = append(declarations, _badDeclarationNode)
 		}
-		matched_log(";", lexemes, position)
-		_typeDeclarationSequenceNode.Children =
-			append(_typeDeclarationSequenceNode.Children, _typeDeclarationNode)
-		_typeDeclarationSequenceNode.Children =
-			append(_typeDeclarationSequenceNode.Children, _semicolonNode)
 	}
-
-	return _typeDeclarationSequenceNode, nil
+	return declarations
 }
 
+// [VAR {VariableDeclaration ";"}]
 func declarationSequence_varDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-	declarationSequenceNode *ParseNode,
-) (*ParseNode, error) {
-	var _varDeclarationSequenceNode = new(ParseNode)
-	_varDeclarationSequenceNode.Label = "declarationSequence_varDeclaration"
-	var positionCheckpoint = *position
+) []ast.Decl {
+	var declarations []ast.Decl
 
 	attempt_log("VAR", lexemes, position)
-	_varReservedWordNode := matchReservedWord(lexemes, position, "VAR")
-	if _varReservedWordNode == nil {
+	_varReservedWordToken := match(lexemes, position, lexer.VAR)
+	if _varReservedWordToken == nil {
 		did_not_match_log("VAR", lexemes, position)
-		return nil, nil
+		return nil
 	}
-	_varDeclarationSequenceNode.Children = append(_varDeclarationSequenceNode.Children, _varReservedWordNode)
 	matched_log("VAR", lexemes, position)
 
 	for {
-		attempt_log("varDeclaration", lexemes, position)
-		_varDeclarationNode, err := varDeclaration(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		attempt_optionally_log("varDeclaration", lexemes, position)
+		_varDeclarationNode := varDeclaration(lexemes, position)
 		if _varDeclarationNode == nil {
-			did_not_match_log("varDeclaration", lexemes, position)
+			did_not_match_optionally_log("varDeclaration", lexemes, position)
 			break
 		}
-		matched_log("varDeclaration", lexemes, position)
+		optionally_matched_log("varDeclaration", lexemes, position)
+		declarations = append(declarations, _varDeclarationNode)
 
-		attempt_log(";", lexemes, position)
-		_semicolonNode := matchOperator(lexemes, position, ";")
-		if _semicolonNode == nil {
-			did_not_match_log(";", lexemes, position)
-			*position = positionCheckpoint
-			return nil, nil
+		if _badDeclarationNode := declarationEnd(lexemes, position, "VariableDeclaration"); _badDeclarationNode != nil {
+			declarations = append(declarations, _badDeclarationNode)
 		}
-		matched_log("varDeclaration", lexemes, position)
-
-		_varDeclarationSequenceNode.Children =
-			append(_varDeclarationSequenceNode.Children, _varDeclarationNode)
-		_varDeclarationSequenceNode.Children =
-			append(_varDeclarationSequenceNode.Children, _semicolonNode)
 	}
-	return _varDeclarationSequenceNode, nil
+	return declarations
 }
 
+// {ProcedureDeclaration ";"}
 func declarationSequence_procedureDeclaration(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-	declarationSequenceNode *ParseNode,
-) (*ParseNode, error) {
-	var _procedureDeclarationSequenceNode = new(ParseNode)
-	_procedureDeclarationSequenceNode.Label = "declarationSequence_procedureDeclaration"
+) []ast.Decl {
+	var declarations []ast.Decl
 
 	for {
 		attempt_optionally_log("procedureDeclaration", lexemes, position)
-		_procedureDeclarationNode, err := procedureDeclaration(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
+		_procedureDeclarationNode := procedureDeclaration(lexemes, position)
 		if _procedureDeclarationNode == nil {
 			did_not_match_optionally_log("procedureDeclaration", lexemes, position)
 			break
 		}
 		optionally_matched_log("procedureDeclaration", lexemes, position)
+		declarations = append(declarations, _procedureDeclarationNode)
 
-		attempt_log(";", lexemes, position)
-		_semicolonNode := matchOperator(lexemes, position, ";")
-		if _semicolonNode == nil {
-			did_not_match_log(";", lexemes, position)
-			return nil, nil
+		if _badDeclarationNode := declarationEnd(lexemes, position, "ProcedureDeclaration"); _badDeclarationNode != nil {
+			declarations = append(declarations, _badDeclarationNode)
 		}
-		matched_log(";", lexemes, position)
-
-		_procedureDeclarationSequenceNode.Children =
-			append(_procedureDeclarationSequenceNode.Children, _procedureDeclarationNode)
-		_procedureDeclarationSequenceNode.Children =
-			append(_procedureDeclarationSequenceNode.Children, _semicolonNode)
 	}
-	return _procedureDeclarationSequenceNode, nil
+	return declarations
 }
 
+// DeclarationSequence = [CONST {ConstDeclaration ";"}]
+// [TYPE {TypeDeclaration ";"}]
+// [VAR {VariableDeclaration ";"}]
+// {ProcedureDeclaration ";"}.
+// Each section is chosen by its keyword. A declaration sequence ends at
+// BEGIN, RETURN, END or the end of the file. Anything else is reported as
+// expecting a later section or one of terminators: a section out of order
+// is parsed anyway, other tokens are skipped as a BadDecl.
 func declarationSequence(
-	lexemes *[]lexer.Lexeme,
-	position *int,
-) (*ParseNode, error) {
-	var declarationSequenceNode = new(ParseNode)
-	declarationSequenceNode.Label = "declarationSequence"
-	var positionCheckpoint = *position
-
-	// [CONST {ConstDeclaration ";"}]
-	attempt_optionally_log("declarationSequence_constSequence", lexemes, position)
-	_constDeclarationSequenceNode, err := declarationSequence_constSequence(lexemes, position, declarationSequenceNode)
-	if err != nil {
-		*position = positionCheckpoint
-		return nil, err
-	}
-	if _constDeclarationSequenceNode != nil {
-		optionally_matched_log("declarationSequence_constSequence", lexemes, position)
-		declarationSequenceNode.Children =
-			append(declarationSequenceNode.Children, _constDeclarationSequenceNode)
-	} else {
-		did_not_match_optionally_log("declarationSequence_constSequence", lexemes, position)
-	}
-
-	// [TYPE {typeDeclaration ";"}]
-	attempt_optionally_log("declarationSequence_typeDeclaration", lexemes, position)
-	_typeDeclarationSequenceNode, err := declarationSequence_typeDeclaration(lexemes, position, declarationSequenceNode)
-	if err != nil {
-		*position = positionCheckpoint
-		return nil, err
-	}
-	if _typeDeclarationSequenceNode != nil {
-		optionally_matched_log("declarationSequence_typeDeclaration", lexemes, position)
-		declarationSequenceNode.Children =
-			append(declarationSequenceNode.Children, _typeDeclarationSequenceNode)
-	} else {
-		did_not_match_optionally_log("declarationSequence_typeDeclaration", lexemes, position)
-	}
+	lexemes *[]lexer.Token,
+	position *int,
+	terminators ...lexer.TokenKind,
+) []ast.Decl {
+	var declarations []ast.Decl
+
+	sections := []struct {
+		keyword lexer.TokenKind
+		name    string
+		parse   func(*[]lexer.Token, *int) []ast.Decl
+	}{
+		{lexer.CONST, "declarationSequence_constSequence", declarationSequence_constSequence},
+		{lexer.TYPE, "declarationSequence_typeDeclaration", declarationSequence_typeDeclaration},
+		{lexer.VAR, "declarationSequence_varDeclaration", declarationSequence_varDeclaration},
+		{lexer.PROCEDURE, "declarationSequence_procedureDeclaration", declarationSequence_procedureDeclaration},
+	}
+	// next is the first section that may still follow.
+	var next = 0
+	for {
+		var acceptable = append([]lexer.TokenKind(nil), terminators...)
+		for i := len(sections) - 1; i >= next; i-- {
+			acceptable = append([]lexer.TokenKind{sections[i].keyword}, acceptable...)
+		}
 
-	// [VAR {VarDeclaration ";"}]
-	attempt_optionally_log("declarationSequence_varDeclaration", lexemes, position)
-	_varDeclarationSequenceNode, err := declarationSequence_varDeclaration(lexemes, position, declarationSequenceNode)
-	if err != nil {
-		*position = positionCheckpoint
-		return nil, err
-	}
-	if _varDeclarationSequenceNode != nil {
-		optionally_matched_log("declarationSequence_varDeclaration", lexemes, position)
-		declarationSequenceNode.Children =
-			append(declarationSequenceNode.Children, _varDeclarationSequenceNode)
-	} else {
-		did_not_match_optionally_log("declarationSequence_varDeclaration", lexemes, position)
-	}
+		var kind = peek(lexemes, *position)
+		var section = -1
+		for i := range sections {
+			if sections[i].keyword == kind {
+				section = i
+			}
+		}
+		if section >= 0 {
+			if section < next {
+				report_error(parse_error(expected(acceptable...), lexemes, position))
+			}
+			attempt_optionally_log(sections[section].name, lexemes, position)
+			_declarations := sections[section].parse(lexemes, position)
+			optionally_matched_log(sections[section].name, lexemes, position)
+			declarations = append(declarations, _declarations...)
+			next = section + 1
+			continue
+		}
 
-	// {ProcedureDeclaration ";"}
-	attempt_optionally_log("declarationSequence_procedureDeclaration", lexemes, position)
-	_procedureDeclarationSequenceNode, err := declarationSequence_procedureDeclaration(lexemes, position, declarationSequenceNode)
-	if err != nil {
-		*position = positionCheckpoint
-		return nil, err
-	}
-	if _procedureDeclarationSequenceNode != nil {
-		optionally_matched_log("declarationSequence_procedureDeclaration", lexemes, position)
-		declarationSequenceNode.Children =
-			append(declarationSequenceNode.Children, _procedureDeclarationSequenceNode)
-	} else {
-		did_not_match_optionally_log("declarationSequence_procedureDeclaration", lexemes, position)
+		if at(lexemes, *position, follow("DeclarationSequence")...) {
+			return declarations
+		}
+		report_error(parse_error(expected(acceptable...), lexemes, position))
+		var from = offset(lexemes, *position)
+		(*position)++
+		_, to := skip(lexemes, position, append([]lexer.TokenKind{lexer.SEMICOLON}, DECLARATION_SYNC...)...)
+		match(lexemes, position, lexer.SEMICOLON)
+		declarations = append(declarations, &ast.BadDecl{From: from, To: to})
 	}
-
-	return declarationSequenceNode, nil
 }
 
+// module = MODULE ident ";" [ImportList] DeclarationSequence
+// [BEGIN StatementSequence] END ident ".".
+// Only a missing MODULE stops the parse; after it, every syntax error is
+// reported and the tree is built from what could be parsed.
 func module(
-	lexemes *[]lexer.Lexeme,
+	lexemes *[]lexer.Token,
 	position *int,
-) (*ParseNode, error) {
-	var moduleNode = new(ParseNode)
-	moduleNode.Label = "module"
+) (*ast.Module, error) {
+	var moduleNode = new(ast.Module)
 
 	// MODULE
 	attempt_log("MODULE", lexemes, position)
-	_moduleNode := matchReservedWord(lexemes, position, "MODULE")
-	if _moduleNode == nil {
+	_moduleToken := match(lexemes, position, lexer.MODULE)
+	if _moduleToken == nil {
 		did_not_match_log("MODULE", lexemes, position)
-		return nil, fmt.Errorf("parse error: expected 'MODULE', found %v", (*lexemes)[*position])
+		return nil, parse_error("\"MODULE\"", lexemes, position)
 	}
 	matched_log("MODULE", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _moduleNode)
+	moduleNode.Module = _moduleToken.Offset
 
 	// ident
-	attempt_log("ident", lexemes, position)
-	_identNode := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode == nil {
-		did_not_match_log("ident", lexemes, position)
-		return nil, fmt.Errorf("parse error: expected identNode, found %v", (*lexemes)[*position])
-	}
-	matched_log("ident", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _identNode)
+	moduleNode.Name = expectIdent(lexemes, position, "module name after MODULE")
 
 	// ;
-	attempt_log(";", lexemes, position)
-	_semicolonNode := matchOperator(lexemes, position, ";")
-	if _semicolonNode == nil {
-		did_not_match_log(";", lexemes, position)
-		return nil, fmt.Errorf("parse error: expected ';', found %v", (*lexemes)[*position])
-	}
-	matched_log(";", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _identNode)
+	expect(lexemes, position, lexer.SEMICOLON, "after the module name")
 
 	// [ImportList]
 	attempt_optionally_log("importList", lexemes, position)
-	_importListNode, err := importList(lexemes, position)
-	if err != nil {
-		did_not_match_optionally_log("importList", lexemes, position)
-		return nil, err
-	}
-	if _importListNode != nil {
+	_imports := importList(lexemes, position)
+	if _imports != nil {
 		optionally_matched_log("importList", lexemes, position)
-		moduleNode.Children = append(moduleNode.Children, _importListNode)
+		moduleNode.Imports = _imports
+	} else {
+		did_not_match_optionally_log("importList", lexemes, position)
 	}
 
 	// DeclarationSequence
 	attempt_log("declarationSequence", lexemes, position)
-	_declarationSequenceNode, err := declarationSequence(lexemes, position)
-	if err != nil {
-		return nil, err
-	}
-	if _declarationSequenceNode == nil {
-		did_not_match_log("declarationSequence", lexemes, position)
-		return nil, fmt.Errorf("parse error: expected declarationSequence, found %v", (*lexemes)[*position])
-	}
+	_declarations := declarationSequence(lexemes, position, lexer.BEGIN, lexer.END)
 	matched_log("declarationSequence", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _declarationSequenceNode)
+	moduleNode.Decls = _declarations
 
 	// [BEGIN StatementSequence]
 	attempt_optionally_log("BEGIN", lexemes, position)
-	_beginNode := matchReservedWord(lexemes, position, "BEGIN")
-	if _beginNode != nil {
+	_beginToken := match(lexemes, position, lexer.BEGIN)
+	if _beginToken != nil {
 		optionally_matched_log("BEGIN", lexemes, position)
-		moduleNode.Children = append(moduleNode.Children, _beginNode)
-		attempt_log("statementSequence", lexemes, position)
-		_statementSequenceNode, err := statementSequence(lexemes, position)
-		if err != nil {
-			return nil, err
-		}
-		if _statementSequenceNode == nil {
-			did_not_match_log("statementSequence", lexemes, position)
-			return nil, parse_error("statementSequence", lexemes, position)
-		}
-		matched_log("statementSequence", lexemes, position)
-		moduleNode.Children = append(moduleNode.Children, _statementSequenceNode)
+
+		_statements := statements(lexemes, position, lexer.END)
+		moduleNode.Body = _statements
 	} else {
 		did_not_match_optionally_log("BEGIN", lexemes, position)
 	}
 
-	attempt_log("END", lexemes, position)
-	_endNode := matchReservedWord(lexemes, position, "END")
-	if _endNode == nil {
-		did_not_match_log("END", lexemes, position)
-		return nil, parse_error("END", lexemes, position)
-	}
-	matched_log("END", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _endNode)
+	// END
+	expect(lexemes, position, lexer.END, "to close module "+moduleNode.Name.Name)
 
-	attempt_log("ident", lexemes, position)
-	_identNode1 := matchtype(lexemes, position, lexer.IDENT)
-	if _identNode1 == nil {
-		did_not_match_log("ident", lexemes, position)
-		return nil, parse_error("ident", lexemes, position)
-	}
-	matched_log("ident", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _identNode1)
+	// ident
+	moduleNode.EndName = closingName(lexemes, position, "module", moduleNode.Name)
 
-	attempt_log(".", lexemes, position)
-	_dotOperatorNode := matchOperator(lexemes, position, ".")
-	if _dotOperatorNode == nil {
-		did_not_match_log(".", lexemes, position)
-		return nil, parse_error(".", lexemes, position)
-	}
-	matched_log(".", lexemes, position)
-	moduleNode.Children = append(moduleNode.Children, _dotOperatorNode)
+	// .
+	moduleNode.Period = expect(lexemes, position, lexer.PERIOD, "to end the module")
 
 	return moduleNode, nil
 }
 
-func Parser(lexemes *[]lexer.Lexeme, debug bool) (*ParseNode, error) {
+// Parser parses the tokens of file, scanned as dialect (Oberon-07 if nil),
+// into an abstract syntax tree. Syntax errors are reported to reporter
+// and the error returned is the first of them. Unless the file does not
+// start with MODULE, the tree is returned even when there are errors; the
+// parts that could not be parsed are BadExpr, BadStmt and BadDecl nodes.
+// No syntax error is reported at an ILLEGAL token, which the lexer has
+// reported already.
+func Parser(file *source.SourceFile, lexemes *[]lexer.Token, dialect *lexer.Dialect, reporter *diag.Reporter, debug bool) (*ast.Module, error) {
 	logging.SetBackend(parser_log_backend_formatter)
 	parserDebug = debug
+	parserFile = file
+	parserDialect = dialect
+	if parserDialect == nil {
+		parserDialect = lexer.OBERON07
+	}
+	parserErrors = nil
+	parserIllegal = map[int]bool{}
+	for _, token := range *lexemes {
+		if token.Kind == lexer.ILLEGAL {
+			parserIllegal[token.Offset] = true
+		}
+	}
 	var position = 0
 	tree, err := module(lexemes, &position)
 	if err != nil {
+		if diagnostic, ok := err.(diag.Diagnostic); ok && !parserIllegal[diagnostic.Span.Start.Offset] {
+			reporter.Report(diagnostic)
+		}
 		return nil, err
 	}
+	for position < len(*lexemes) && (*lexemes)[position].Kind == lexer.ILLEGAL {
+		position++
+	}
 	if position < len(*lexemes) {
 		unparsedToken := (*lexemes)[position]
-		return nil, fmt.Errorf("parse error: unparsed token: %v at (line: %d, column: %d), token number: %d", unparsedToken.Label, unparsedToken.Line, unparsedToken.Column, position)
+		parserErrors = append(parserErrors, diag.Errorf(TRAILING_TOKENS, span(lexemes, position), "unexpected %s after the end of the module", describe(unparsedToken)).
+			WithNote(span(lexemes, position-1), "the module ends here"))
+	}
+	reporter.Report(parserErrors...)
+	if len(parserErrors) > 0 {
+		return tree, parserErrors[0]
 	}
-	return tree, err
+	return tree, nil
 }
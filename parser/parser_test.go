@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	diag "oberon/diag"
+	lexer "oberon/lexer"
+	source "oberon/source"
+)
+
+// BenchmarkParse parses generated modules of growing size; for a
+// linear-time parser the bytes parsed per second stay about the same.
+func BenchmarkParse(b *testing.B) {
+	for _, procedures := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("procedures=%d", procedures), func(b *testing.B) {
+			file := source.NewSourceFile(fmt.Sprintf("generated_%d.ob", procedures), generate(procedures, 8))
+			lexerResult, err := lexer.Lexer(file, lexer.Options{}, false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(len(file.Contents)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := Parser(file, lexerResult.Tokens, lexer.OBERON07, diag.NewReporter(), false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// generate returns a module with the given number of procedures. The
+// body of each procedure nests IF, WHILE, CASE and FOR statements depth
+// deep, with designators, calls and parenthesized expressions nested as
+// deep in its assignments: the shapes a backtracking parser re-reads the
+// most.
+func generate(procedures int, depth int) []byte {
+	var b strings.Builder
+	b.WriteString("MODULE Generated;\n")
+	b.WriteString("IMPORT Out;\n")
+	b.WriteString("CONST limit = 100;\n")
+	b.WriteString("TYPE\n")
+	b.WriteString("    Node = POINTER TO NodeDesc;\n")
+	b.WriteString("    NodeDesc = RECORD key: INTEGER; next: Node; values: ARRAY 10 OF INTEGER END;\n")
+	b.WriteString("VAR root: Node; total: INTEGER;\n\n")
+	for p := 0; p < procedures; p++ {
+		fmt.Fprintf(&b, "PROCEDURE P%d(n: Node; VAR sum: INTEGER): INTEGER;\n", p)
+		b.WriteString("    VAR i, j: INTEGER;\n")
+		b.WriteString("BEGIN\n")
+		generateStatements(&b, depth, 1)
+		b.WriteString("    RETURN sum\n")
+		fmt.Fprintf(&b, "END P%d;\n\n", p)
+	}
+	b.WriteString("BEGIN\n")
+	b.WriteString("    total := 0;\n")
+	for p := 0; p < procedures; p++ {
+		fmt.Fprintf(&b, "    total := total + P%d(root, total);\n", p)
+	}
+	b.WriteString("    Out.Int(total, 0)\n")
+	b.WriteString("END Generated.\n")
+	return []byte(b.String())
+}
+
+// generateStatements writes the statements of a procedure body, nested
+// depth deep, indented by level.
+func generateStatements(b *strings.Builder, depth int, level int) {
+	var indentation = strings.Repeat("    ", level)
+	fmt.Fprintf(b, "%ssum := sum + %s;\n", indentation, generateExpression(depth))
+	fmt.Fprintf(b, "%s%s := %s;\n", indentation, generateDesignator(depth), generateExpression(depth))
+	fmt.Fprintf(b, "%sOut.Int(%s, 0);\n", indentation, generateExpression(depth))
+	if depth == 0 {
+		return
+	}
+	switch depth % 4 {
+	case 0:
+		fmt.Fprintf(b, "%sIF (i < limit) & ~(n = NIL) THEN\n", indentation)
+		generateStatements(b, depth-1, level+1)
+		fmt.Fprintf(b, "%sELSIF i = j THEN\n", indentation)
+		fmt.Fprintf(b, "%s    i := i + 1\n", indentation)
+		fmt.Fprintf(b, "%sELSE\n", indentation)
+		fmt.Fprintf(b, "%s    j := j - 1\n", indentation)
+		fmt.Fprintf(b, "%sEND;\n", indentation)
+	case 1:
+		fmt.Fprintf(b, "%sWHILE n # NIL DO\n", indentation)
+		generateStatements(b, depth-1, level+1)
+		fmt.Fprintf(b, "%s    n := n.next\n", indentation)
+		fmt.Fprintf(b, "%sEND;\n", indentation)
+	case 2:
+		fmt.Fprintf(b, "%sCASE i OF\n", indentation)
+		fmt.Fprintf(b, "%s  0..9:\n", indentation)
+		generateStatements(b, depth-1, level+1)
+		fmt.Fprintf(b, "%s| 10, 20: i := 0\n", indentation)
+		fmt.Fprintf(b, "%sEND;\n", indentation)
+	case 3:
+		fmt.Fprintf(b, "%sFOR i := 0 TO limit BY 2 DO\n", indentation)
+		generateStatements(b, depth-1, level+1)
+		fmt.Fprintf(b, "%sEND;\n", indentation)
+	}
+}
+
+// generateDesignator returns a designator with depth selectors, each index
+// itself a designator.
+func generateDesignator(depth int) string {
+	var b strings.Builder
+	b.WriteString("n")
+	for d := 0; d < depth; d++ {
+		switch d % 3 {
+		case 0:
+			b.WriteString(".next")
+		case 1:
+			b.WriteString("^")
+		case 2:
+			fmt.Fprintf(&b, ".values[%s]", generateIndex(d))
+		}
+	}
+	b.WriteString(".key")
+	return b.String()
+}
+
+// generateIndex returns an index nested depth deep.
+func generateIndex(depth int) string {
+	if depth == 0 {
+		return "i"
+	}
+	return fmt.Sprintf("n.values[%s]", generateIndex(depth-1))
+}
+
+// generateExpression returns an expression with depth nested parentheses and
+// calls.
+func generateExpression(depth int) string {
+	if depth == 0 {
+		return "i * 2 + j DIV 3 - 1"
+	}
+	if depth%2 == 0 {
+		return fmt.Sprintf("(%s) * (j + %d)", generateExpression(depth-1), depth)
+	}
+	return fmt.Sprintf("P0(n, sum) + (%s)", generateExpression(depth-1))
+}
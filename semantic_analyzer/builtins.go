@@ -0,0 +1,204 @@
+package semantic_analyzer
+
+// parameterClass is what a predeclared procedure, or a procedure of
+// SYSTEM, takes as one argument. Their parameters are not typed like those
+// of declared procedures: ABS takes a number of any numeric type, and
+// SIZE the name of a type.
+type parameterClass int
+
+const (
+	ANY_VALUE parameterClass = iota
+	INTEGER_VALUE
+	NUMERIC_VALUE
+	REAL_VALUE
+	CHAR_VALUE
+	BOOLEAN_VALUE
+	ORDINAL_VALUE
+	ARRAY_VALUE
+	STRING_VALUE
+	ANY_VARIABLE
+	INTEGER_VARIABLE
+	REAL_VARIABLE
+	SET_VARIABLE
+	POINTER_VARIABLE
+	CHAR_ARRAY_VARIABLE
+	TYPE_NAME
+	BASIC_TYPE_NAME
+)
+
+var parameterClassNames = [...]string{
+	ANY_VALUE:           "an expression",
+	INTEGER_VALUE:       "an integer",
+	NUMERIC_VALUE:       "a number",
+	REAL_VALUE:          "a real number",
+	CHAR_VALUE:          "a character",
+	BOOLEAN_VALUE:       "a BOOLEAN",
+	ORDINAL_VALUE:       "a character, BOOLEAN or SET",
+	ARRAY_VALUE:         "an array",
+	STRING_VALUE:        "a string or character array",
+	ANY_VARIABLE:        "a variable",
+	INTEGER_VARIABLE:    "an integer variable",
+	REAL_VARIABLE:       "a REAL variable",
+	SET_VARIABLE:        "a SET variable",
+	POINTER_VARIABLE:    "a pointer variable",
+	CHAR_ARRAY_VARIABLE: "a character array variable",
+	TYPE_NAME:           "a type name",
+	BASIC_TYPE_NAME:     "the name of a basic type",
+}
+
+func (p parameterClass) String() string {
+	return parameterClassNames[p]
+}
+
+// isVariableClass reports whether an argument of class p must be a
+// variable, which the procedure may change.
+func (p parameterClass) isVariableClass() bool {
+	return p >= ANY_VARIABLE && p <= CHAR_ARRAY_VARIABLE
+}
+
+// accepts reports whether an argument of type t is of class p.
+func (p parameterClass) accepts(t Type) bool {
+	if isInvalid(t) {
+		return true
+	}
+	switch p {
+	case INTEGER_VALUE, INTEGER_VARIABLE:
+		return isInteger(t)
+	case NUMERIC_VALUE:
+		return isNumeric(t)
+	case REAL_VALUE, REAL_VARIABLE:
+		return isReal(t)
+	case CHAR_VALUE:
+		return isChar(t)
+	case BOOLEAN_VALUE:
+		return isBoolean(t)
+	case ORDINAL_VALUE:
+		return isChar(t) || isBoolean(t) || isSet(t)
+	case ARRAY_VALUE:
+		_, ok := t.(*Array)
+		return ok
+	case STRING_VALUE:
+		return isString(t)
+	case SET_VARIABLE:
+		return isSet(t)
+	case POINTER_VARIABLE:
+		_, ok := t.(*Pointer)
+		return ok
+	case CHAR_ARRAY_VARIABLE:
+		return isCharArray(t)
+	case BASIC_TYPE_NAME:
+		_, ok := t.(*Basic)
+		return ok
+	}
+	return true
+}
+
+// The results of the predeclared procedures whose result type depends on
+// their arguments.
+const (
+	ARGUMENT_RESULT = "x"     // the type of the first argument
+	TYPE_RESULT     = "T"     // the type named by the first argument
+	LIMIT_RESULT    = "LIMIT" // the type named by the first argument, INTEGER for SET
+	LONGER_RESULT   = "LONG"  // the next larger type than the argument's
+	SHORTER_RESULT  = "SHORT" // the next smaller type than the argument's
+)
+
+// builtin is the signature of a predeclared procedure or a procedure of
+// SYSTEM. The last Optional parameters may be left out. Result is the name
+// of the type of the result, one of the results above, or empty for a
+// proper procedure.
+type builtin struct {
+	Params   []parameterClass
+	Optional int
+	Result   string
+}
+
+// PREDECLARED_PROCEDURES are the signatures of the procedures predeclared
+// in any of the dialects; Dialect.PredeclaredProcedures tells which of
+// them a dialect has.
+var PREDECLARED_PROCEDURES = map[string]builtin{
+	"ABS":    {[]parameterClass{NUMERIC_VALUE}, 0, ARGUMENT_RESULT},
+	"ASH":    {[]parameterClass{INTEGER_VALUE, INTEGER_VALUE}, 0, "LONGINT"},
+	"ASR":    {[]parameterClass{INTEGER_VALUE, INTEGER_VALUE}, 0, "INTEGER"},
+	"ASSERT": {[]parameterClass{BOOLEAN_VALUE, INTEGER_VALUE}, 1, ""},
+	"BITS":   {[]parameterClass{INTEGER_VALUE}, 0, "SET"},
+	"CAP":    {[]parameterClass{CHAR_VALUE}, 0, "CHAR"},
+	"CHR":    {[]parameterClass{INTEGER_VALUE}, 0, "CHAR"},
+	"COPY":   {[]parameterClass{STRING_VALUE, CHAR_ARRAY_VARIABLE}, 0, ""},
+	"DEC":    {[]parameterClass{INTEGER_VARIABLE, INTEGER_VALUE}, 1, ""},
+	"ENTIER": {[]parameterClass{REAL_VALUE}, 0, "LONGINT"},
+	"EXCL":   {[]parameterClass{SET_VARIABLE, INTEGER_VALUE}, 0, ""},
+	"FLOOR":  {[]parameterClass{REAL_VALUE}, 0, "INTEGER"},
+	"FLT":    {[]parameterClass{INTEGER_VALUE}, 0, "REAL"},
+	"HALT":   {[]parameterClass{INTEGER_VALUE}, 0, ""},
+	"INC":    {[]parameterClass{INTEGER_VARIABLE, INTEGER_VALUE}, 1, ""},
+	"INCL":   {[]parameterClass{SET_VARIABLE, INTEGER_VALUE}, 0, ""},
+	"LEN":    {[]parameterClass{ARRAY_VALUE, INTEGER_VALUE}, 1, "INTEGER"},
+	"LONG":   {[]parameterClass{NUMERIC_VALUE}, 0, LONGER_RESULT},
+	"LSL":    {[]parameterClass{INTEGER_VALUE, INTEGER_VALUE}, 0, "INTEGER"},
+	"MAX":    {[]parameterClass{BASIC_TYPE_NAME}, 0, LIMIT_RESULT},
+	"MIN":    {[]parameterClass{BASIC_TYPE_NAME}, 0, LIMIT_RESULT},
+	"NEW":    {[]parameterClass{POINTER_VARIABLE, INTEGER_VALUE}, 1, ""},
+	"ODD":    {[]parameterClass{INTEGER_VALUE}, 0, "BOOLEAN"},
+	"ORD":    {[]parameterClass{ORDINAL_VALUE}, 0, "INTEGER"},
+	"PACK":   {[]parameterClass{REAL_VARIABLE, INTEGER_VALUE}, 0, ""},
+	"ROR":    {[]parameterClass{INTEGER_VALUE, INTEGER_VALUE}, 0, "INTEGER"},
+	"SHORT":  {[]parameterClass{NUMERIC_VALUE}, 0, SHORTER_RESULT},
+	"SIZE":   {[]parameterClass{TYPE_NAME}, 0, "INTEGER"},
+	"UNPK":   {[]parameterClass{REAL_VARIABLE, INTEGER_VARIABLE}, 0, ""},
+}
+
+// newSignature returns the signature of NEW(v, ...) for v of type t, in a
+// call with count arguments: v is followed by a length for each open
+// dimension of the array t points to, if any. The lengths are not counted
+// if t is not known to be a pointer.
+func newSignature(t Type, count int) builtin {
+	var params = []parameterClass{POINTER_VARIABLE}
+	if pointer, ok := t.(*Pointer); ok {
+		for array, ok := pointer.Base.(*Array); ok && array.Len == OPEN_ARRAY; array, ok = array.Elem.(*Array) {
+			params = append(params, INTEGER_VALUE)
+		}
+		return builtin{params, 0, ""}
+	}
+	for len(params) < count {
+		params = append(params, INTEGER_VALUE)
+	}
+	return builtin{params, 0, ""}
+}
+
+// builtinResult returns the type of the result of a call of procedure
+// with arguments of types args, or nil for a proper procedure.
+func builtinResult(procedure builtin, args []Type) Type {
+	var first Type = BASIC_TYPES[INVALID]
+	if len(args) > 0 {
+		first = args[0]
+	}
+	switch procedure.Result {
+	case "":
+		return nil
+	case ARGUMENT_RESULT, TYPE_RESULT:
+		return first
+	case LIMIT_RESULT:
+		// MAX(SET) and MIN(SET) are the largest and smallest element.
+		if isSet(first) {
+			return BASIC_TYPES[INTEGER]
+		}
+		return first
+	case LONGER_RESULT, SHORTER_RESULT:
+		b, ok := first.(*Basic)
+		if !ok {
+			return BASIC_TYPES[INVALID]
+		}
+		var step = 1
+		if procedure.Result == SHORTER_RESULT {
+			step = -1
+		}
+		for kind, rank := range NUMERIC_RANKS {
+			if rank == NUMERIC_RANKS[b.Kind]+step && isInteger(BASIC_TYPES[kind]) == isInteger(b) {
+				return BASIC_TYPES[kind]
+			}
+		}
+		return b
+	}
+	return PREDECLARED_TYPES[procedure.Result]
+}
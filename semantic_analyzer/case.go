@@ -0,0 +1,285 @@
+package semantic_analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	ast "oberon/ast"
+	diag "oberon/diag"
+	lexer "oberon/lexer"
+	source "oberon/source"
+)
+
+// caseRange is a label of a CASE on an integer or a character: the
+// values from low to high.
+type caseRange struct {
+	label     ast.Expr
+	low, high int64
+}
+
+// caseType is a label of a CASE on the type of a variable.
+type caseType struct {
+	label ast.Expr
+	typ   Type
+}
+
+// labelValue returns n, a value of type t, a character or an integer.
+func labelValue(t Type, n int64) Value {
+	if isChar(t) {
+		return CharValue(n)
+	}
+	return IntegerValue(n)
+}
+
+// caseStatement checks CaseStatement = CASE expression OF case {"|" case}
+// END. The expression is an integer or a character and the labels are
+// constants of its type, no two of which cover the same value; or the
+// expression is a pointer or a VAR parameter of record type and the
+// labels are types, as in typeCase.
+func (c *checker) caseStatement(stmt *ast.CaseStmt) {
+	x := c.value(stmt.X)
+	if recordOf(x.typ) != nil {
+		c.typeCase(stmt, x)
+		return
+	}
+	if !isInteger(x.typ) && !isChar(x.typ) && !isInvalid(x.typ) {
+		c.report(diag.Errorf(INVALID_OPERATION, span(stmt.X), "the expression of CASE must be an integer, a character or a pointer to a record, not %s", x.typ))
+		x = invalidOperand
+	}
+	var ranges []caseRange
+	var known = !isInvalid(x.typ)
+	var t, like = x.typ, ast.Expr(stmt.X)
+	if !known {
+		// The labels are still checked against the first of them.
+		t, like = labelType(stmt)
+	}
+	for _, clause := range stmt.Clauses {
+		for _, label := range clause.Labels {
+			r, ok := c.labelRange(stmt, label, t, like)
+			if !ok {
+				known = false
+				continue
+			}
+			c.overlap(t, r, ranges)
+			ranges = append(ranges, r)
+		}
+		c.statements(clause.Body)
+	}
+	c.statements(stmt.Else)
+	if c.warnIncompleteCase && known && stmt.Else == nil {
+		c.coverage(stmt, x.typ, ranges)
+	}
+}
+
+// labelType returns the type of the first label of stmt that is an
+// integer or a character literal, and the label, for a CASE whose
+// expression is in error.
+func labelType(stmt *ast.CaseStmt) (Type, ast.Expr) {
+	for _, clause := range stmt.Clauses {
+		for _, label := range clause.Labels {
+			if rangeExpr, isRange := label.(*ast.RangeExpr); isRange {
+				label = rangeExpr.Low
+			}
+			literal, ok := label.(*ast.BasicLit)
+			switch {
+			case !ok:
+			case literal.Kind == lexer.INTEGER:
+				return BASIC_TYPES[INTEGER], label
+			case literal.Kind == lexer.CHAR, literal.Kind == lexer.STRING && utf8.RuneCountInString(literal.StrValue) == 1:
+				return BASIC_TYPES[CHAR], label
+			}
+		}
+	}
+	return BASIC_TYPES[INVALID], nil
+}
+
+// labelRange checks LabelRange = label [".." label] of stmt, a CASE on an
+// expression of type t, and returns the values it covers. ok is false if
+// they are not known. The labels must be of type t like like, the
+// expression of stmt or its first label.
+func (c *checker) labelRange(stmt *ast.CaseStmt, label ast.Expr, t Type, like ast.Expr) (r caseRange, ok bool) {
+	low, high := label, label
+	if rangeExpr, isRange := label.(*ast.RangeExpr); isRange {
+		low, high = rangeExpr.Low, rangeExpr.High
+	}
+	a, lowKnown := c.label(low, t, like)
+	b, highKnown := a, lowKnown
+	if high != low {
+		b, highKnown = c.label(high, t, like)
+	}
+	if !lowKnown || !highKnown {
+		return caseRange{}, false
+	}
+	if a > b {
+		c.report(diag.Errorf(EMPTY_LABEL_RANGE, span(label), "the label range %s is empty: %s is greater than %s", text(label), labelValue(t, a), labelValue(t, b)))
+		return caseRange{}, false
+	}
+	return caseRange{label, a, b}, true
+}
+
+// label returns the value of label = integer | string | character |
+// qualident, a constant of type t, the type of like.
+func (c *checker) label(label ast.Expr, t Type, like ast.Expr) (int64, bool) {
+	y := c.constExpression(label, "a CASE label")
+	if y.mode != CONSTANT || y.val == nil || isInvalid(t) {
+		return 0, false
+	}
+	var kind = "an integer"
+	if isChar(t) {
+		kind = "a character"
+		if ch, ok := charOf(y.val); ok {
+			return int64(ch), true
+		}
+	} else if n, ok := y.val.(IntegerValue); ok {
+		return int64(n), true
+	}
+	c.report(diag.Errorf(CASE_LABEL_MISMATCH, span(label), "the CASE label %s must be %s like %s, not %s", text(label), kind, text(like), y.typ))
+	return 0, false
+}
+
+// overlap checks that r, a label of a CASE on an expression of type t,
+// covers none of the values of the labels before it.
+func (c *checker) overlap(t Type, r caseRange, ranges []caseRange) {
+	for _, previous := range ranges {
+		if r.low > previous.high || previous.low > r.high {
+			continue
+		}
+		var d diag.Diagnostic
+		if text(r.label) == text(previous.label) {
+			d = diag.Errorf(OVERLAPPING_LABELS, span(r.label), "the CASE label %s is repeated", text(r.label))
+		} else {
+			var common = r.low
+			if previous.low > common {
+				common = previous.low
+			}
+			d = diag.Errorf(OVERLAPPING_LABELS, span(r.label), "the CASE labels %s and %s both cover %s", text(previous.label), text(r.label), labelValue(t, common))
+		}
+		c.report(d.WithNote(span(previous.label), "%s is a label here", text(previous.label)))
+		return
+	}
+}
+
+// coverage warns of the values of type t that ranges, the labels of
+// stmt, leave out: the first of them.
+func (c *checker) coverage(stmt *ast.CaseStmt, t Type, ranges []caseRange) {
+	var low, high int64 = math.MinInt64, math.MaxInt64
+	if isChar(t) {
+		low, high = 0, lexer.MAX_CHAR
+	}
+	var note = "a CASE statement stops the program when no label covers the value of its expression"
+	if len(ranges) == 0 {
+		c.report(diag.Warningf(INCOMPLETE_CASE, span(stmt.X), "CASE has no labels: it traps whatever the value of %s", text(stmt.X)).
+			WithNote(source.Span{}, note))
+		return
+	}
+	var sorted = append([]caseRange(nil), ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].low < sorted[j].low })
+	var next = low // the least value not known to be covered
+	var missing string
+	for _, r := range sorted {
+		if r.low > next {
+			missing = caseValues(t, next, r.low-1, low, high)
+			break
+		}
+		if r.high >= high {
+			return
+		}
+		if r.high >= next {
+			next = r.high + 1
+		}
+	}
+	if missing == "" {
+		missing = caseValues(t, next, high, low, high)
+	}
+	c.report(diag.Warningf(INCOMPLETE_CASE, span(stmt.X), "CASE traps if %s is %s: no label covers it", text(stmt.X), missing).
+		WithNote(source.Span{}, note))
+}
+
+// caseValues describes the values of type t from a to b, which are between
+// low and high, the least and the largest value of t.
+func caseValues(t Type, a, b, low, high int64) string {
+	switch {
+	case a == b:
+		return labelValue(t, a).String()
+	case a == low && isInteger(t):
+		return fmt.Sprintf("less than %s", labelValue(t, b+1))
+	case b == high && isInteger(t):
+		return fmt.Sprintf("greater than %s", labelValue(t, a-1))
+	}
+	return fmt.Sprintf("%s..%s", labelValue(t, a), labelValue(t, b))
+}
+
+// typeCase checks a CASE on x, a pointer or a VAR parameter of record
+// type. Its labels are types that extend the type of x, which x has in the
+// statements of a case with a single label. The labels are tested in
+// order, so a label that extends an earlier one is never selected.
+func (c *checker) typeCase(stmt *ast.CaseStmt, x operand) {
+	var dynamic = c.dynamic(stmt.X, x)
+	var types []caseType
+	var covered bool
+	for _, clause := range stmt.Clauses {
+		var guard Type = BASIC_TYPES[INVALID]
+		for _, label := range clause.Labels {
+			t := c.typeLabel(stmt, label)
+			if !dynamic || isInvalid(t) {
+				continue
+			}
+			if !isExtension(t, x.typ) {
+				c.report(diag.Errorf(NOT_AN_EXTENSION, span(label), "%s is not an extension of %s, the type of %s", t, x.typ, text(stmt.X)))
+				continue
+			}
+			c.typeOverlap(caseType{label, t}, types)
+			c.typeCheck(label, TYPE_TEST, t)
+			types = append(types, caseType{label, t})
+			covered = covered || t == x.typ
+			guard = t
+		}
+		if len(clause.Labels) == 1 {
+			c.guarded(x.object, guard, clause.Body)
+		} else {
+			c.statements(clause.Body)
+		}
+	}
+	c.statements(stmt.Else)
+	if c.warnIncompleteCase && dynamic && !covered && stmt.Else == nil {
+		c.report(diag.Warningf(INCOMPLETE_CASE, span(stmt.X), "CASE traps if %s is of type %s: no label covers it", text(stmt.X), x.typ).
+			WithNote(source.Span{}, "a CASE statement stops the program when no label covers the type of its expression"))
+	}
+}
+
+// typeLabel returns the type label names in stmt, a CASE on a type.
+func (c *checker) typeLabel(stmt *ast.CaseStmt, label ast.Expr) Type {
+	if r, ok := label.(*ast.RangeExpr); ok {
+		c.expr(r.Low)
+		c.expr(r.High)
+		c.report(diag.Errorf(CASE_LABEL_MISMATCH, span(label), "the CASE on the type of %s takes types as labels, not ranges", text(stmt.X)))
+		return BASIC_TYPES[INVALID]
+	}
+	y := c.expr(label)
+	if y.mode == TYPE_OPERAND {
+		return y.typ
+	}
+	if y.mode != IMPORTED && !isInvalid(y.typ) {
+		c.report(diag.Errorf(NOT_A_TYPE, span(label), "the CASE label %s must be a type: the CASE tests the type of %s", text(label), text(stmt.X)))
+	}
+	return BASIC_TYPES[INVALID]
+}
+
+// typeOverlap checks that l, a label of a CASE on a type, can be
+// selected: that no label before it is the same type or a base type.
+func (c *checker) typeOverlap(l caseType, types []caseType) {
+	for _, previous := range types {
+		var d diag.Diagnostic
+		if l.typ == previous.typ {
+			d = diag.Errorf(OVERLAPPING_LABELS, span(l.label), "the CASE label %s is repeated", text(l.label))
+		} else if isExtension(l.typ, previous.typ) {
+			d = diag.Errorf(OVERLAPPING_LABELS, span(l.label), "the CASE label %s is never selected: it extends %s, an earlier label", text(l.label), text(previous.label))
+		} else {
+			continue
+		}
+		c.report(d.WithNote(span(previous.label), "%s is a label here", text(previous.label)))
+		return
+	}
+}
@@ -0,0 +1,1365 @@
+package semantic_analyzer
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	ast "oberon/ast"
+	diag "oberon/diag"
+	lexer "oberon/lexer"
+	source "oberon/source"
+)
+
+// operandMode is what an expression denotes.
+type operandMode int
+
+const (
+	NO_VALUE       operandMode = iota // the call of a proper procedure
+	VALUE                             // a value computed at run time
+	CONSTANT                          // a value known to the compiler
+	VARIABLE                          // a variable, which may be assigned
+	READ_ONLY                         // a variable that may not be assigned
+	TYPE_OPERAND                      // the name of a type
+	BUILTIN                           // a predeclared procedure or a procedure of SYSTEM
+	MODULE_OPERAND                    // an imported module
+	IMPORTED                          // an object of another module, whose type is unknown
+)
+
+// operand is an expression as the checker sees it. object is the object
+// a designator denotes, builtin the signature of a predeclared procedure
+// and val the value of a constant.
+type operand struct {
+	mode    operandMode
+	typ     Type
+	object  *Object
+	builtin *builtin
+	val     Value
+}
+
+var invalidOperand = operand{mode: VALUE, typ: BASIC_TYPES[INVALID]}
+
+// checker computes the type of each declaration and expression of a
+// module and checks them against the rules of the dialect. The types of
+// the imported modules are not known, so objects of other modules pass
+// every check.
+type checker struct {
+	tree     *AnnotatedTree
+	dialect  *lexer.Dialect
+	reporter *diag.Reporter
+	building map[*Object]bool // the type declarations being checked
+	narrowed map[*Object]Type // the variables a WITH clause guards
+	result   Type             // the result type of the procedure checked
+	name     string           // the name of the procedure checked
+
+	records     []*Record // the record types of the module, in the order of their declaration
+	descriptors map[*Record]*TypeDescriptor
+
+	warnIncompleteCase bool
+}
+
+// text returns the source of node, for messages.
+func text(node ast.Node) string {
+	return string(analyzerFile.Contents[node.Pos():node.End()])
+}
+
+// span returns the span of node in the file analyzed.
+func span(node ast.Node) source.Span {
+	return ast.Span(analyzerFile, node)
+}
+
+func (c *checker) report(d diag.Diagnostic) {
+	c.reporter.Report(d)
+}
+
+// prefix is the part of a designator up to one of its selectors.
+type prefix struct {
+	from, to int
+}
+
+func (p prefix) Pos() int { return p.from }
+func (p prefix) End() int { return p.to }
+
+// record records the type of expression e, and its value if it is a
+// constant.
+func (c *checker) record(e ast.Expr, x operand) operand {
+	if e != nil && x.typ != nil {
+		c.tree.Types[e] = x.typ
+	}
+	if e != nil && x.mode == CONSTANT && x.val != nil {
+		c.tree.Values[e] = x.val
+	}
+	return x
+}
+
+// constant returns the operand of e, a constant expression of type t
+// whose value is v, or reports err, an error in its evaluation.
+func (c *checker) constant(e ast.Expr, t Type, v Value, err error) operand {
+	if err != nil {
+		c.report(diag.Errorf(err.(*constantError).code, span(e), "%s", err))
+		return invalidOperand
+	}
+	return operand{mode: CONSTANT, typ: t, val: v}
+}
+
+// constExpression returns the operand of e, which must be a constant
+// expression: what e is tells in the message if it is not.
+func (c *checker) constExpression(e ast.Expr, what string) operand {
+	x := c.value(e)
+	if x.mode != CONSTANT && x.mode != IMPORTED && !isInvalid(x.typ) {
+		c.report(diag.Errorf(NOT_CONSTANT, span(e), "%s must be a constant expression", what))
+		return invalidOperand
+	}
+	return x
+}
+
+// objectType returns the type of object, checking its declaration first
+// if it is a type declared later, as the base type of a pointer may be.
+func (c *checker) objectType(object *Object) Type {
+	if object.Type != nil {
+		return object.Type
+	}
+	decl, ok := object.Decl.(*ast.TypeDecl)
+	if object.Kind != TYPE_OBJECT || !ok {
+		return BASIC_TYPES[INVALID]
+	}
+	if c.building[object] {
+		c.report(diag.Errorf(INVALID_TYPE, span(object.Ident), "type %s is declared in terms of itself", object.Name))
+		object.Type = BASIC_TYPES[INVALID]
+		return object.Type
+	}
+	c.building[object] = true
+	t := c.typeExpr(decl.Type, object)
+	delete(c.building, object)
+	if object.Type == nil {
+		object.Type = t
+	}
+	name(object.Type, object.Name)
+	return object.Type
+}
+
+// typeName returns the type qualident names.
+func (c *checker) typeName(qualident *ast.QualIdent) Type {
+	x := c.qualident(qualident)
+	switch x.mode {
+	case TYPE_OPERAND:
+		return c.record(qualident, x).typ
+	case IMPORTED:
+		return BASIC_TYPES[INVALID]
+	}
+	if !isInvalid(x.typ) || x.object != nil {
+		c.report(diag.Errorf(NOT_A_TYPE, span(qualident), "%s is not a type", text(qualident)))
+	}
+	return BASIC_TYPES[INVALID]
+}
+
+// typeExpr returns the type node denotes. A record or pointer type
+// declared by named is named before its components are checked, so that
+// they may refer to it.
+func (c *checker) typeExpr(node ast.Type, named *Object) Type {
+	switch node := node.(type) {
+	case *ast.QualIdent:
+		return c.typeName(node)
+	case *ast.ArrayType:
+		var t = c.typeExpr(node.Elem, nil)
+		if len(node.Lengths) == 0 {
+			return &Array{Len: OPEN_ARRAY, Elem: t}
+		}
+		for index := len(node.Lengths) - 1; index >= 0; index-- {
+			t = &Array{Len: c.length(node.Lengths[index]), Elem: t}
+		}
+		return t
+	case *ast.RecordType:
+		return c.recordType(node, named)
+	case *ast.PointerType:
+		var pointer = &Pointer{}
+		if named != nil {
+			named.Type = pointer
+			pointer.name = named.Name
+		}
+		pointer.Base = c.typeExpr(node.Base, nil)
+		switch pointer.Base.(type) {
+		case *Record, *Array:
+		default:
+			if !isInvalid(pointer.Base) {
+				c.report(diag.Errorf(INVALID_TYPE, span(node.Base), "a pointer must point to a record or an array, not to %s", pointer.Base))
+				pointer.Base = BASIC_TYPES[INVALID]
+			}
+		}
+		return pointer
+	case *ast.ProcedureType:
+		return c.signature(node.Params)
+	}
+	return BASIC_TYPES[INVALID]
+}
+
+// length returns the length of an array type, which must be a positive
+// integer constant.
+func (c *checker) length(e ast.Expr) int64 {
+	x := c.constExpression(e, "the length of an array")
+	if isInvalid(x.typ) {
+		return UNKNOWN_LENGTH
+	}
+	if !isInteger(x.typ) {
+		c.report(diag.Errorf(INVALID_TYPE, span(e), "the length of an array must be an integer, not %s", x.typ))
+		return UNKNOWN_LENGTH
+	}
+	if length := x.val.(IntegerValue); length <= 0 {
+		c.report(diag.Errorf(OUT_OF_RANGE, span(e), "the length of an array must be positive, not %s", length))
+		return UNKNOWN_LENGTH
+	}
+	return int64(x.val.(IntegerValue))
+}
+
+// recordType checks RecordType = RECORD ["(" BaseType ")"] [FieldListSequence] END.
+// A field may not have the name of a field of the base type.
+func (c *checker) recordType(node *ast.RecordType, named *Object) Type {
+	var record = newRecord()
+	record.Attr = node.Attr
+	c.records = append(c.records, record)
+	if fields := c.tree.Scopes[node]; fields != nil {
+		record.Fields = fields
+	}
+	if named != nil {
+		named.Type = record
+		record.name = named.Name
+	}
+	if node.Base != nil {
+		base := c.typeName(node.Base)
+		record.Base = recordOf(base)
+		if record.Base == nil && !isInvalid(base) {
+			c.report(diag.Errorf(INVALID_TYPE, span(node.Base), "a record can only extend a record type, not %s", base))
+		} else if record.Base != nil && record.Base.Extends(record) {
+			// The base type is declared in terms of the record, as in
+			// T = RECORD (T) END: no extension chain may be a cycle.
+			if record.Base == record {
+				c.report(diag.Errorf(INVALID_TYPE, span(node.Base), "%s cannot extend itself", record))
+			} else {
+				c.report(diag.Errorf(INVALID_TYPE, span(node.Base), "%s cannot extend %s, which is an extension of it", record, record.Base))
+			}
+			record.Base = nil
+		} else if record.Base != nil && c.dialect.Has(lexer.RECORD_ATTRIBUTES) {
+			switch record.Base.Attr {
+			case lexer.ABSTRACT, lexer.EXTENSIBLE, lexer.LIMITED:
+			default:
+				c.report(diag.Errorf(INVALID_TYPE, span(node.Base), "%s cannot be extended: only ABSTRACT, EXTENSIBLE and LIMITED records can", record.Base))
+			}
+		}
+	}
+	for _, field := range node.Fields {
+		t := c.typeExpr(field.Type, nil)
+		for _, name := range field.Names {
+			object := c.tree.Defs[name.Name]
+			if object == nil {
+				continue
+			}
+			object.Type = t
+			if record.Base == nil {
+				continue
+			}
+			if inherited := record.Base.Field(object.Name); inherited != nil {
+				c.report(diag.Errorf(REDECLARED_IDENTIFIER, span(name.Name), "%s redeclares a %s of the base type %s", object.Name, inherited.Kind, record.Base).
+					WithNote(span(inherited.Ident), "%s is first declared here", object.Name))
+			}
+		}
+	}
+	return record
+}
+
+// signature returns the procedure type of FormalParameters =
+// "(" [FPSection {";" FPSection}] ")" [":" qualident].
+func (c *checker) signature(params *ast.FormalParameters) *Procedure {
+	var procedure = &Procedure{}
+	if params == nil {
+		return procedure
+	}
+	for _, section := range params.Sections {
+		t := c.typeExpr(section.Type, nil)
+		for _, name := range section.Names {
+			procedure.Params = append(procedure.Params, &Param{Name: name.Name, Mode: section.Mode, Type: t})
+		}
+	}
+	if params.Result != nil {
+		procedure.Result = c.typeName(params.Result)
+		switch procedure.Result.(type) {
+		case *Record, *Array:
+			c.report(diag.Errorf(INVALID_TYPE, span(params.Result), "a procedure cannot return %s: its result must not be a record or an array", procedure.Result))
+			procedure.Result = BASIC_TYPES[INVALID]
+		}
+	}
+	return procedure
+}
+
+// heading gives the procedure declared by decl, and its parameters, their
+// types. A type-bound procedure is bound to the record type of its
+// receiver.
+func (c *checker) heading(decl *ast.ProcDecl) {
+	var procedure = c.signature(decl.Params)
+	var index = 0
+	if decl.Params != nil {
+		for _, section := range decl.Params.Sections {
+			for _, name := range section.Names {
+				if object := c.tree.Defs[name]; object != nil {
+					object.Type = procedure.Params[index].Type
+				}
+				index++
+			}
+		}
+	}
+	var object = c.tree.Defs[decl.Name.Name]
+	if object == nil {
+		return
+	}
+	object.Type = procedure
+	if decl.Receiver != nil {
+		c.receiver(decl.Receiver, object)
+	}
+}
+
+// receiver checks Receiver = "(" [VAR] ident ":" ident ")": a record
+// receiver is a VAR or IN parameter, a pointer receiver a value.
+func (c *checker) receiver(receiver *ast.Receiver, method *Object) {
+	var t Type = BASIC_TYPES[INVALID]
+	if object := c.tree.Uses[receiver.Type]; object != nil {
+		if object.Kind == TYPE_OBJECT {
+			t = c.objectType(object)
+		} else {
+			c.report(diag.Errorf(NOT_A_TYPE, span(receiver.Type), "%s is not a type", receiver.Type.Name))
+		}
+	}
+	if object := c.tree.Defs[receiver.Name]; object != nil {
+		object.Type = t
+	}
+	var record = recordOf(t)
+	if record == nil {
+		if !isInvalid(t) {
+			c.report(diag.Errorf(INVALID_TYPE, span(receiver.Type), "a receiver must be a record or a pointer to a record, not %s", t))
+		}
+		return
+	}
+	switch t.(type) {
+	case *Record:
+		if receiver.Mode == lexer.ILLEGAL {
+			c.report(diag.Errorf(INVALID_TYPE, span(receiver), "a receiver of record type %s must be a VAR parameter", t))
+		}
+	case *Pointer:
+		if receiver.Mode != lexer.ILLEGAL {
+			c.report(diag.Errorf(INVALID_TYPE, span(receiver), "a receiver of pointer type %s must be a value parameter", t))
+		}
+	}
+	if previous := record.Methods.Insert(method); previous != nil {
+		c.report(diag.Errorf(REDECLARED_IDENTIFIER, span(method.Ident), "%s is already bound to %s", method.Name, record).
+			WithNote(span(previous.Ident), "%s is first bound here", method.Name))
+	} else if field := record.Fields.Lookup(method.Name); field != nil {
+		c.report(diag.Errorf(REDECLARED_IDENTIFIER, span(method.Ident), "%s is a field of %s", method.Name, record).
+			WithNote(span(field.Ident), "%s is declared here", method.Name))
+	}
+}
+
+// block checks a declaration sequence and the statements that follow.
+// The procedure headings are checked before the procedure bodies, so a
+// body may call the type-bound procedures declared after it.
+func (c *checker) block(decls []ast.Decl, body []ast.Stmt) {
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.ConstDecl:
+			x := c.constExpression(decl.Value, "the value of a constant")
+			if object := c.tree.Defs[decl.Name.Name]; object != nil {
+				object.Type, object.Value = x.typ, x.val
+			}
+		case *ast.TypeDecl:
+			if object := c.tree.Defs[decl.Name.Name]; object != nil {
+				c.objectType(object)
+			}
+		case *ast.VarDecl:
+			t := c.typeExpr(decl.Type, nil)
+			for _, name := range decl.Names {
+				if object := c.tree.Defs[name.Name]; object != nil {
+					object.Type = t
+				}
+			}
+		case *ast.ProcDecl:
+			c.heading(decl)
+		}
+	}
+	for _, decl := range decls {
+		if procedure, ok := decl.(*ast.ProcDecl); ok {
+			c.body(procedure)
+		}
+	}
+	c.statements(body)
+}
+
+// body checks the declarations and statements of procedure, and the
+// expression it returns against its result type.
+func (c *checker) body(procedure *ast.ProcDecl) {
+	var result, name = c.result, c.name
+	c.result, c.name = nil, procedure.Name.Name.Name
+	if object := c.tree.Defs[procedure.Name.Name]; object != nil {
+		if t, ok := object.Type.(*Procedure); ok {
+			c.result = t.Result
+		}
+	}
+	c.block(procedure.Decls, procedure.Body)
+	if procedure.Return != nil {
+		c.returnValue(procedure.Return)
+	}
+	c.result, c.name = result, name
+}
+
+// returnValue checks e, an expression the procedure checked returns,
+// against its result type.
+func (c *checker) returnValue(e ast.Expr) {
+	x := c.value(e)
+	if c.result != nil && !c.assignable(c.result, x) {
+		c.report(diag.Errorf(INCOMPATIBLE_ASSIGNMENT, span(e), "cannot return %s from %s, whose result is of type %s", x.typ, c.name, c.result))
+	} else if c.result != nil {
+		c.localProcedure(e, c.result, x)
+	}
+}
+
+func (c *checker) statements(list []ast.Stmt) {
+	for _, stmt := range list {
+		c.statement(stmt)
+	}
+}
+
+// condition checks that e, the condition of keyword, is a BOOLEAN.
+func (c *checker) condition(e ast.Expr, keyword string) {
+	if x := c.value(e); !isInvalid(x.typ) && !isBoolean(x.typ) {
+		c.report(diag.Errorf(INVALID_OPERATION, span(e), "the condition of %s must be a BOOLEAN, not %s", keyword, x.typ))
+	}
+}
+
+func (c *checker) elsifs(elsifs []*ast.Elsif) {
+	for _, elsif := range elsifs {
+		c.condition(elsif.Cond, "ELSIF")
+		c.statements(elsif.Body)
+	}
+}
+
+func (c *checker) statement(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.AssignStmt:
+		c.assignment(stmt)
+	case *ast.CallStmt:
+		c.call(stmt.Call, true)
+	case *ast.IfStmt:
+		c.condition(stmt.Cond, "IF")
+		c.statements(stmt.Body)
+		c.elsifs(stmt.Elsifs)
+		c.statements(stmt.Else)
+	case *ast.CaseStmt:
+		c.caseStatement(stmt)
+	case *ast.WhileStmt:
+		c.condition(stmt.Cond, "WHILE")
+		c.statements(stmt.Body)
+		c.elsifs(stmt.Elsifs)
+	case *ast.RepeatStmt:
+		c.statements(stmt.Body)
+		c.condition(stmt.Cond, "UNTIL")
+	case *ast.ForStmt:
+		c.forStatement(stmt)
+	case *ast.WithStmt:
+		for _, clause := range stmt.Clauses {
+			c.withClause(clause)
+		}
+		c.statements(stmt.Else)
+	case *ast.LoopStmt:
+		c.statements(stmt.Body)
+	case *ast.ReturnStmt:
+		if stmt.X != nil {
+			c.returnValue(stmt.X)
+		}
+	}
+}
+
+// assignment checks assignment = designator ":=" expression.
+func (c *checker) assignment(stmt *ast.AssignStmt) {
+	lhs := c.expr(stmt.Lhs)
+	rhs := c.value(stmt.Rhs)
+	if !c.variable(stmt.Lhs, lhs, "assign to") {
+		return
+	}
+	if !c.assignable(lhs.typ, rhs) {
+		d := diag.Errorf(INCOMPATIBLE_ASSIGNMENT, span(stmt.Rhs), "cannot assign %s to %s of type %s", rhs.typ, text(stmt.Lhs), lhs.typ)
+		if isInteger(lhs.typ) && isReal(rhs.typ) {
+			d = d.WithNote(source.Span{}, "FLOOR converts a REAL to an INTEGER")
+		} else if isReal(lhs.typ) && isInteger(rhs.typ) {
+			d = d.WithNote(source.Span{}, "FLT converts an INTEGER to a REAL")
+		}
+		c.report(d)
+		return
+	}
+	c.localProcedure(stmt.Rhs, lhs.typ, rhs)
+	if _, ok := lhs.typ.(*Record); ok && isVarParameter(lhs.object) {
+		// The variable may be of an extension of its type, whose other
+		// fields the assignment would leave as they are.
+		c.typeCheck(stmt.Lhs, EXACT_TYPE, lhs.typ)
+	}
+}
+
+// variable reports whether x, the operand of e, is a variable, and
+// reports it if not: what names what is done with it.
+func (c *checker) variable(e ast.Node, x operand, what string) bool {
+	switch x.mode {
+	case VARIABLE, IMPORTED:
+		return true
+	case READ_ONLY:
+		c.report(diag.Errorf(NOT_A_VARIABLE, span(e), "cannot %s %s: it is read-only", what, text(e)))
+		return false
+	}
+	if !isInvalid(x.typ) || x.object != nil {
+		c.report(diag.Errorf(NOT_A_VARIABLE, span(e), "cannot %s %s: it is not a variable", what, text(e)))
+	}
+	return false
+}
+
+// assignable reports whether x can be assigned to a variable of type t.
+// An integer constant may be assigned to a variable of any integer type.
+func (c *checker) assignable(t Type, x operand) bool {
+	if x.mode == IMPORTED {
+		return true
+	}
+	if x.mode == CONSTANT && (isInteger(t) && isInteger(x.typ) || isReal(t) && isReal(x.typ)) {
+		return true
+	}
+	return assignable(c.dialect, t, x.typ)
+}
+
+// localProcedure reports x, the value of e given to a variable of type t,
+// if it is a procedure declared local to another procedure: only one
+// declared at module level may be the value of a procedure variable.
+func (c *checker) localProcedure(e ast.Expr, t Type, x operand) {
+	object := x.object
+	if _, ok := t.(*Procedure); !ok || object == nil || object.Kind != PROC_OBJECT || object.IsPredeclared() || isMethod(object) || object.Scope == c.tree.Scope {
+		return
+	}
+	c.report(diag.Errorf(PROCEDURE_USE, span(e), "%s is local to a procedure and cannot be the value of a procedure variable", text(e)).
+		WithNote(span(object.Ident), "%s is declared here", object.Name))
+}
+
+// forStatement checks ForStatement = FOR ident ":=" expression TO
+// expression [BY ConstExpression] DO StatementSequence END. The control
+// variable and the bounds are integers.
+func (c *checker) forStatement(stmt *ast.ForStmt) {
+	var t Type = BASIC_TYPES[INVALID]
+	if object := c.tree.Uses[stmt.Var]; object != nil {
+		x := c.record(stmt.Var, c.object(object))
+		if c.variable(stmt.Var, x, "count with") {
+			t = x.typ
+			if !isInteger(t) && !isInvalid(t) {
+				c.report(diag.Errorf(INVALID_OPERATION, span(stmt.Var), "the control variable %s must be an integer, not %s", stmt.Var.Name, t))
+			}
+		}
+	}
+	for _, e := range []ast.Expr{stmt.Low, stmt.High, stmt.By} {
+		if e == nil {
+			continue
+		}
+		var x operand
+		if e == stmt.By {
+			x = c.constExpression(e, "the step of FOR")
+		} else {
+			x = c.value(e)
+		}
+		if !isInteger(x.typ) && !isInvalid(x.typ) {
+			c.report(diag.Errorf(INVALID_OPERATION, span(e), "the bounds and step of FOR must be integers, not %s", x.typ))
+		} else if e == stmt.By && x.val == IntegerValue(0) {
+			c.report(diag.Errorf(OUT_OF_RANGE, span(e), "the step of FOR must not be zero"))
+		}
+	}
+	c.statements(stmt.Body)
+}
+
+// withClause checks guard = qualident ":" qualident, and the statements
+// it guards, in which the variable has the type of the guard.
+func (c *checker) withClause(clause *ast.WithClause) {
+	x := c.expr(clause.Var)
+	t := c.expr(clause.Type)
+	if t.mode != TYPE_OPERAND && t.mode != IMPORTED {
+		if !isInvalid(t.typ) {
+			c.report(diag.Errorf(NOT_A_TYPE, span(clause.Type), "%s is not a type", text(clause.Type)))
+		}
+		t = invalidOperand
+	}
+	c.typeTest(clause.Var, x, clause.Type, t.typ, TYPE_TEST)
+	c.guarded(x.object, t.typ, clause.Body)
+}
+
+// guarded checks the statements body, in which the variable object has
+// the type t a type test guarantees.
+func (c *checker) guarded(object *Object, t Type, body []ast.Stmt) {
+	if object == nil || isInvalid(t) {
+		c.statements(body)
+		return
+	}
+	previous, ok := c.narrowed[object]
+	c.narrowed[object] = t
+	c.statements(body)
+	if ok {
+		c.narrowed[object] = previous
+	} else {
+		delete(c.narrowed, object)
+	}
+}
+
+// typeTest checks the test of x, the operand of e, for type t, named by
+// guard, a test of the given kind: x must be a pointer or a VAR parameter
+// of record type, and t an extension of its type.
+func (c *checker) typeTest(e ast.Node, x operand, guard ast.Node, t Type, kind TypeCheckKind) {
+	if isInvalid(x.typ) || isInvalid(t) || x.mode == IMPORTED || !c.dynamic(e, x) {
+		return
+	}
+	if !isExtension(t, x.typ) {
+		c.report(diag.Errorf(NOT_AN_EXTENSION, span(guard), "%s is not an extension of %s, the type of %s", t, x.typ, text(e)))
+		return
+	}
+	c.typeCheck(guard, kind, t)
+}
+
+// dynamic reports whether x, the operand of e, has a dynamic type, which
+// may be tested, and reports it if not.
+func (c *checker) dynamic(e ast.Node, x operand) bool {
+	switch x.typ.(type) {
+	case *Pointer:
+		return true
+	case *Record:
+		if isVarParameter(x.object) {
+			return true
+		}
+		c.report(diag.Errorf(INVALID_OPERATION, span(e), "cannot test the type of %s: only pointers and VAR parameters of record type have a dynamic type", text(e)))
+		return false
+	}
+	c.report(diag.Errorf(INVALID_OPERATION, span(e), "cannot test the type of %s, which is of type %s: it is not a pointer or a record", text(e), x.typ))
+	return false
+}
+
+// isExtension reports whether t is static or an extension of it: both
+// pointers to records or both records.
+func isExtension(t, static Type) bool {
+	switch static := static.(type) {
+	case *Pointer:
+		p, ok := t.(*Pointer)
+		return ok && recordOf(p) != nil && recordOf(static) != nil && recordOf(p).Extends(recordOf(static))
+	case *Record:
+		r, ok := t.(*Record)
+		return ok && r.Extends(static)
+	}
+	return false
+}
+
+// isVarParameter reports whether object is a VAR, IN or OUT parameter,
+// or a receiver passed so.
+func isVarParameter(object *Object) bool {
+	if object == nil || object.Kind != PARAM_OBJECT {
+		return false
+	}
+	switch decl := object.Decl.(type) {
+	case *ast.FPSection:
+		return decl.Mode != lexer.ILLEGAL
+	case *ast.Receiver:
+		return decl.Mode != lexer.ILLEGAL
+	}
+	return false
+}
+
+// isMethod reports whether object is a type-bound procedure.
+func isMethod(object *Object) bool {
+	if object == nil {
+		return false
+	}
+	decl, ok := object.Decl.(*ast.ProcDecl)
+	return ok && decl.Receiver != nil
+}
+
+// isTypeName reports whether e is the name of a type. The names of
+// other modules are taken for types.
+func (c *checker) isTypeName(e ast.Expr) bool {
+	var qualident, ok = e.(*ast.QualIdent)
+	if designator, isDesignator := e.(*ast.Designator); isDesignator && len(designator.Selectors) == 0 {
+		qualident, ok = designator.Qualident, true
+	}
+	if !ok {
+		return false
+	}
+	if qualident.Module != nil {
+		module := c.tree.Uses[qualident.Module]
+		return module != nil && module.Kind == MODULE_OBJECT && !isSystem(module)
+	}
+	object := c.tree.Uses[qualident.Name]
+	return object != nil && object.Kind == TYPE_OBJECT
+}
+
+// value returns the operand of e, which must be a value.
+func (c *checker) value(e ast.Expr) operand {
+	x := c.expr(e)
+	switch x.mode {
+	case TYPE_OPERAND:
+		c.report(diag.Errorf(NOT_A_VARIABLE, span(e), "%s is a type, not a value", text(e)))
+	case MODULE_OPERAND:
+		c.report(diag.Errorf(NOT_A_VARIABLE, span(e), "%s is a module, not a value", text(e)))
+	case BUILTIN:
+		c.report(diag.Errorf(PROCEDURE_USE, span(e), "%s is a procedure and can only be called", text(e)))
+	case NO_VALUE:
+		return invalidOperand
+	default:
+		return x
+	}
+	return invalidOperand
+}
+
+// expr returns the operand of e and records its type.
+func (c *checker) expr(e ast.Expr) operand {
+	return c.record(e, c.expr1(e))
+}
+
+func (c *checker) expr1(e ast.Expr) operand {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		switch e.Kind {
+		case lexer.INTEGER:
+			return operand{mode: CONSTANT, typ: BASIC_TYPES[INTEGER], val: IntegerValue(e.IntValue)}
+		case lexer.REAL:
+			if strings.ContainsRune(e.Value, 'D') && c.dialect.PredeclaredTypes["LONGREAL"] {
+				return operand{mode: CONSTANT, typ: BASIC_TYPES[LONGREAL], val: RealValue(e.RealValue)}
+			}
+			return operand{mode: CONSTANT, typ: BASIC_TYPES[REAL], val: RealValue(e.RealValue)}
+		case lexer.CHAR:
+			return operand{mode: CONSTANT, typ: BASIC_TYPES[CHAR], val: CharValue(e.IntValue)}
+		case lexer.STRING:
+			return operand{mode: CONSTANT, typ: &String{Length: utf8.RuneCountInString(e.StrValue)}, val: StringValue(e.StrValue)}
+		}
+	case *ast.BoolLit:
+		return operand{mode: CONSTANT, typ: BASIC_TYPES[BOOLEAN], val: BooleanValue(e.Value)}
+	case *ast.NilLit:
+		return operand{mode: CONSTANT, typ: BASIC_TYPES[NIL], val: NilValue{}}
+	case *ast.Ident:
+		if object := c.tree.Uses[e]; object != nil {
+			return c.object(object)
+		}
+	case *ast.QualIdent:
+		return c.qualident(e)
+	case *ast.Designator:
+		return c.designator(e)
+	case *ast.CallExpr:
+		return c.call(e, false)
+	case *ast.SetExpr:
+		return c.set(e)
+	case *ast.ParenExpr:
+		x := c.value(e.X)
+		if x.mode != CONSTANT {
+			x.mode = VALUE
+		}
+		return x
+	case *ast.UnaryExpr:
+		return c.unary(e)
+	case *ast.BinaryExpr:
+		return c.binary(e)
+	case *ast.RangeExpr:
+		c.value(e.Low)
+		c.value(e.High)
+		c.report(diag.Errorf(INVALID_OPERATION, span(e), "a range is only allowed in a set or a CASE label"))
+	}
+	return invalidOperand
+}
+
+// set checks set = "{" [element {"," element}] "}" with element =
+// expression [".." expression]: the elements are integers from 0 to
+// MAX_SET.
+func (c *checker) set(e *ast.SetExpr) operand {
+	var x = operand{mode: CONSTANT, typ: BASIC_TYPES[SET], val: SetValue(0)}
+	var member = func(e ast.Expr) IntegerValue {
+		y := c.value(e)
+		if !isInteger(y.typ) {
+			if !isInvalid(y.typ) {
+				c.report(diag.Errorf(INVALID_OPERATION, span(e), "the elements of a set must be integers, not %s", y.typ))
+			}
+			x = invalidOperand
+			return 0
+		}
+		if y.mode != CONSTANT {
+			x.mode, x.val = VALUE, nil
+			return 0
+		}
+		if err := element(y.val.(IntegerValue)); err != nil {
+			c.constant(e, nil, nil, err)
+			x = invalidOperand
+		}
+		return y.val.(IntegerValue)
+	}
+	for _, e := range e.Elements {
+		var low, high IntegerValue
+		if r, ok := e.(*ast.RangeExpr); ok {
+			low, high = member(r.Low), member(r.High)
+		} else {
+			low = member(e)
+			high = low
+		}
+		if x.mode == CONSTANT {
+			elements, _ := rangeValue(low, high)
+			x.val = x.val.(SetValue) | elements
+		}
+	}
+	return x
+}
+
+func (c *checker) unary(e *ast.UnaryExpr) operand {
+	x := c.value(e.X)
+	if isInvalid(x.typ) {
+		return invalidOperand
+	}
+	var ok bool
+	switch e.Op {
+	case lexer.NOT:
+		ok = isBoolean(x.typ)
+	case lexer.MINUS:
+		ok = isNumeric(x.typ) || isSet(x.typ)
+	case lexer.PLUS:
+		ok = isNumeric(x.typ)
+	}
+	if !ok {
+		c.report(diag.Errorf(INVALID_OPERATION, span(e), "operator %s does not apply to %s", e.Op, x.typ))
+		return invalidOperand
+	}
+	if x.mode == CONSTANT {
+		v, err := unaryValue(e.Op, x.val)
+		return c.constant(e, x.typ, v, err)
+	}
+	x.mode = VALUE
+	return x
+}
+
+// larger returns the type of the result of an arithmetic operation on
+// numbers of types a and b: the type that includes the other, or nil.
+func (c *checker) larger(a, b Type) Type {
+	if includes(c.dialect, a, b) {
+		return a
+	}
+	if includes(c.dialect, b, a) {
+		return b
+	}
+	return nil
+}
+
+// comparable reports whether x and y can be compared with = and #, or,
+// if ordered, with <, <=, > and >=.
+func (c *checker) comparable(x, y operand, ordered bool) bool {
+	if isNumeric(x.typ) && isNumeric(y.typ) {
+		return c.larger(x.typ, y.typ) != nil
+	}
+	if isChar(x.typ) && isChar(y.typ) || isString(x.typ) && isString(y.typ) {
+		return true
+	}
+	if ordered {
+		return false
+	}
+	if isBoolean(x.typ) && isBoolean(y.typ) || isSet(x.typ) && isSet(y.typ) {
+		return true
+	}
+	switch x.typ.(type) {
+	case *Pointer, *Procedure:
+		return assignable(c.dialect, x.typ, y.typ) || assignable(c.dialect, y.typ, x.typ)
+	case *Basic:
+		if basic(x.typ, NIL) {
+			switch y.typ.(type) {
+			case *Pointer, *Procedure:
+				return true
+			}
+			return basic(y.typ, NIL)
+		}
+	}
+	return false
+}
+
+// binary checks the operands of the binary operators:
+//
+//   - - *        numbers, or sets
+//     /            real numbers, or sets
+//     DIV MOD      integers
+//     OR &         BOOLEANs
+//     = #          numbers, characters, strings, BOOLEANs, sets, pointers or procedures
+//     < <= > >=    numbers, characters or strings
+//     IN           an integer and a set
+//     IS           a pointer or record variable and a type
+func (c *checker) binary(e *ast.BinaryExpr) operand {
+	x := c.value(e.X)
+	if e.Op == lexer.IS {
+		var t Type = BASIC_TYPES[INVALID]
+		if qualident, ok := e.Y.(*ast.QualIdent); ok {
+			t = c.typeName(qualident)
+		} else if y := c.expr(e.Y); y.mode == TYPE_OPERAND {
+			t = y.typ
+		} else if !isInvalid(y.typ) {
+			c.report(diag.Errorf(NOT_A_TYPE, span(e.Y), "%s is not a type", text(e.Y)))
+		}
+		c.typeTest(e.X, x, e.Y, t, TYPE_TEST)
+		return operand{mode: VALUE, typ: BASIC_TYPES[BOOLEAN]}
+	}
+	y := c.value(e.Y)
+	if isInvalid(x.typ) || isInvalid(y.typ) {
+		return invalidOperand
+	}
+	var t Type
+	switch e.Op {
+	case lexer.PLUS, lexer.MINUS, lexer.TIMES:
+		if isNumeric(x.typ) && isNumeric(y.typ) {
+			t = c.larger(x.typ, y.typ)
+		} else if isSet(x.typ) && isSet(y.typ) {
+			t = x.typ
+		}
+	case lexer.SLASH:
+		if isSet(x.typ) && isSet(y.typ) {
+			t = x.typ
+		} else if isNumeric(x.typ) && isNumeric(y.typ) {
+			t = c.larger(x.typ, y.typ)
+			if isInteger(t) {
+				t = nil
+				if c.dialect.Has(lexer.NUMERIC_TYPE_INCLUSION) {
+					t = BASIC_TYPES[REAL]
+				}
+			}
+		}
+	case lexer.DIV, lexer.MOD:
+		if isInteger(x.typ) && isInteger(y.typ) {
+			t = c.larger(x.typ, y.typ)
+		}
+	case lexer.OR, lexer.AND:
+		if isBoolean(x.typ) && isBoolean(y.typ) {
+			t = x.typ
+		}
+	case lexer.EQL, lexer.NEQ, lexer.LSS, lexer.LEQ, lexer.GTR, lexer.GEQ:
+		if c.comparable(x, y, e.Op != lexer.EQL && e.Op != lexer.NEQ) {
+			t = BASIC_TYPES[BOOLEAN]
+		}
+	case lexer.IN:
+		if isInteger(x.typ) && isSet(y.typ) {
+			t = BASIC_TYPES[BOOLEAN]
+		}
+	}
+	if t == nil {
+		d := diag.Errorf(INVALID_OPERATION, span(e), "operator %s does not apply to %s and %s", e.Op, x.typ, y.typ)
+		if e.Op == lexer.SLASH && isInteger(x.typ) && isInteger(y.typ) {
+			d = d.WithNote(source.Span{}, "DIV divides integers")
+		} else if isInteger(x.typ) && isReal(y.typ) || isReal(x.typ) && isInteger(y.typ) {
+			d = d.WithNote(source.Span{}, "%s does not mix integers and real numbers: convert with FLT or FLOOR", c.dialect.Name)
+		}
+		c.report(d)
+		return invalidOperand
+	}
+	switch e.Op {
+	case lexer.SLASH, lexer.DIV, lexer.MOD:
+		if y.mode == CONSTANT && isZero(y.val) {
+			c.report(diag.Errorf(DIVISION_BY_ZERO, span(e), "division by zero"))
+			return invalidOperand
+		}
+	}
+	if x.mode == CONSTANT && y.mode == CONSTANT {
+		v, err := binaryValue(e.Op, x.val, y.val, t)
+		return c.constant(e, t, v, err)
+	}
+	return operand{mode: VALUE, typ: t}
+}
+
+// object returns the operand object denotes.
+func (c *checker) object(object *Object) operand {
+	var x = operand{mode: VALUE, typ: object.Type, object: object}
+	switch object.Kind {
+	case CONST_OBJECT:
+		// A constant whose value is in error is left out of the
+		// expressions that use it.
+		x.mode, x.val = CONSTANT, object.Value
+		if x.val == nil {
+			x.typ = BASIC_TYPES[INVALID]
+		}
+	case TYPE_OBJECT:
+		x.mode = TYPE_OPERAND
+		x.typ = c.objectType(object)
+	case VAR_OBJECT:
+		x.mode = VARIABLE
+	case PARAM_OBJECT:
+		x.mode = VARIABLE
+		if section, ok := object.Decl.(*ast.FPSection); ok && section.Mode == lexer.IN {
+			x.mode = READ_ONLY
+		}
+		if receiver, ok := object.Decl.(*ast.Receiver); ok && receiver.Mode == lexer.IN {
+			x.mode = READ_ONLY
+		}
+	case PROC_OBJECT:
+		if object.IsPredeclared() {
+			if b, ok := PREDECLARED_PROCEDURES[object.Name]; ok {
+				x.mode = BUILTIN
+				x.builtin = &b
+			}
+		}
+	case MODULE_OBJECT:
+		x.mode = MODULE_OPERAND
+	}
+	if t, ok := c.narrowed[object]; ok {
+		x.typ = t
+	}
+	if x.typ == nil && x.mode != BUILTIN && x.mode != MODULE_OPERAND {
+		x.typ = BASIC_TYPES[INVALID]
+	}
+	return x
+}
+
+// qualident returns the operand of qualident = [ident "."] ident, which
+// is a field selection unless the first ident denotes a module.
+func (c *checker) qualident(qualident *ast.QualIdent) operand {
+	if qualident.Module == nil {
+		if object := c.tree.Uses[qualident.Name]; object != nil {
+			return c.object(object)
+		}
+		return invalidOperand
+	}
+	var module = c.tree.Uses[qualident.Module]
+	if module == nil {
+		return invalidOperand
+	}
+	if module.Kind != MODULE_OBJECT {
+		x := c.record(qualident.Module, c.object(module))
+		return c.field(qualident.Module, x, qualident.Name)
+	}
+	if !isSystem(module) {
+		return operand{mode: IMPORTED, typ: BASIC_TYPES[INVALID]}
+	}
+	if b, ok := SYSTEM_PROCEDURES[qualident.Name.Name]; ok {
+		return operand{mode: BUILTIN, builtin: &b}
+	}
+	undefinedSystemProcedure(qualident.Name, c.reporter)
+	return invalidOperand
+}
+
+// designator returns the operand of designator = qualident {selector}.
+func (c *checker) designator(designator *ast.Designator) operand {
+	var x = c.qualident(designator.Qualident)
+	var e = prefix{designator.Pos(), designator.Qualident.End()}
+	for _, selector := range designator.Selectors {
+		x = c.selector(e, x, selector)
+		e.to = selector.End()
+	}
+	return x
+}
+
+// selectable reports whether x, the operand of e, may be selected from.
+// A predeclared procedure can only be called and a module only qualified.
+func (c *checker) selectable(e ast.Node, x operand) bool {
+	switch x.mode {
+	case BUILTIN:
+		c.report(diag.Errorf(PROCEDURE_USE, span(e), "%s is a procedure and can only be called", text(e)))
+		return false
+	case MODULE_OPERAND:
+		c.report(diag.Errorf(INVALID_SELECTOR, span(e), "%s is a module and can only be qualified", text(e)))
+		return false
+	}
+	return true
+}
+
+// field returns the operand of the field or type-bound procedure name of
+// x, the operand of e. Records are selected through pointers.
+func (c *checker) field(e ast.Node, x operand, name *ast.Ident) operand {
+	if !c.selectable(e, x) {
+		return invalidOperand
+	}
+	if x.mode == IMPORTED || isInvalid(x.typ) {
+		return operand{mode: x.mode, typ: BASIC_TYPES[INVALID]}
+	}
+	var record = recordOf(x.typ)
+	if record == nil {
+		c.report(diag.Errorf(INVALID_SELECTOR, span(name), "%s has no fields: it is of type %s", text(e), x.typ))
+		return invalidOperand
+	}
+	var field = record.Field(name.Name)
+	if field == nil {
+		c.report(diag.Errorf(INVALID_SELECTOR, span(name), "%s has no field or procedure %s", record, name.Name))
+		return invalidOperand
+	}
+	c.tree.Uses[name] = field
+	if field.Kind == PROC_OBJECT {
+		return operand{mode: VALUE, typ: field.Type, object: field}
+	}
+	var mode = x.mode
+	if _, ok := x.typ.(*Pointer); ok {
+		mode = VARIABLE
+	}
+	if field.Type == nil {
+		return operand{mode: mode, typ: BASIC_TYPES[INVALID]}
+	}
+	return operand{mode: mode, typ: field.Type}
+}
+
+// selector returns the operand of selector = "." ident | "[" ExpList "]" |
+// "^" | "(" qualident ")" applied to x, the operand of e.
+func (c *checker) selector(e ast.Node, x operand, selector ast.Selector) operand {
+	switch selector := selector.(type) {
+	case *ast.FieldSelector:
+		return c.field(e, x, selector.Name)
+	case *ast.IndexSelector:
+		if !c.selectable(e, x) {
+			x = invalidOperand
+		}
+		for _, index := range selector.Indices {
+			i := c.value(index)
+			if !isInteger(i.typ) && !isInvalid(i.typ) {
+				c.report(diag.Errorf(INVALID_OPERATION, span(index), "an index must be an integer, not %s", i.typ))
+			}
+			if x.mode == IMPORTED || isInvalid(x.typ) {
+				continue
+			}
+			var mode = x.mode
+			var t = x.typ
+			if pointer, ok := t.(*Pointer); ok {
+				t, mode = pointer.Base, VARIABLE
+			}
+			array, ok := t.(*Array)
+			if !ok {
+				c.report(diag.Errorf(INVALID_SELECTOR, span(selector), "%s is not an array: it is of type %s", text(e), x.typ))
+				return invalidOperand
+			}
+			if n, ok := i.val.(IntegerValue); ok && i.mode == CONSTANT && array.Len >= 0 && (n < 0 || int64(n) >= array.Len) {
+				c.report(diag.Errorf(OUT_OF_RANGE, span(index), "index %s is out of range 0..%d of %s", n, array.Len-1, array))
+			}
+			x = operand{mode: mode, typ: array.Elem}
+		}
+		if x.mode == CONSTANT {
+			x.mode = VALUE
+		}
+		return x
+	case *ast.DerefSelector:
+		if !c.selectable(e, x) {
+			return invalidOperand
+		}
+		if x.mode == IMPORTED || isInvalid(x.typ) {
+			return x
+		}
+		if isMethod(x.object) {
+			// The procedure a type-bound procedure redefines.
+			return x
+		}
+		pointer, ok := x.typ.(*Pointer)
+		if !ok {
+			c.report(diag.Errorf(INVALID_SELECTOR, span(selector), "%s is not a pointer: it is of type %s", text(e), x.typ))
+			return invalidOperand
+		}
+		return operand{mode: VARIABLE, typ: pointer.Base}
+	case *ast.TypeGuardSelector:
+		if !c.selectable(e, x) {
+			c.typeName(selector.Type)
+			return invalidOperand
+		}
+		t := c.typeName(selector.Type)
+		c.typeTest(e, x, selector.Type, t, TYPE_GUARD)
+		if isInvalid(t) {
+			return operand{mode: x.mode, typ: t}
+		}
+		return operand{mode: x.mode, typ: t, object: x.object}
+	}
+	return invalidOperand
+}
+
+// call checks the call of a procedure, a statement if statement is set.
+// The parser reads a type guard v(T) not followed by a selector as a
+// call; it is told from one by the argument, a type.
+func (c *checker) call(call *ast.CallExpr, statement bool) operand {
+	f := c.expr(call.Fun)
+	switch f.mode {
+	case IMPORTED:
+		for _, arg := range call.Args {
+			c.expr(arg)
+		}
+		return operand{mode: IMPORTED, typ: BASIC_TYPES[INVALID]}
+	case BUILTIN:
+		return c.builtinCall(call, f.builtin, statement)
+	case MODULE_OPERAND, TYPE_OPERAND:
+		c.report(diag.Errorf(NOT_A_PROCEDURE, span(call.Fun), "%s is not a procedure", text(call.Fun)))
+		return invalidOperand
+	}
+	if len(call.Args) == 1 && recordOf(f.typ) != nil && c.isTypeName(call.Args[0]) {
+		t := c.expr(call.Args[0])
+		c.typeTest(call.Fun, f, call.Args[0], t.typ, TYPE_GUARD)
+		return operand{mode: f.mode, typ: t.typ, object: f.object}
+	}
+	procedure, ok := f.typ.(*Procedure)
+	if !ok {
+		if !isInvalid(f.typ) {
+			c.report(diag.Errorf(NOT_A_PROCEDURE, span(call.Fun), "%s is not a procedure: it is of type %s", text(call.Fun), f.typ))
+		}
+		for _, arg := range call.Args {
+			c.expr(arg)
+		}
+		return invalidOperand
+	}
+	if len(call.Args) != len(procedure.Params) {
+		c.report(diag.Errorf(ARGUMENT_COUNT, span(call), "%s takes %s, but is called with %d", text(call.Fun), plural(len(procedure.Params), "argument"), len(call.Args)))
+	}
+	for index, arg := range call.Args {
+		if index >= len(procedure.Params) {
+			c.expr(arg)
+			continue
+		}
+		c.argument(call, index, procedure.Params[index], arg)
+	}
+	return c.callResult(call, procedure.Result, statement)
+}
+
+// procedureName returns the identifier that names the procedure called
+// by designator fun.
+func procedureName(fun *ast.Designator) ast.Node {
+	if len(fun.Selectors) == 0 {
+		return fun.Qualident.Name
+	}
+	if field, ok := fun.Selectors[len(fun.Selectors)-1].(*ast.FieldSelector); ok {
+		return field.Name
+	}
+	return fun
+}
+
+// callResult returns the operand of the call of a procedure with the result
+// type result, or none, and checks that a function procedure is called
+// in an expression and a proper procedure as a statement.
+func (c *checker) callResult(call *ast.CallExpr, result Type, statement bool) operand {
+	if result == nil {
+		if !statement {
+			c.report(diag.Errorf(PROCEDURE_USE, span(call), "proper procedure %s used in an expression", text(call.Fun)).
+				WithNote(span(procedureName(call.Fun)), "%s does not return a value", text(call.Fun)))
+		}
+		return operand{mode: NO_VALUE}
+	}
+	if statement {
+		c.report(diag.Errorf(PROCEDURE_USE, span(call), "function procedure %s called as a statement", text(call.Fun)).
+			WithNote(span(procedureName(call.Fun)), "%s returns a value", text(call.Fun)))
+	}
+	return operand{mode: VALUE, typ: result}
+}
+
+// argument checks arg, the argument for param of call: a value parameter
+// takes a value assignment compatible with its type, or array compatible
+// with an open array; a VAR, IN or OUT parameter a variable of its type.
+func (c *checker) argument(call *ast.CallExpr, index int, param *Param, arg ast.Expr) {
+	x := c.value(arg)
+	if x.mode == IMPORTED || isInvalid(x.typ) {
+		return
+	}
+	var ok bool
+	switch param.Mode {
+	case lexer.VAR, lexer.OUT:
+		if !c.variable(arg, x, "pass to "+param.Mode.String()+" parameter") {
+			return
+		}
+		fallthrough
+	case lexer.IN:
+		a, b := recordOf(param.Type), recordOf(x.typ)
+		_, record := param.Type.(*Record)
+		ok = arrayCompatible(param.Type, x.typ) || record && a != nil && b != nil && b.Extends(a)
+		if param.Mode == lexer.IN && !ok {
+			ok = c.assignable(param.Type, x)
+		}
+	default:
+		if array, open := param.Type.(*Array); open && array.Len == OPEN_ARRAY {
+			ok = arrayCompatible(param.Type, x.typ)
+		} else {
+			ok = c.assignable(param.Type, x)
+		}
+	}
+	if !ok {
+		c.report(diag.Errorf(ARGUMENT_MISMATCH, span(arg), "cannot pass %s for parameter %s of %s, which is of type %s", x.typ, param.Name, text(call.Fun), param.Type))
+	} else if param.Mode == lexer.ILLEGAL {
+		c.localProcedure(arg, param.Type, x)
+	}
+}
+
+// builtinCall checks the call of a predeclared procedure or a procedure
+// of SYSTEM against its signature.
+func (c *checker) builtinCall(call *ast.CallExpr, procedure *builtin, statement bool) operand {
+	if procedure == nil {
+		for _, arg := range call.Args {
+			c.expr(arg)
+		}
+		return invalidOperand
+	}
+	var name = text(call.Fun)
+	var fun = call.Fun
+	var predeclared = fun.Qualident.Module == nil && len(fun.Selectors) == 0
+	var pointer *operand // the first argument of NEW, checked before the others
+	if predeclared && fun.Qualident.Name.Name == "NEW" && len(call.Args) > 0 {
+		x := c.value(call.Args[0])
+		pointer = &x
+		signature := newSignature(x.typ, len(call.Args))
+		procedure = &signature
+	}
+	var most, least = len(procedure.Params), len(procedure.Params) - procedure.Optional
+	if len(call.Args) < least || len(call.Args) > most {
+		var count = plural(most, "argument")
+		if least != most {
+			count = plural(least, "argument")
+			count = count[:strings.IndexByte(count, ' ')] + " or " + plural(most, "argument")
+		}
+		c.report(diag.Errorf(ARGUMENT_COUNT, span(call), "%s takes %s, but is called with %d", name, count, len(call.Args)))
+	}
+	var args []Type
+	var values []Value
+	var unknown bool // an argument is of another module, or in error
+	for index, arg := range call.Args {
+		if index >= most {
+			c.expr(arg)
+			continue
+		}
+		class := procedure.Params[index]
+		var x operand
+		if class == TYPE_NAME || class == BASIC_TYPE_NAME {
+			x = c.expr(arg)
+			if x.mode != TYPE_OPERAND && x.mode != IMPORTED || !class.accepts(x.typ) {
+				c.report(diag.Errorf(ARGUMENT_MISMATCH, span(arg), "argument %d of %s must be %s", index+1, name, class))
+				x = invalidOperand
+			}
+		} else {
+			if index == 0 && pointer != nil {
+				x = *pointer
+			} else {
+				x = c.value(arg)
+			}
+			if class.isVariableClass() && x.mode != VARIABLE && x.mode != IMPORTED && !isInvalid(x.typ) || !class.accepts(x.typ) {
+				c.report(diag.Errorf(ARGUMENT_MISMATCH, span(arg), "argument %d of %s must be %s", index+1, name, class))
+				x = invalidOperand
+			}
+		}
+		args = append(args, x.typ)
+		unknown = unknown || x.mode == IMPORTED || isInvalid(x.typ)
+		if x.mode == CONSTANT && x.val != nil {
+			values = append(values, x.val)
+		}
+	}
+	x := c.callResult(call, builtinResult(*procedure, args), statement)
+	if x.typ == nil && x.mode == VALUE {
+		x.typ = BASIC_TYPES[INVALID]
+	}
+	var folded = predeclared && FOLDED_PROCEDURES[fun.Qualident.Name.Name]
+	if folded && x.mode == VALUE && unknown {
+		// The call may be a constant whose value is not known, as an
+		// operation on an imported constant is.
+		return operand{mode: IMPORTED, typ: BASIC_TYPES[INVALID]}
+	}
+	if folded && x.mode == VALUE && !isInvalid(x.typ) && len(values) == most && len(call.Args) == most {
+		if v, err := builtinValue(fun.Qualident.Name.Name, values); v != nil || err != nil {
+			return c.constant(call, x.typ, v, err)
+		}
+	}
+	return x
+}
+
+// check computes the types of tree into annotated and checks them.
+func check(tree *ast.Module, options Options, annotated *AnnotatedTree, reporter *diag.Reporter) {
+	var c = &checker{
+		tree:     annotated,
+		dialect:  options.Dialect,
+		reporter: reporter,
+		building: make(map[*Object]bool),
+		narrowed: make(map[*Object]Type),
+
+		descriptors: make(map[*Record]*TypeDescriptor),
+
+		warnIncompleteCase: options.WarnIncompleteCase,
+	}
+	c.block(tree.Decls, tree.Body)
+	c.methodTables()
+}
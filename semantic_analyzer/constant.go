@@ -0,0 +1,413 @@
+package semantic_analyzer
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+
+	lexer "oberon/lexer"
+)
+
+// Value is the value of a constant expression, which the analyzer
+// computes: an IntegerValue, RealValue, CharValue, BooleanValue,
+// SetValue, StringValue or NilValue. Integer constants are computed in 64
+// bits; a result that does not fit is an overflow.
+type Value interface {
+	String() string
+}
+
+type IntegerValue int64
+type RealValue float64
+type CharValue int64
+type BooleanValue bool
+type SetValue uint64
+type StringValue string
+type NilValue struct{}
+
+// MAX_SET is the largest element of a set.
+const MAX_SET = 31
+
+func (v IntegerValue) String() string { return strconv.FormatInt(int64(v), 10) }
+func (v CharValue) String() string    { return fmt.Sprintf("%XX", int64(v)) }
+func (v StringValue) String() string  { return strconv.Quote(string(v)) }
+func (v NilValue) String() string     { return "NIL" }
+
+func (v RealValue) String() string {
+	var s = strconv.FormatFloat(float64(v), 'G', -1, 64)
+	if !strings.ContainsAny(s, ".EIN") {
+		s += ".0"
+	}
+	return s
+}
+
+func (v BooleanValue) String() string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func (v SetValue) String() string {
+	var elements []string
+	for element := 0; element <= MAX_SET; element++ {
+		if v&(1<<uint(element)) != 0 {
+			elements = append(elements, strconv.Itoa(element))
+		}
+	}
+	return "{" + strings.Join(elements, ", ") + "}"
+}
+
+// constantError is an error in the evaluation of a constant expression:
+// an overflow, a division by zero or an argument out of range.
+type constantError struct {
+	code    string
+	message string
+}
+
+func (e *constantError) Error() string {
+	return e.message
+}
+
+func overflow(format string, args ...interface{}) error {
+	return &constantError{CONSTANT_OVERFLOW, fmt.Sprintf(format, args...)}
+}
+
+func outOfRange(format string, args ...interface{}) error {
+	return &constantError{OUT_OF_RANGE, fmt.Sprintf(format, args...)}
+}
+
+var divisionByZero = &constantError{DIVISION_BY_ZERO, "division by zero"}
+
+// PREDECLARED_VALUES are the values of the predeclared constants.
+var PREDECLARED_VALUES = map[string]Value{
+	"FALSE": BooleanValue(false),
+	"TRUE":  BooleanValue(true),
+	"INF":   RealValue(math.Inf(1)),
+}
+
+// charOf returns the character v denotes, a character or a string of one
+// character.
+func charOf(v Value) (CharValue, bool) {
+	switch v := v.(type) {
+	case CharValue:
+		return v, true
+	case StringValue:
+		if runes := []rune(string(v)); len(runes) == 1 {
+			return CharValue(runes[0]), true
+		}
+	}
+	return 0, false
+}
+
+// realOf returns the real number v denotes, an integer or a real.
+func realOf(v Value) (float64, bool) {
+	switch v := v.(type) {
+	case IntegerValue:
+		return float64(v), true
+	case RealValue:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// isZero reports whether v is an integer or real zero.
+func isZero(v Value) bool {
+	switch v := v.(type) {
+	case IntegerValue:
+		return v == 0
+	case RealValue:
+		return v == 0
+	}
+	return false
+}
+
+// element checks that v is an integer between 0 and MAX_SET.
+func element(v IntegerValue) error {
+	if v < 0 || v > MAX_SET {
+		return outOfRange("set element %d is out of range 0..%d", v, MAX_SET)
+	}
+	return nil
+}
+
+// rangeValue returns the set of the elements from low to high.
+func rangeValue(low, high IntegerValue) (SetValue, error) {
+	if err := element(low); err != nil {
+		return 0, err
+	}
+	if err := element(high); err != nil {
+		return 0, err
+	}
+	var set SetValue
+	for e := low; e <= high; e++ {
+		set |= 1 << uint(e)
+	}
+	return set, nil
+}
+
+// unaryValue returns the value of op x.
+func unaryValue(op lexer.TokenKind, x Value) (Value, error) {
+	switch x := x.(type) {
+	case IntegerValue:
+		if op == lexer.MINUS {
+			if x == math.MinInt64 {
+				return nil, overflow("-(%s) overflows", x)
+			}
+			return -x, nil
+		}
+		return x, nil
+	case RealValue:
+		if op == lexer.MINUS {
+			return -x, nil
+		}
+		return x, nil
+	case SetValue:
+		return ^x & (1<<(MAX_SET+1) - 1), nil
+	case BooleanValue:
+		return !x, nil
+	}
+	return nil, nil
+}
+
+// floorDiv returns the quotient and the modulus of x and y, y not zero:
+// x = q*y + r with 0 <= r < y for a positive y.
+func floorDiv(x, y IntegerValue) (IntegerValue, IntegerValue) {
+	q, r := x/y, x%y
+	if r != 0 && (r < 0) != (y < 0) {
+		q, r = q-1, r+y
+	}
+	return q, r
+}
+
+// integerValue returns the value of x op y for integers x and y.
+func integerValue(op lexer.TokenKind, x, y IntegerValue) (Value, error) {
+	switch op {
+	case lexer.PLUS:
+		if sum := x + y; (sum > x) == (y > 0) {
+			return sum, nil
+		}
+	case lexer.MINUS:
+		if difference := x - y; (difference < x) == (y > 0) {
+			return difference, nil
+		}
+	case lexer.TIMES:
+		hi, lo := bits.Mul64(uint64(abs(x)), uint64(abs(y)))
+		negative := (x < 0) != (y < 0)
+		if hi == 0 && (lo <= math.MaxInt64 || negative && lo == 1<<63) {
+			if negative {
+				return IntegerValue(-int64(lo)), nil
+			}
+			return IntegerValue(lo), nil
+		}
+	case lexer.DIV, lexer.MOD:
+		if y == 0 {
+			return nil, divisionByZero
+		}
+		if x == math.MinInt64 && y == -1 {
+			break
+		}
+		q, r := floorDiv(x, y)
+		if op == lexer.DIV {
+			return q, nil
+		}
+		return r, nil
+	}
+	return nil, overflow("%s %s %s overflows", x, op, y)
+}
+
+// abs returns the absolute value of x, as an unsigned number.
+func abs(x IntegerValue) uint64 {
+	if x < 0 {
+		return uint64(-x)
+	}
+	return uint64(x)
+}
+
+// realValue returns the value of x op y for real numbers x and y.
+func realValue(op lexer.TokenKind, x, y float64) (Value, error) {
+	var result float64
+	switch op {
+	case lexer.PLUS:
+		result = x + y
+	case lexer.MINUS:
+		result = x - y
+	case lexer.TIMES:
+		result = x * y
+	case lexer.SLASH:
+		if y == 0 {
+			return nil, divisionByZero
+		}
+		result = x / y
+	}
+	if math.IsInf(result, 0) && !math.IsInf(x, 0) && !math.IsInf(y, 0) {
+		return nil, overflow("%s %s %s overflows", RealValue(x), op, RealValue(y))
+	}
+	return RealValue(result), nil
+}
+
+// compare returns the result of the comparison of x and y, which are
+// numbers, characters or strings: -1, 0 or 1.
+func compare(x, y Value) (int, bool) {
+	if a, ok := charOf(x); ok {
+		if b, ok := charOf(y); ok {
+			return compareInts(int64(a), int64(b)), true
+		}
+	}
+	if a, ok := x.(IntegerValue); ok {
+		if b, ok := y.(IntegerValue); ok {
+			return compareInts(int64(a), int64(b)), true
+		}
+	}
+	if a, ok := realOf(x); ok {
+		if b, ok := realOf(y); ok {
+			switch {
+			case a < b:
+				return -1, true
+			case a > b:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if a, ok := x.(StringValue); ok {
+		if b, ok := y.(StringValue); ok {
+			return strings.Compare(string(a), string(b)), true
+		}
+	}
+	return 0, false
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// binaryValue returns the value of x op y, of type result.
+func binaryValue(op lexer.TokenKind, x, y Value, result Type) (Value, error) {
+	switch op {
+	case lexer.EQL, lexer.NEQ, lexer.LSS, lexer.LEQ, lexer.GTR, lexer.GEQ:
+		return relationValue(op, x, y), nil
+	case lexer.IN:
+		e, set := x.(IntegerValue), y.(SetValue)
+		if err := element(e); err != nil {
+			return nil, err
+		}
+		return BooleanValue(set&(1<<uint(e)) != 0), nil
+	case lexer.OR:
+		return x.(BooleanValue) || y.(BooleanValue), nil
+	case lexer.AND:
+		return x.(BooleanValue) && y.(BooleanValue), nil
+	}
+	if a, ok := x.(SetValue); ok {
+		b := y.(SetValue)
+		switch op {
+		case lexer.PLUS:
+			return a | b, nil
+		case lexer.MINUS:
+			return a &^ b, nil
+		case lexer.TIMES:
+			return a & b, nil
+		}
+		return a ^ b, nil
+	}
+	if isInteger(result) {
+		return integerValue(op, x.(IntegerValue), y.(IntegerValue))
+	}
+	a, _ := realOf(x)
+	b, _ := realOf(y)
+	return realValue(op, a, b)
+}
+
+// relationValue returns the value of the comparison x op y.
+func relationValue(op lexer.TokenKind, x, y Value) Value {
+	order, ordered := compare(x, y)
+	var equal = ordered && order == 0 || !ordered && x == y
+	switch op {
+	case lexer.EQL:
+		return BooleanValue(equal)
+	case lexer.NEQ:
+		return BooleanValue(!equal)
+	case lexer.LSS:
+		return BooleanValue(order < 0)
+	case lexer.LEQ:
+		return BooleanValue(order <= 0)
+	case lexer.GTR:
+		return BooleanValue(order > 0)
+	}
+	return BooleanValue(order >= 0)
+}
+
+// FOLDED_PROCEDURES are the predeclared procedures whose calls with
+// constant arguments are constant.
+var FOLDED_PROCEDURES = map[string]bool{
+	"ABS": true,
+	"ASR": true,
+	"CHR": true,
+	"LSL": true,
+	"ODD": true,
+	"ORD": true,
+	"ROR": true,
+}
+
+// builtinValue returns the value of the call of the predeclared procedure
+// name, one of FOLDED_PROCEDURES, with the constant arguments args.
+func builtinValue(name string, args []Value) (Value, error) {
+	switch name {
+	case "ABS":
+		switch x := args[0].(type) {
+		case IntegerValue:
+			if x == math.MinInt64 {
+				return nil, overflow("ABS(%s) overflows", x)
+			}
+			if x < 0 {
+				return -x, nil
+			}
+			return x, nil
+		case RealValue:
+			return RealValue(math.Abs(float64(x))), nil
+		}
+	case "ODD":
+		return BooleanValue(args[0].(IntegerValue)%2 != 0), nil
+	case "ORD":
+		if c, ok := charOf(args[0]); ok {
+			return IntegerValue(c), nil
+		}
+		switch x := args[0].(type) {
+		case BooleanValue:
+			if x {
+				return IntegerValue(1), nil
+			}
+			return IntegerValue(0), nil
+		case SetValue:
+			return IntegerValue(x), nil
+		}
+	case "CHR":
+		x := args[0].(IntegerValue)
+		if x < 0 || x > lexer.MAX_CHAR {
+			return nil, outOfRange("CHR(%s) is out of range: characters are 0X..%XX", x, lexer.MAX_CHAR)
+		}
+		return CharValue(x), nil
+	case "LSL", "ASR", "ROR":
+		x, n := args[0].(IntegerValue), args[1].(IntegerValue)
+		if n < 0 || n > 63 {
+			return nil, outOfRange("%s(%s, %s) shifts by %s, which is out of range 0..63", name, x, n, n)
+		}
+		switch name {
+		case "ASR":
+			return x >> uint(n), nil
+		case "ROR":
+			return IntegerValue(bits.RotateLeft64(uint64(x), -int(n))), nil
+		}
+		if shifted := x << uint(n); shifted>>uint(n) == x {
+			return shifted, nil
+		}
+		return nil, overflow("LSL(%s, %s) overflows", x, n)
+	}
+	return nil, nil
+}
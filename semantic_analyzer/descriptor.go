@@ -0,0 +1,124 @@
+package semantic_analyzer
+
+import (
+	ast "oberon/ast"
+	diag "oberon/diag"
+)
+
+// TypeDescriptor describes a record type at run time. Each record
+// variable, and each record a pointer points to, is tagged with the
+// descriptor of its dynamic type, from which a backend tests the type:
+// the dynamic type of x is T or an extension of it if
+//
+//	tag(x).Level >= T.Level & tag(x).Bases[T.Level] = T
+//
+// Bases holds the descriptors of the types the record type extends, by
+// extension level, and the descriptor itself last. Methods is the table
+// of the type-bound procedures: those of the base type, each in the slot
+// of the base type unless it is redefined there, then those the record
+// type adds.
+type TypeDescriptor struct {
+	Record  *Record
+	Level   int
+	Bases   []*TypeDescriptor
+	Methods []*Object
+}
+
+func (d *TypeDescriptor) String() string {
+	return d.Record.String()
+}
+
+// TypeCheckKind is the kind of test of a dynamic type.
+type TypeCheckKind int
+
+const (
+	TYPE_TEST  TypeCheckKind = iota // x IS T, a WITH guard or a label of a type CASE: FALSE unless x is a T
+	TYPE_GUARD                      // x(T): the program stops unless x is a T
+	EXACT_TYPE                      // an assignment to a VAR parameter: the program stops unless x is of type T itself
+)
+
+// TypeCheck is a test of the dynamic type of a variable that the program
+// makes at run time: whether it is of the record type Type, or, for a
+// pointer, points to one.
+type TypeCheck struct {
+	Kind TypeCheckKind
+	Type *TypeDescriptor
+}
+
+// descriptor returns the descriptor of record, whose declaration is
+// checked, so that its chain of base types is known to end. Its Methods
+// are filled in by methodTables once all the type-bound procedures are
+// bound.
+func (c *checker) descriptor(record *Record) *TypeDescriptor {
+	if d := c.descriptors[record]; d != nil {
+		return d
+	}
+	var d = &TypeDescriptor{Record: record}
+	if record.Base != nil {
+		d.Bases = append(d.Bases, c.descriptor(record.Base).Bases...)
+	}
+	d.Bases = append(d.Bases, d)
+	d.Level = len(d.Bases) - 1
+	c.descriptors[record] = d
+	return d
+}
+
+// typeCheck records the test at node of the dynamic type of a variable
+// for t, a record or a pointer to one.
+func (c *checker) typeCheck(node ast.Node, kind TypeCheckKind, t Type) {
+	if record := recordOf(t); record != nil {
+		c.tree.TypeChecks[node] = &TypeCheck{kind, c.descriptor(record)}
+	}
+}
+
+// methodTables completes the descriptors of the record types of the
+// module into tree.Descriptors, the descriptors of base types before
+// those of their extensions.
+func (c *checker) methodTables() {
+	var done = make(map[*TypeDescriptor]bool)
+	for _, record := range c.records {
+		c.methodTable(c.descriptor(record), done)
+	}
+}
+
+func (c *checker) methodTable(d *TypeDescriptor, done map[*TypeDescriptor]bool) {
+	if done[d] {
+		return
+	}
+	done[d] = true
+	if d.Level > 0 {
+		base := d.Bases[d.Level-1]
+		c.methodTable(base, done)
+		d.Methods = append([]*Object(nil), base.Methods...)
+	}
+	for _, method := range d.Record.Methods.Objects {
+		c.bind(d, method)
+	}
+	c.tree.Descriptors = append(c.tree.Descriptors, d)
+}
+
+// bind gives method, bound to the record type of d, its slot in the
+// table of d: the slot of the procedure of a base type it redefines, whose
+// formal parameters it must match, or a slot of its own.
+func (c *checker) bind(d *TypeDescriptor, method *Object) {
+	for slot, inherited := range d.Methods {
+		if inherited.Name != method.Name {
+			continue
+		}
+		p, isProcedure := method.Type.(*Procedure)
+		q, inheritedProcedure := inherited.Type.(*Procedure)
+		if isProcedure && inheritedProcedure && !matches(p, q) {
+			c.report(diag.Errorf(INVALID_OVERRIDE, span(method.Ident), "%s redefines a procedure of %s with other parameters: %s, not %s", method.Name, d.Record.Base, p, q).
+				WithNote(span(inherited.Ident), "%s is first bound here", method.Name))
+		}
+		d.Methods[slot] = method
+		return
+	}
+	if d.Record.Base != nil {
+		if field := d.Record.Base.Field(method.Name); field != nil && field.Kind == FIELD_OBJECT {
+			c.report(diag.Errorf(REDECLARED_IDENTIFIER, span(method.Ident), "%s is a field of the base type %s", method.Name, d.Record.Base).
+				WithNote(span(field.Ident), "%s is declared here", method.Name))
+		}
+	}
+	d.Methods = append(d.Methods, method)
+}
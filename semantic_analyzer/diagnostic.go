@@ -0,0 +1,42 @@
+package semantic_analyzer
+
+// Codes of the semantic diagnostics.
+const (
+	DUPLICATE_IMPORT        = "S001"
+	MODULE_NAME_MISMATCH    = "S002"
+	PROCEDURE_NAME_MISMATCH = "S003"
+	PROCEDURE_RETURNS_VALUE = "S004"
+	MISSING_RETURN          = "S005"
+	EXIT_OUTSIDE_LOOP       = "S006"
+
+	UNSAFE_MODULE              = "S007"
+	UNDEFINED_SYSTEM_PROCEDURE = "S008"
+	ARGUMENT_COUNT             = "S009"
+	ARGUMENT_MISMATCH          = "S010"
+	PROCEDURE_USE              = "S011"
+
+	UNDECLARED_IDENTIFIER  = "S012"
+	REDECLARED_IDENTIFIER  = "S013"
+	USE_BEFORE_DECLARATION = "S014"
+
+	NOT_A_TYPE              = "S015"
+	INVALID_TYPE            = "S016"
+	INVALID_OPERATION       = "S017"
+	INCOMPATIBLE_ASSIGNMENT = "S018"
+	NOT_A_VARIABLE          = "S019"
+	INVALID_SELECTOR        = "S020"
+	NOT_AN_EXTENSION        = "S021"
+	NOT_A_PROCEDURE         = "S022"
+
+	NOT_CONSTANT      = "S023"
+	CONSTANT_OVERFLOW = "S024"
+	DIVISION_BY_ZERO  = "S025"
+	OUT_OF_RANGE      = "S026"
+
+	CASE_LABEL_MISMATCH = "S027"
+	EMPTY_LABEL_RANGE   = "S028"
+	OVERLAPPING_LABELS  = "S029"
+	INCOMPLETE_CASE     = "S030"
+
+	INVALID_OVERRIDE = "S031"
+)
@@ -0,0 +1,210 @@
+package semantic_analyzer
+
+import (
+	ast "oberon/ast"
+	diag "oberon/diag"
+)
+
+// resolver builds the scopes of a module and resolves each identifier
+// used in it to the object it denotes. The scope of a declaration
+// extends from the declaration to the end of the block it is declared
+// in, so an identifier may not be used before its declaration; only the
+// base type of a pointer type may be declared later in the same block.
+type resolver struct {
+	tree     *AnnotatedTree
+	reporter *diag.Reporter
+}
+
+// define records the object declared by ident and declares it in scope,
+// unless the name is missing after a syntax error. A name declared twice
+// in one scope is reported.
+func (r *resolver) define(scope *Scope, object *Object) {
+	if object.Name == "" {
+		return
+	}
+	r.tree.Defs[object.Ident] = object
+	if previous := scope.Insert(object); previous != nil {
+		r.reporter.Report(diag.Errorf(REDECLARED_IDENTIFIER, ast.Span(analyzerFile, object.Ident), "%s redeclared in this block", object.Name).
+			WithNote(ast.Span(analyzerFile, previous.Ident), "%s is first declared here as a %s", previous.Name, previous.Kind))
+	}
+}
+
+// identDefObject returns the object of kind named by name, exported as
+// marked.
+func identDefObject(kind ObjectKind, name *ast.IdentDef, decl ast.Node) *Object {
+	return &Object{
+		Kind:     kind,
+		Name:     name.Name.Name,
+		Ident:    name.Name,
+		Decl:     decl,
+		Exported: name.IsExported(),
+		ReadOnly: name.ReadOnly,
+	}
+}
+
+// defineIdentDef declares the object of kind named by name in scope,
+// exported as marked.
+func (r *resolver) defineIdentDef(scope *Scope, kind ObjectKind, name *ast.IdentDef, decl ast.Node) {
+	r.define(scope, identDefObject(kind, name, decl))
+}
+
+// use resolves ident in scope. forward allows an object declared in
+// scope itself after ident, as the base type of a pointer type may be.
+func (r *resolver) use(scope *Scope, ident *ast.Ident, forward bool) *Object {
+	if ident == nil || ident.Name == "" {
+		return nil
+	}
+	var object = scope.Resolve(ident.Name)
+	if object == nil {
+		r.reporter.Report(diag.Errorf(UNDECLARED_IDENTIFIER, ast.Span(analyzerFile, ident), "undeclared identifier %s", ident.Name))
+		return nil
+	}
+	r.tree.Uses[ident] = object
+	if !object.IsPredeclared() && object.Pos() > ident.Pos() && !(forward && object.Scope == scope) {
+		r.reporter.Report(diag.Errorf(USE_BEFORE_DECLARATION, ast.Span(analyzerFile, ident), "%s used before its declaration", ident.Name).
+			WithNote(ast.Span(analyzerFile, object.Ident), "%s is declared here", ident.Name))
+	}
+	return object
+}
+
+// qualident resolves qualident = [ident "."] ident. Unless the first
+// ident denotes an imported module, the parser has taken a field
+// selection for a qualified identifier: the second ident is then a field
+// name, which only the types tell the object of.
+func (r *resolver) qualident(scope *Scope, qualident *ast.QualIdent, forward bool) {
+	if qualident.Module == nil {
+		r.use(scope, qualident.Name, forward)
+		return
+	}
+	r.use(scope, qualident.Module, false)
+}
+
+// inspect resolves the identifiers used in node, an expression, type or
+// statement, in scope.
+func (r *resolver) inspect(scope *Scope, node ast.Node) {
+	if node == nil {
+		return
+	}
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.QualIdent:
+			r.qualident(scope, node, false)
+			return false
+		case *ast.FieldSelector:
+			return false
+		case *ast.ForStmt:
+			r.use(scope, node.Var, false)
+		case *ast.PointerType:
+			if base, ok := node.Base.(*ast.QualIdent); ok {
+				r.qualident(scope, base, true)
+				return false
+			}
+		case *ast.RecordType:
+			r.record(scope, node)
+			return false
+		}
+		return true
+	})
+}
+
+// record declares the fields of a record type in a scope of their own.
+// The base and field types are resolved in scope, which encloses the
+// record type.
+func (r *resolver) record(scope *Scope, record *ast.RecordType) {
+	if record.Base != nil {
+		r.qualident(scope, record.Base, false)
+	}
+	var fields = NewScope(nil)
+	r.tree.Scopes[record] = fields
+	for _, field := range record.Fields {
+		for _, name := range field.Names {
+			r.defineIdentDef(fields, FIELD_OBJECT, name, field)
+		}
+		r.inspect(scope, field.Type)
+	}
+}
+
+// parameters declares the receiver and the formal parameters of
+// procedure in its scope. Their types are resolved in outer, which
+// encloses the procedure.
+func (r *resolver) parameters(outer, scope *Scope, procedure *ast.ProcDecl) {
+	if receiver := procedure.Receiver; receiver != nil {
+		r.use(outer, receiver.Type, false)
+		r.define(scope, &Object{Kind: PARAM_OBJECT, Name: receiver.Name.Name, Ident: receiver.Name, Decl: receiver})
+	}
+	if procedure.Params == nil {
+		return
+	}
+	for _, section := range procedure.Params.Sections {
+		r.inspect(outer, section.Type)
+		for _, name := range section.Names {
+			r.define(scope, &Object{Kind: PARAM_OBJECT, Name: name.Name, Ident: name, Decl: section})
+		}
+	}
+	if procedure.Params.Result != nil {
+		r.qualident(outer, procedure.Params.Result, false)
+	}
+}
+
+// block declares the objects of a declaration sequence in scope, then
+// resolves the identifiers used in the declarations, in the nested
+// procedures and in the statements that follow.
+func (r *resolver) block(scope *Scope, decls []ast.Decl, body []ast.Stmt) {
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.ConstDecl:
+			r.defineIdentDef(scope, CONST_OBJECT, decl.Name, decl)
+		case *ast.TypeDecl:
+			r.defineIdentDef(scope, TYPE_OBJECT, decl.Name, decl)
+		case *ast.VarDecl:
+			for _, name := range decl.Names {
+				r.defineIdentDef(scope, VAR_OBJECT, name, decl)
+			}
+		case *ast.ProcDecl:
+			// Type-bound procedures belong to their receiver's type,
+			// which the checker binds them to.
+			if decl.Receiver == nil {
+				r.defineIdentDef(scope, PROC_OBJECT, decl.Name, decl)
+			} else if decl.Name.Name.Name != "" {
+				r.tree.Defs[decl.Name.Name] = identDefObject(PROC_OBJECT, decl.Name, decl)
+			}
+		}
+	}
+	for _, decl := range decls {
+		switch decl := decl.(type) {
+		case *ast.ConstDecl:
+			r.inspect(scope, decl.Value)
+		case *ast.TypeDecl:
+			r.inspect(scope, decl.Type)
+		case *ast.VarDecl:
+			r.inspect(scope, decl.Type)
+		case *ast.ProcDecl:
+			var procedureScope = NewScope(scope)
+			r.tree.Scopes[decl] = procedureScope
+			r.parameters(scope, procedureScope, decl)
+			r.block(procedureScope, decl.Decls, decl.Body)
+			r.inspect(procedureScope, decl.Return)
+		}
+	}
+	for _, stmt := range body {
+		r.inspect(scope, stmt)
+	}
+}
+
+// resolve builds the scopes of tree, declared in universe, into
+// annotated.
+func resolve(tree *ast.Module, universe *Scope, annotated *AnnotatedTree, reporter *diag.Reporter) {
+	var r = &resolver{tree: annotated, reporter: reporter}
+	var scope = NewScope(universe)
+	annotated.Scope = scope
+	annotated.Scopes[tree] = scope
+	for _, importDecl := range tree.Imports {
+		name := importDecl.LocalName()
+		// Importing a module twice is reported by importList.
+		if name.Name == "" || scope.Lookup(name.Name) != nil {
+			continue
+		}
+		r.define(scope, &Object{Kind: MODULE_OBJECT, Name: name.Name, Ident: name, Decl: importDecl})
+	}
+	r.block(scope, tree.Decls, tree.Body)
+}
@@ -0,0 +1,155 @@
+package semantic_analyzer
+
+import (
+	"sort"
+
+	ast "oberon/ast"
+	lexer "oberon/lexer"
+)
+
+// ObjectKind is the kind of entity an identifier denotes.
+type ObjectKind int
+
+const (
+	CONST_OBJECT ObjectKind = iota
+	TYPE_OBJECT
+	VAR_OBJECT
+	PARAM_OBJECT
+	PROC_OBJECT
+	FIELD_OBJECT
+	MODULE_OBJECT
+)
+
+var objectKindNames = [...]string{
+	CONST_OBJECT:  "constant",
+	TYPE_OBJECT:   "type",
+	VAR_OBJECT:    "variable",
+	PARAM_OBJECT:  "parameter",
+	PROC_OBJECT:   "procedure",
+	FIELD_OBJECT:  "field",
+	MODULE_OBJECT: "module",
+}
+
+func (k ObjectKind) String() string {
+	return objectKindNames[k]
+}
+
+// Object is a named entity: a constant, type, variable, parameter,
+// procedure, record field or imported module. Decl is the node that
+// declares it: a ConstDecl, TypeDecl, VarDecl, FPSection, Receiver,
+// ProcDecl, FieldList or ImportDecl. Predeclared objects have neither
+// Ident nor Decl. Type is nil for modules, for the predeclared procedures
+// and for objects whose declaration has not been checked. Value is the
+// value of a constant.
+type Object struct {
+	Kind     ObjectKind
+	Name     string
+	Ident    *ast.Ident
+	Decl     ast.Node
+	Exported bool
+	ReadOnly bool
+	Scope    *Scope
+	Type     Type
+	Value    Value
+}
+
+// Pos returns the position of the identifier that declares o, or NO_POS
+// for a predeclared object.
+func (o *Object) Pos() int {
+	if o.Ident == nil {
+		return ast.NO_POS
+	}
+	return o.Ident.Pos()
+}
+
+// IsPredeclared reports whether o is declared by the language report
+// rather than by the source.
+func (o *Object) IsPredeclared() bool {
+	return o.Ident == nil
+}
+
+// Scope is a region of the source in which each name denotes one object:
+// the universe of predeclared identifiers, a module, a procedure or the
+// fields of a record. Names not declared in a scope are looked up in the
+// Outer scope, except in the scopes of records, which have none.
+type Scope struct {
+	Outer   *Scope
+	Objects []*Object // in the order of declaration
+
+	names map[string]*Object
+}
+
+func NewScope(outer *Scope) *Scope {
+	return &Scope{Outer: outer, names: make(map[string]*Object)}
+}
+
+// Lookup returns the object declared in s under name, or nil.
+func (s *Scope) Lookup(name string) *Object {
+	return s.names[name]
+}
+
+// Resolve returns the object name denotes in s, declared in s or in one
+// of the scopes enclosing it, or nil.
+func (s *Scope) Resolve(name string) *Object {
+	for scope := s; scope != nil; scope = scope.Outer {
+		if object := scope.names[name]; object != nil {
+			return object
+		}
+	}
+	return nil
+}
+
+// Insert declares object in s. If s already declares its name, the
+// object declared first is returned and s is left unchanged.
+func (s *Scope) Insert(object *Object) *Object {
+	if previous := s.names[object.Name]; previous != nil {
+		return previous
+	}
+	object.Scope = s
+	s.names[object.Name] = object
+	s.Objects = append(s.Objects, object)
+	return nil
+}
+
+// PREDECLARED_TYPES are the types of the predeclared type identifiers and
+// constants. ANYPTR and ANYREC are the Component Pascal types that every
+// pointer and record type extends; ANYREC is abstract.
+var PREDECLARED_TYPES = func() map[string]Type {
+	var types = make(map[string]Type)
+	for _, t := range BASIC_TYPES[BOOLEAN:NIL] {
+		types[t.Name] = t
+	}
+	var anyrec = newRecord()
+	anyrec.name = "ANYREC"
+	anyrec.Attr = lexer.ABSTRACT
+	types["ANYREC"] = anyrec
+	types["ANYPTR"] = &Pointer{name: "ANYPTR", Base: anyrec}
+	types["FALSE"] = BASIC_TYPES[BOOLEAN]
+	types["TRUE"] = BASIC_TYPES[BOOLEAN]
+	types["INF"] = BASIC_TYPES[REAL]
+	return types
+}()
+
+// Universe returns the scope of the identifiers predeclared in dialect,
+// which encloses the scope of every module.
+func Universe(dialect *lexer.Dialect) *Scope {
+	var universe = NewScope(nil)
+	for _, table := range []struct {
+		kind  ObjectKind
+		names map[string]bool
+	}{
+		{TYPE_OBJECT, dialect.PredeclaredTypes},
+		{CONST_OBJECT, dialect.PredeclaredConstants},
+		{PROC_OBJECT, dialect.PredeclaredProcedures},
+	} {
+		var names []string
+		for name := range table.names {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			universe.Insert(&Object{Kind: table.kind, Name: name, Type: PREDECLARED_TYPES[name], Value: PREDECLARED_VALUES[name]})
+		}
+	}
+	return universe
+}
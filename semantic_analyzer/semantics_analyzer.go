@@ -1,10 +1,15 @@
 package semantic_analyzer
 
 import (
-	"oberon/parser"
+	"fmt"
 	"os"
 
 	"github.com/op/go-logging"
+
+	ast "oberon/ast"
+	diag "oberon/diag"
+	lexer "oberon/lexer"
+	source "oberon/source"
 )
 
 var LOG = logging.MustGetLogger("semantic_analyzer")
@@ -18,38 +23,196 @@ var parser_log_backend_formatter = logging.NewBackendFormatter(
 	log_format,
 )
 var parserDebug = false
+var analyzerFile *source.SourceFile
 
+// AnnotatedTree is the result of the analysis of a module. Scope is the
+// scope of the module; Scopes maps the module, each procedure and each
+// record type to the scope it opens. Defs maps each identifier that
+// declares an object to the object, and Uses each identifier that
+// denotes an object to that object, field names included. Types maps
+// each expression, and each type name, to its type, and Values each
+// constant expression to its value. Descriptors are the run-time
+// descriptors of the record types of the module, and TypeChecks maps the
+// type name of each type test, type guard, WITH guard and type CASE
+// label, and the variable of each assignment to a record VAR parameter,
+// to the test of a dynamic type the program makes there.
 type AnnotatedTree struct {
 	Children []*AnnotatedTree
+	Unsafe   bool // the module imports SYSTEM
+
+	Scope  *Scope
+	Scopes map[ast.Node]*Scope
+	Defs   map[*ast.Ident]*Object
+	Uses   map[*ast.Ident]*Object
+	Types  map[ast.Expr]Type
+	Values map[ast.Expr]Value
+
+	Descriptors []*TypeDescriptor
+	TypeChecks  map[ast.Node]*TypeCheck
 }
 
-func module(tree *parser.ParseNode) (*AnnotatedTree, error) {
-	var moduleNode = new(AnnotatedTree)
-	var childIndex = 0
-	moduleName := tree.Children[childIndex]
-	moduleNode.Children = append(moduleNode.Children, moduleNode)
-	childIndex += 1
-	if tree.Children[childIndex].Label == "importList" {
-		err := importList(tree.Children[childIndex])
-		if err != nil {
-			return nil, err
+func newAnnotatedTree() *AnnotatedTree {
+	return &AnnotatedTree{
+		Scopes: make(map[ast.Node]*Scope),
+		Defs:   make(map[*ast.Ident]*Object),
+		Uses:   make(map[*ast.Ident]*Object),
+		Types:  make(map[ast.Expr]Type),
+		Values: make(map[ast.Expr]Value),
+
+		TypeChecks: make(map[ast.Node]*TypeCheck),
+	}
+}
+
+// plural returns count followed by noun, in the plural unless count is 1.
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// importList checks that no two imports bind the same name in the
+// importing module. Imports whose name is missing after a syntax error
+// are left out.
+func importList(imports []*ast.ImportDecl, reporter *diag.Reporter) {
+	var imported = make(map[string]*ast.Ident)
+	for _, importDecl := range imports {
+		name := importDecl.LocalName()
+		if name.Name == "" {
+			continue
+		}
+		if previous, ok := imported[name.Name]; ok {
+			reporter.Report(diag.Errorf(DUPLICATE_IMPORT, ast.Span(analyzerFile, name), "module %s imported more than once", name.Name).
+				WithNote(ast.Span(analyzerFile, previous), "%s is first imported here", name.Name))
+			continue
 		}
-		childIndex += 1
+		imported[name.Name] = name
+	}
+}
+
+// exits checks that every EXIT in body is inside a LOOP, which it leaves.
+func exits(body []ast.Stmt, reporter *diag.Reporter) {
+	for _, stmt := range body {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LoopStmt:
+				return false
+			case *ast.ExitStmt:
+				reporter.Report(diag.Errorf(EXIT_OUTSIDE_LOOP, ast.Span(analyzerFile, node), "EXIT outside of a LOOP statement"))
+			}
+			return true
+		})
 	}
-	if moduleName.Label != tree.Children[childIndex].Label {
-		LOG.Error(tree.Children[len(tree.Children)-1])
-		return nil, nil
+}
+
+// returns lists the RETURN statements of body, outside the procedures
+// declared in it.
+func returns(body []ast.Stmt) []*ast.ReturnStmt {
+	var list []*ast.ReturnStmt
+	for _, stmt := range body {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if node, ok := node.(*ast.ReturnStmt); ok {
+				list = append(list, node)
+			}
+			return true
+		})
 	}
-	childIndex += 1
-	return moduleNode, nil
+	return list
 }
 
-func Analyze(tree *parser.ParseNode, debug bool) (*AnnotatedTree, error) {
+// procedures checks that every procedure in decls, and in the
+// declarations of those procedures, ends with its own name:
+// ProcedureDeclaration = ProcedureHeading ";" ProcedureBody ident.
+// A function procedure must return a value, at the end of its body or,
+// in the dialects with RETURN statements, from each RETURN in it; a
+// proper procedure must not. EXIT statements are checked by exits.
+func procedures(decls []ast.Decl, reporter *diag.Reporter) {
+	for _, decl := range decls {
+		procedure, ok := decl.(*ast.ProcDecl)
+		if !ok {
+			continue
+		}
+		name, endName := procedure.Name.Name, procedure.EndName
+		if endName != nil && name.Name != "" && endName.Name != "" && name.Name != endName.Name {
+			reporter.Report(diag.Errorf(PROCEDURE_NAME_MISMATCH, ast.Span(analyzerFile, endName), "procedure %s ends with the name %s", name.Name, endName.Name).
+				WithNote(ast.Span(analyzerFile, name), "procedure %s is declared here", name.Name).
+				WithSuggestion(ast.Span(analyzerFile, endName), name.Name, "end the procedure with its own name"))
+		}
+		var function = procedure.Params != nil && procedure.Params.Result != nil
+		var returned = procedure.Return != nil
+		if !function && procedure.Return != nil {
+			reporter.Report(diag.Errorf(PROCEDURE_RETURNS_VALUE, ast.Span(analyzerFile, procedure.Return), "proper procedure %s returns a value", name.Name).
+				WithNote(ast.Span(analyzerFile, name), "procedure %s is declared without a result type", name.Name))
+		}
+		for _, stmt := range returns(procedure.Body) {
+			switch {
+			case !function && stmt.X != nil:
+				reporter.Report(diag.Errorf(PROCEDURE_RETURNS_VALUE, ast.Span(analyzerFile, stmt.X), "proper procedure %s returns a value", name.Name).
+					WithNote(ast.Span(analyzerFile, name), "procedure %s is declared without a result type", name.Name))
+			case function && stmt.X == nil:
+				reporter.Report(diag.Errorf(MISSING_RETURN, ast.Span(analyzerFile, stmt), "RETURN in function procedure %s has no value", name.Name).
+					WithNote(ast.Span(analyzerFile, procedure.Params.Result), "procedure %s returns a result of this type", name.Name))
+			}
+			returned = returned || stmt.X != nil
+		}
+		if function && !returned && procedure.EndPos != ast.NO_POS {
+			reporter.Report(diag.Errorf(MISSING_RETURN, analyzerFile.Span(procedure.EndPos, procedure.EndPos+len("END")), "function procedure %s ends without RETURN", name.Name).
+				WithNote(ast.Span(analyzerFile, procedure.Params.Result), "procedure %s returns a result of this type", name.Name))
+		}
+		exits(procedure.Body, reporter)
+		procedures(procedure.Decls, reporter)
+	}
+}
+
+// module checks the module header against its closing ident:
+// MODULE ident ";" [ImportList] DeclarationSequence
+// [BEGIN StatementSequence] END ident ".".
+func module(tree *ast.Module, options Options, reporter *diag.Reporter) *AnnotatedTree {
+	var moduleNode = newAnnotatedTree()
+	importList(tree.Imports, reporter)
+	resolve(tree, Universe(options.Dialect), moduleNode, reporter)
+	moduleNode.Unsafe = system(tree, reporter)
+	check(tree, options, moduleNode, reporter)
+	procedures(tree.Decls, reporter)
+	exits(tree.Body, reporter)
+	for _, stmt := range returns(tree.Body) {
+		if stmt.X != nil {
+			reporter.Report(diag.Errorf(PROCEDURE_RETURNS_VALUE, ast.Span(analyzerFile, stmt.X), "the body of module %s returns a value", tree.Name.Name))
+		}
+	}
+	if tree.Name.Name != "" && tree.EndName.Name != "" && tree.Name.Name != tree.EndName.Name {
+		reporter.Report(diag.Errorf(MODULE_NAME_MISMATCH, ast.Span(analyzerFile, tree.EndName), "module %s ends with the name %s", tree.Name.Name, tree.EndName.Name).
+			WithNote(ast.Span(analyzerFile, tree.Name), "module %s is declared here", tree.Name.Name).
+			WithSuggestion(ast.Span(analyzerFile, tree.EndName), tree.Name.Name, "end the module with its own name"))
+	}
+	return moduleNode
+}
+
+// Options select the dialect the analysis follows and the warnings it
+// reports besides those always reported.
+type Options struct {
+	Dialect *lexer.Dialect // Oberon-07 if nil
+
+	// WarnIncompleteCase asks for an INCOMPLETE_CASE warning on each CASE
+	// statement whose labels leave out values of its expression.
+	WarnIncompleteCase bool
+}
+
+// Analyze checks the syntax tree of file against options.Dialect.
+// Semantic errors are reported to reporter; the error returned is the
+// first of them.
+func Analyze(file *source.SourceFile, tree *ast.Module, options Options, reporter *diag.Reporter, debug bool) (*AnnotatedTree, error) {
 	logging.SetBackend(parser_log_backend_formatter)
 	parserDebug = debug
+	analyzerFile = file
+	if options.Dialect == nil {
+		options.Dialect = lexer.OBERON07
+	}
+	var semanticReporter = diag.NewReporter()
 	annotated_tree := new(AnnotatedTree)
-	_moduleNode, err := module(tree)
-	if err != nil {
+	_moduleNode := module(tree, options, semanticReporter)
+	reporter.Report(semanticReporter.Diagnostics()...)
+	if err := semanticReporter.Err(); err != nil {
 		return nil, err
 	}
 	annotated_tree.Children = append(annotated_tree.Children, _moduleNode)
@@ -0,0 +1,84 @@
+package semantic_analyzer
+
+import (
+	"sort"
+	"strings"
+
+	ast "oberon/ast"
+	diag "oberon/diag"
+	source "oberon/source"
+)
+
+// SYSTEM is the name of the pseudo-module built into the compiler. It has
+// no source; its procedures give access to the machine beneath the
+// language:
+//
+//	ADR(v): INTEGER      the address of variable v
+//	BIT(a, n): BOOLEAN   bit n of the word at address a
+//	COPY(src, dst, n)    copies n words from address src to address dst
+//	GET(a, v)            reads the variable v from address a
+//	PUT(a, x)            writes the value x to address a
+//	SIZE(T): INTEGER     the number of bytes a variable of type T takes
+//	VAL(T, x): T         the value x, its bits taken as of type T
+//
+// None of these is type-safe, so a module that imports SYSTEM is unsafe:
+// the analyzer says so in an UNSAFE_MODULE note at the import and sets
+// AnnotatedTree.Unsafe. Calls of the SYSTEM procedures are checked
+// against SYSTEM_PROCEDURES like calls of the predeclared procedures.
+const SYSTEM = "SYSTEM"
+
+// SYSTEM_PROCEDURES are the procedures of SYSTEM.
+var SYSTEM_PROCEDURES = map[string]builtin{
+	"ADR":  {[]parameterClass{ANY_VARIABLE}, 0, "INTEGER"},
+	"BIT":  {[]parameterClass{INTEGER_VALUE, INTEGER_VALUE}, 0, "BOOLEAN"},
+	"COPY": {[]parameterClass{INTEGER_VALUE, INTEGER_VALUE, INTEGER_VALUE}, 0, ""},
+	"GET":  {[]parameterClass{INTEGER_VALUE, ANY_VARIABLE}, 0, ""},
+	"PUT":  {[]parameterClass{INTEGER_VALUE, ANY_VALUE}, 0, ""},
+	"SIZE": {[]parameterClass{TYPE_NAME}, 0, "INTEGER"},
+	"VAL":  {[]parameterClass{TYPE_NAME, ANY_VALUE}, 0, TYPE_RESULT},
+}
+
+// systemImports returns the imports of SYSTEM among imports. The module
+// may import it under aliases.
+func systemImports(imports []*ast.ImportDecl) []*ast.ImportDecl {
+	var systemDecls []*ast.ImportDecl
+	for _, importDecl := range imports {
+		if importDecl.Module != nil && importDecl.Module.Name == SYSTEM {
+			systemDecls = append(systemDecls, importDecl)
+		}
+	}
+	return systemDecls
+}
+
+// undefinedSystemProcedure reports name, which SYSTEM does not declare.
+func undefinedSystemProcedure(name *ast.Ident, reporter *diag.Reporter) {
+	var names []string
+	for procedure := range SYSTEM_PROCEDURES {
+		names = append(names, procedure)
+	}
+	sort.Strings(names)
+	reporter.Report(diag.Errorf(UNDEFINED_SYSTEM_PROCEDURE, ast.Span(analyzerFile, name), "SYSTEM has no procedure %s", name.Name).
+		WithNote(source.Span{}, "SYSTEM has the procedures %s", strings.Join(names, ", ")))
+}
+
+// isSystem reports whether object is the module SYSTEM, imported under
+// its own name or an alias.
+func isSystem(object *Object) bool {
+	if object == nil || object.Kind != MODULE_OBJECT {
+		return false
+	}
+	importDecl, ok := object.Decl.(*ast.ImportDecl)
+	return ok && importDecl.Module.Name == SYSTEM
+}
+
+// system reports whether the module tree is unsafe, that is, whether it
+// imports SYSTEM.
+func system(tree *ast.Module, reporter *diag.Reporter) bool {
+	var systemDecls = systemImports(tree.Imports)
+	if len(systemDecls) == 0 {
+		return false
+	}
+	reporter.Report(diag.Notef(UNSAFE_MODULE, ast.Span(analyzerFile, systemDecls[0]), "module %s is unsafe: it imports SYSTEM", tree.Name.Name).
+		WithNote(source.Span{}, "the procedures of SYSTEM read and write memory regardless of types"))
+	return true
+}
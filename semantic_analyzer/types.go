@@ -0,0 +1,405 @@
+package semantic_analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	lexer "oberon/lexer"
+)
+
+// Type is the type of an Oberon value. Oberon types are equal by name:
+// each array, record, pointer or procedure type in the source is a type
+// of its own, which a type declaration gives a name, so two types are the
+// same type only if they are the same Type. Open arrays and procedure
+// types are the exceptions; see identical.
+type Type interface {
+	String() string
+}
+
+// BasicKind is the kind of a basic type.
+type BasicKind int
+
+const (
+	INVALID BasicKind = iota // the type of an expression with errors
+	BOOLEAN
+	SHORTCHAR
+	CHAR
+	BYTE
+	SHORTINT
+	INTEGER
+	LONGINT
+	SHORTREAL
+	REAL
+	LONGREAL
+	SET
+	NIL
+)
+
+// Basic is a basic type. INVALID is compatible with every type, so that
+// an error is reported once and not again for each expression built on
+// the one in error.
+type Basic struct {
+	Kind BasicKind
+	Name string
+}
+
+func (t *Basic) String() string { return t.Name }
+
+var BASIC_TYPES = [...]*Basic{
+	INVALID:   {INVALID, "invalid type"},
+	BOOLEAN:   {BOOLEAN, "BOOLEAN"},
+	SHORTCHAR: {SHORTCHAR, "SHORTCHAR"},
+	CHAR:      {CHAR, "CHAR"},
+	BYTE:      {BYTE, "BYTE"},
+	SHORTINT:  {SHORTINT, "SHORTINT"},
+	INTEGER:   {INTEGER, "INTEGER"},
+	LONGINT:   {LONGINT, "LONGINT"},
+	SHORTREAL: {SHORTREAL, "SHORTREAL"},
+	REAL:      {REAL, "REAL"},
+	LONGREAL:  {LONGREAL, "LONGREAL"},
+	SET:       {SET, "SET"},
+	NIL:       {NIL, "NIL"},
+}
+
+// String is the type of a string constant of Length characters. A string
+// of one character is also a character constant.
+type String struct {
+	Length int
+}
+
+func (t *String) String() string { return fmt.Sprintf("string of length %d", t.Length) }
+
+// OPEN_ARRAY is the Len of an open array, whose length is that of the
+// actual parameter or of the array allocated by NEW. UNKNOWN_LENGTH is
+// that of an array whose length is given by an expression in error.
+const (
+	OPEN_ARRAY     = -1
+	UNKNOWN_LENGTH = -2
+)
+
+type Array struct {
+	name string
+	Len  int64
+	Elem Type
+}
+
+func (t *Array) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	if t.Len == OPEN_ARRAY {
+		return fmt.Sprintf("ARRAY OF %s", t.Elem)
+	}
+	if t.Len == UNKNOWN_LENGTH {
+		return fmt.Sprintf("ARRAY ? OF %s", t.Elem)
+	}
+	return fmt.Sprintf("ARRAY %d OF %s", t.Len, t.Elem)
+}
+
+// Record is a record type. A record type that extends Base has the
+// fields and the type-bound procedures of Base, besides those it
+// declares. The chain of base types ends: a record that would extend
+// itself is given no Base. Attr is the Component Pascal attribute of the
+// record, or ILLEGAL.
+type Record struct {
+	name    string
+	Attr    lexer.TokenKind
+	Base    *Record
+	Fields  *Scope
+	Methods *Scope
+}
+
+func newRecord() *Record {
+	return &Record{Attr: lexer.ILLEGAL, Fields: NewScope(nil), Methods: NewScope(nil)}
+}
+
+func (t *Record) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "RECORD"
+}
+
+// Field returns the field or type-bound procedure name of t, declared in
+// t or in one of the types it extends, or nil.
+func (t *Record) Field(name string) *Object {
+	for record := t; record != nil; record = record.Base {
+		if field := record.Fields.Lookup(name); field != nil {
+			return field
+		}
+		if method := record.Methods.Lookup(name); method != nil {
+			return method
+		}
+	}
+	return nil
+}
+
+// Extends reports whether t is base or an extension of it.
+func (t *Record) Extends(base *Record) bool {
+	for record := t; record != nil; record = record.Base {
+		if record == base {
+			return true
+		}
+	}
+	return false
+}
+
+// Level returns the extension level of t: 0 if t extends no record
+// type, and one more than the level of its base type if it does.
+func (t *Record) Level() int {
+	var level = 0
+	for record := t.Base; record != nil; record = record.Base {
+		level++
+	}
+	return level
+}
+
+type Pointer struct {
+	name string
+	Base Type
+}
+
+func (t *Pointer) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return fmt.Sprintf("POINTER TO %s", t.Base)
+}
+
+// Param is a formal parameter. Mode is VAR, IN or OUT, or ILLEGAL for a
+// value parameter.
+type Param struct {
+	Name string
+	Mode lexer.TokenKind
+	Type Type
+}
+
+// Procedure is the type of a procedure. Result is nil for a proper
+// procedure.
+type Procedure struct {
+	name   string
+	Params []*Param
+	Result Type
+}
+
+func (t *Procedure) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	var params []string
+	for _, param := range t.Params {
+		if param.Mode != lexer.ILLEGAL {
+			params = append(params, param.Mode.String()+" "+param.Type.String())
+		} else {
+			params = append(params, param.Type.String())
+		}
+	}
+	var result string
+	if t.Result != nil {
+		result = ": " + t.Result.String()
+	}
+	return fmt.Sprintf("PROCEDURE (%s)%s", strings.Join(params, "; "), result)
+}
+
+// name gives t, the type a type declaration declares, the name of the
+// declaration unless it is already named.
+func name(t Type, declared string) {
+	switch t := t.(type) {
+	case *Array:
+		if t.name == "" {
+			t.name = declared
+		}
+	case *Record:
+		if t.name == "" {
+			t.name = declared
+		}
+	case *Pointer:
+		if t.name == "" {
+			t.name = declared
+		}
+	case *Procedure:
+		if t.name == "" {
+			t.name = declared
+		}
+	}
+}
+
+func basic(t Type, kinds ...BasicKind) bool {
+	if b, ok := t.(*Basic); ok {
+		for _, kind := range kinds {
+			if b.Kind == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isInvalid(t Type) bool { return t == nil || basic(t, INVALID) }
+func isBoolean(t Type) bool { return basic(t, BOOLEAN) }
+func isSet(t Type) bool     { return basic(t, SET) }
+func isInteger(t Type) bool { return basic(t, BYTE, SHORTINT, INTEGER, LONGINT) }
+func isReal(t Type) bool    { return basic(t, SHORTREAL, REAL, LONGREAL) }
+func isNumeric(t Type) bool { return isInteger(t) || isReal(t) }
+
+// isChar reports whether t is a character type or a string of one
+// character, which is a character constant.
+func isChar(t Type) bool {
+	if s, ok := t.(*String); ok {
+		return s.Length == 1
+	}
+	return basic(t, SHORTCHAR, CHAR)
+}
+
+// isCharArray reports whether t is an array of characters.
+func isCharArray(t Type) bool {
+	array, ok := t.(*Array)
+	return ok && basic(array.Elem, SHORTCHAR, CHAR)
+}
+
+// isString reports whether t is a string or an array of characters,
+// which hold strings.
+func isString(t Type) bool {
+	_, ok := t.(*String)
+	return ok || isCharArray(t)
+}
+
+// recordOf returns the record type t is or points to, or nil.
+func recordOf(t Type) *Record {
+	switch t := t.(type) {
+	case *Record:
+		return t
+	case *Pointer:
+		record, _ := t.Base.(*Record)
+		return record
+	}
+	return nil
+}
+
+// identical reports whether a and b are the same type: the same named
+// type, open arrays of identical element types, or procedure types with
+// matching formal parameters.
+func identical(a, b Type) bool {
+	if a == b {
+		return true
+	}
+	switch a := a.(type) {
+	case *Array:
+		b, ok := b.(*Array)
+		return ok && a.Len == OPEN_ARRAY && b.Len == OPEN_ARRAY && identical(a.Elem, b.Elem)
+	case *Procedure:
+		b, ok := b.(*Procedure)
+		return ok && matches(a, b)
+	}
+	return false
+}
+
+// matches reports whether the formal parameters of a and b match: they
+// have parameters of the same modes and identical types, and identical
+// results or none.
+func matches(a, b *Procedure) bool {
+	if len(a.Params) != len(b.Params) || (a.Result == nil) != (b.Result == nil) {
+		return false
+	}
+	for index, param := range a.Params {
+		other := b.Params[index]
+		if param.Mode != other.Mode || !identical(param.Type, other.Type) {
+			return false
+		}
+	}
+	return a.Result == nil || identical(a.Result, b.Result)
+}
+
+// NUMERIC_RANKS orders the numeric types: under type inclusion, a type
+// includes the values of all types of lower rank.
+var NUMERIC_RANKS = map[BasicKind]int{
+	BYTE:      1,
+	SHORTINT:  2,
+	INTEGER:   3,
+	LONGINT:   4,
+	SHORTREAL: 5,
+	REAL:      6,
+	LONGREAL:  7,
+}
+
+// includes reports whether the values of type small are values of type
+// large. In Oberon-07 the integer types include each other and no other
+// numeric type; dialects with NUMERIC_TYPE_INCLUSION order them all.
+func includes(dialect *lexer.Dialect, large, small Type) bool {
+	if large == small {
+		return true
+	}
+	if basic(large, CHAR) && basic(small, SHORTCHAR) && dialect.Has(lexer.NUMERIC_TYPE_INCLUSION) {
+		return true
+	}
+	if !isNumeric(large) || !isNumeric(small) {
+		return false
+	}
+	if !dialect.Has(lexer.NUMERIC_TYPE_INCLUSION) {
+		return isInteger(large) && isInteger(small)
+	}
+	return NUMERIC_RANKS[large.(*Basic).Kind] >= NUMERIC_RANKS[small.(*Basic).Kind]
+}
+
+// assignable reports whether a value of type value can be assigned to a
+// variable of type variable, as by the rules of assignment compatibility.
+func assignable(dialect *lexer.Dialect, variable, value Type) bool {
+	if isInvalid(variable) || isInvalid(value) || identical(variable, value) {
+		return true
+	}
+	if includes(dialect, variable, value) {
+		return true
+	}
+	switch variable := variable.(type) {
+	case *Basic:
+		// A string of one character is a character constant.
+		return isChar(variable) && isChar(value) && !basic(value, CHAR, SHORTCHAR)
+	case *Array:
+		if s, ok := value.(*String); ok {
+			return isCharArray(variable) && (variable.Len < 0 || int64(s.Length) < variable.Len)
+		}
+		// An open array may be assigned to an array of equal element type.
+		value, ok := value.(*Array)
+		return ok && value.Len == OPEN_ARRAY && identical(variable.Elem, value.Elem)
+	case *Record:
+		value, ok := value.(*Record)
+		return ok && value.Extends(variable)
+	case *Pointer:
+		if basic(value, NIL) {
+			return true
+		}
+		value, ok := value.(*Pointer)
+		if !ok {
+			return false
+		}
+		base, valueBase := recordOf(variable), recordOf(value)
+		return base != nil && valueBase != nil && valueBase.Extends(base)
+	case *Procedure:
+		if basic(value, NIL) {
+			return true
+		}
+		value, ok := value.(*Procedure)
+		return ok && matches(variable, value)
+	}
+	return false
+}
+
+// arrayCompatible reports whether an actual parameter of type actual can
+// be passed for a formal parameter of type formal: the types are the
+// same, or formal is an open array of elements that the elements of
+// actual are array compatible with, or formal is an open array of
+// characters and actual a string.
+func arrayCompatible(formal, actual Type) bool {
+	if isInvalid(formal) || isInvalid(actual) || identical(formal, actual) {
+		return true
+	}
+	array, ok := formal.(*Array)
+	if !ok || array.Len != OPEN_ARRAY {
+		return false
+	}
+	if _, ok := actual.(*String); ok {
+		return isCharArray(array)
+	}
+	actualArray, ok := actual.(*Array)
+	return ok && arrayCompatible(array.Elem, actualArray.Elem)
+}
@@ -0,0 +1,164 @@
+package source
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"unicode/utf8"
+)
+
+// Position is a resolved location in a SourceFile. Line and Column are
+// 1-based; Column counts bytes from the start of the line, while
+// UTF16Column counts UTF-16 code units as most editors do.
+type Position struct {
+	Filename    string
+	Offset      int
+	Line        int
+	Column      int
+	UTF16Column int
+}
+
+func (p Position) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
+
+// Span is the range of bytes from Start up to, but not including, End.
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (s Span) String() string {
+	return s.Start.String()
+}
+
+// SourceFile holds the complete contents of a source file together with
+// the byte offset at which every line starts, so that any offset produced
+// by the lexer can be mapped back to a line and column.
+type SourceFile struct {
+	Name     string
+	Contents []byte
+	lines    []int
+}
+
+func NewSourceFile(name string, contents []byte) *SourceFile {
+	var file = &SourceFile{Name: name, Contents: contents}
+	file.lines = append(file.lines, 0)
+	for i, b := range contents {
+		if b == '\n' {
+			file.lines = append(file.lines, i+1)
+		}
+	}
+	return file
+}
+
+// Read drains reader and returns its contents as a SourceFile. The reader
+// is consumed in chunks, so files of any size are read completely.
+func Read(name string, reader io.Reader) (*SourceFile, error) {
+	var contents []byte
+	var buffered = bufio.NewReader(reader)
+	var chunk = make([]byte, 32*1024)
+	for {
+		n, err := buffered.Read(chunk)
+		contents = append(contents, chunk[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("source error: reading %s: %v", name, err)
+		}
+	}
+	return NewSourceFile(name, contents), nil
+}
+
+func ReadFile(path string) (*SourceFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("source error: %v", err)
+	}
+	defer file.Close()
+	return Read(path, file)
+}
+
+func (f *SourceFile) Size() int {
+	return len(f.Contents)
+}
+
+func (f *SourceFile) LineCount() int {
+	return len(f.lines)
+}
+
+// LineStart returns the offset of the first byte of the given 1-based line.
+func (f *SourceFile) LineStart(line int) int {
+	if line < 1 {
+		return 0
+	}
+	if line > len(f.lines) {
+		return len(f.Contents)
+	}
+	return f.lines[line-1]
+}
+
+// Line returns the text of the given 1-based line without its line
+// terminator.
+func (f *SourceFile) Line(line int) []byte {
+	if line < 1 || line > len(f.lines) {
+		return nil
+	}
+	var start = f.lines[line-1]
+	var end = len(f.Contents)
+	if line < len(f.lines) {
+		end = f.lines[line] - 1
+	}
+	if end > start && f.Contents[end-1] == '\r' {
+		end--
+	}
+	return f.Contents[start:end]
+}
+
+// Position maps a byte offset to its line and column. Offsets past the
+// end of the file resolve to the position just after the last byte.
+func (f *SourceFile) Position(offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(f.Contents) {
+		offset = len(f.Contents)
+	}
+	var line = sort.Search(len(f.lines), func(i int) bool {
+		return f.lines[i] > offset
+	})
+	var lineStart = f.lines[line-1]
+	return Position{
+		Filename:    f.Name,
+		Offset:      offset,
+		Line:        line,
+		Column:      offset - lineStart + 1,
+		UTF16Column: utf16Length(f.Contents[lineStart:offset]) + 1,
+	}
+}
+
+// utf16Length returns the number of UTF-16 code units needed to encode
+// text. Bytes that are not valid UTF-8 count as one unit each.
+func utf16Length(text []byte) int {
+	var length = 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		if r >= 0x10000 {
+			length += 2
+		} else {
+			length += 1
+		}
+		text = text[size:]
+	}
+	return length
+}
+
+func (f *SourceFile) Span(start int, end int) Span {
+	return Span{Start: f.Position(start), End: f.Position(end)}
+}